@@ -50,7 +50,7 @@ func main() {
 	msg.InitMessaging(&cfg.Messaging)
 	service.Init(engine, cfg, logger)
 	process.Init(cfg)
-	business.Init()
+	business.Init(cfg)
 	boot.RunHttpServer(engine, &cfg.Application)
 }
 
@@ -0,0 +1,489 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adaptertest
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+//===
+//=== Conformance suite: every adapter must pass it against a fake backend.
+//===
+//=== Usage, from the adapter package:
+//===
+//===    func TestConformance(t *testing.T) {
+//===        adaptertest.Run(t, func(t *testing.T) *adaptertest.Setup {
+//===            NewBackend(t, fakeBroker())
+//===            return &adaptertest.Setup{ ... }
+//===        })
+//===    }
+//===
+//=== The factory is called once per sub test, so each one gets a fresh backend
+//=== and a fresh adapter. Sub tests must not run in parallel because the fake
+//=== backend replaces the shared HTTP transport
+//===
+//=============================================================================
+
+type Setup struct {
+	Adapter       adapter.Adapter
+	ConfigParams  map[string]any
+	ConnectParams map[string]any
+	Fixture       Fixture
+}
+
+//=============================================================================
+//--- What the fake backend is expected to return. Empty fields skip the checks
+
+type Fixture struct {
+	Root       string                    // root symbol that must be found
+	Instrument string                    // instrument of Root that must be listed
+	Bars       *adapter.PriceBarsRequest // price bars that must be returned
+	MinBars    int
+	Accounts   []string                  // account codes that must be returned
+	Orders     []string                  // order ids that must be returned
+	Positions  []string                  // symbols of the positions that must be returned
+	NewOrder   *adapter.Order            // order that must be accepted, then cancelled
+}
+
+//=============================================================================
+
+type Factory func(t *testing.T) *Setup
+
+//=============================================================================
+
+const (
+	TestUsername   = "tester"
+	TestConnection = "conformance"
+	TestHost       = "localhost"
+)
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func Run(t *testing.T, factory Factory) {
+	t.Run("Info",        func(t *testing.T) { testInfo       (t, factory(t)) })
+	t.Run("Connection",  func(t *testing.T) { testConnection (t, factory(t)) })
+	t.Run("Session",     func(t *testing.T) { testSession    (t, factory(t)) })
+	t.Run("RootSymbols", func(t *testing.T) { testRootSymbols(t, factory(t)) })
+	t.Run("Instruments", func(t *testing.T) { testInstruments(t, factory(t)) })
+	t.Run("PriceBars",   func(t *testing.T) { testPriceBars  (t, factory(t)) })
+	t.Run("Accounts",    func(t *testing.T) { testAccounts   (t, factory(t)) })
+	t.Run("Orders",      func(t *testing.T) { testOrders     (t, factory(t)) })
+	t.Run("Positions",   func(t *testing.T) { testPositions  (t, factory(t)) })
+}
+
+//=============================================================================
+
+func Connect(t *testing.T, s *Setup) *adapter.ConnectionContext {
+	t.Helper()
+
+	ctx,err := adapter.NewConnectionContext(TestUsername, TestConnection, TestHost, s.Adapter, s.ConfigParams, s.ConnectParams)
+	if err != nil {
+		t.Fatalf("cannot create the connection context: %v", err)
+	}
+
+	cr,err := ctx.Connect()
+	if err != nil {
+		t.Fatalf("cannot connect: %v", err)
+	}
+
+	if cr != adapter.ConnectionResultConnected {
+		t.Fatalf("expected a direct connection, got result %v", cr)
+	}
+
+	t.Cleanup(func() {
+		_ = ctx.Disconnect()
+	})
+
+	return ctx
+}
+
+//=============================================================================
+//===
+//=== Tests
+//===
+//=============================================================================
+
+func testInfo(t *testing.T, s *Setup) {
+	info := s.Adapter.GetInfo()
+
+	if info.Code == "" || info.Name == "" {
+		t.Errorf("adapter code and name are mandatory: %+v", info)
+	}
+
+	for _,list := range [][]*adapter.ParamDef{ info.ConfigParams, info.ConnectParams } {
+		for _,p := range list {
+			if p.Name == "" {
+				t.Errorf("param without a name in adapter %v", info.Code)
+			}
+
+			switch p.Type {
+				case adapter.ParamTypeString, adapter.ParamTypePassword, adapter.ParamTypeBool, adapter.ParamTypeInt, adapter.ParamTypeFloat:
+				default:
+					t.Errorf("param %v has an invalid type: %v", p.Name, p.Type)
+			}
+		}
+	}
+
+	if info.Reconnect.MaxAttempts < 0 || info.Reconnect.InitialDelaySec > info.Reconnect.MaxDelaySec {
+		t.Errorf("invalid reconnect policy: %+v", info.Reconnect)
+	}
+
+	clone := s.Adapter.Clone(s.ConfigParams, s.ConnectParams)
+	if clone == s.Adapter {
+		t.Errorf("Clone must return a new instance")
+	}
+}
+
+//=============================================================================
+
+func testConnection(t *testing.T, s *Setup) {
+	ctx := Connect(t, s)
+
+	if !ctx.IsConnected() {
+		t.Fatalf("context must be connected, status is %v", ctx.GetStatus())
+	}
+
+	if s.Adapter.GetTokenExpSeconds() > 0 && ctx.GetNextRefreshTime() == nil {
+		t.Errorf("a refresh must be scheduled when the token expires")
+	}
+
+	err := ctx.Disconnect()
+	if err != nil {
+		t.Errorf("cannot disconnect: %v", err)
+	}
+
+	if !ctx.IsDisconnected() {
+		t.Errorf("context must be disconnected, status is %v", ctx.GetStatus())
+	}
+}
+
+//=============================================================================
+//--- A failed refresh is retried with a short backoff, so a successful one is
+//--- recognised by the next refresh being scheduled near the token expiration
+
+func testSession(t *testing.T, s *Setup) {
+	ctx := Connect(t, s)
+
+	if exp := time.Duration(s.Adapter.GetTokenExpSeconds()) * time.Second; exp > 0 {
+		err := ctx.RefreshToken()
+		if err != nil {
+			t.Fatalf("cannot refresh the token: %v", err)
+		}
+
+		next := ctx.GetNextRefreshTime()
+		if next == nil || next.Before(time.Now().Add(exp/2)) {
+			t.Errorf("token refresh failed: next refresh at %v", next)
+		}
+
+		if !ctx.IsConnected() {
+			t.Errorf("context must still be connected after a refresh, status is %v", ctx.GetStatus())
+		}
+	}
+
+	//--- Adapters that keep their session across restarts must be able to restore it
+
+	if _,ok := s.Adapter.(adapter.SessionKeeper); !ok {
+		return
+	}
+
+	token := ctx.GetRefreshToken()
+	if token == "" {
+		t.Fatalf("a session keeper must provide a refresh token")
+	}
+
+	rc,err := adapter.RestoreConnectionContext(TestUsername, TestConnection, TestHost, s.Adapter, s.ConfigParams)
+	if err != nil {
+		t.Fatalf("cannot create the restored context: %v", err)
+	}
+
+	err = rc.Restore(token)
+	if err != nil {
+		t.Fatalf("cannot restore the session: %v", err)
+	}
+
+	if !rc.IsConnected() {
+		t.Errorf("restored context must be connected, status is %v", rc.GetStatus())
+	}
+
+	if s.Fixture.Accounts != nil {
+		_,err = rc.GetAccounts()
+		if err != nil {
+			t.Errorf("restored session cannot be used: %v", err)
+		}
+	}
+}
+
+//=============================================================================
+
+func testRootSymbols(t *testing.T, s *Setup) {
+	if !s.Adapter.GetInfo().SupportsData || s.Fixture.Root == "" {
+		t.Skip("no data support or no root in the fixture")
+	}
+
+	ctx  := Connect(t, s)
+	root := s.Fixture.Root
+
+	roots,err := ctx.GetRootSymbols(root)
+	if err != nil {
+		t.Fatalf("GetRootSymbols failed: %v", err)
+	}
+
+	if !slices.ContainsFunc(roots, func(rs *adapter.RootSymbol) bool { return rs.Code == root }) {
+		t.Errorf("root %v not returned by GetRootSymbols: %d roots found", root, len(roots))
+	}
+
+	rs,err := ctx.GetRootSymbol(root)
+	if err != nil {
+		t.Fatalf("GetRootSymbol failed: %v", err)
+	}
+
+	if rs == nil || rs.Code != root {
+		t.Errorf("GetRootSymbol returned the wrong root: %+v", rs)
+	}
+
+	rs,err = ctx.GetRootSymbol("NOTAROOT")
+	if err == nil {
+		t.Errorf("GetRootSymbol must fail for an unknown root, got %+v", rs)
+	}
+}
+
+//=============================================================================
+
+func testInstruments(t *testing.T, s *Setup) {
+	if !s.Adapter.GetInfo().SupportsData || s.Fixture.Instrument == "" {
+		t.Skip("no data support or no instrument in the fixture")
+	}
+
+	ctx  := Connect(t, s)
+	root := s.Fixture.Root
+
+	list,err := ctx.GetInstruments(root)
+	if err != nil {
+		t.Fatalf("GetInstruments failed: %v", err)
+	}
+
+	for _,i := range list {
+		if i.Name == "" || i.Root != root {
+			t.Errorf("bad instrument for root %v: %+v", root, i)
+		}
+	}
+
+	if !slices.ContainsFunc(list, func(i *adapter.Instrument) bool { return i.Name == s.Fixture.Instrument }) {
+		t.Errorf("instrument %v not returned: %d instruments found", s.Fixture.Instrument, len(list))
+	}
+}
+
+//=============================================================================
+
+func testPriceBars(t *testing.T, s *Setup) {
+	rq := s.Fixture.Bars
+	if !s.Adapter.GetInfo().SupportsData || rq == nil {
+		t.Skip("no data support or no bars request in the fixture")
+	}
+
+	ctx := Connect(t, s)
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil {
+		t.Fatalf("GetPriceBars failed: %v", err)
+	}
+
+	if pb.Symbol != rq.Symbol || pb.Unit != rq.Unit || pb.Interval != rq.Interval {
+		t.Errorf("price bars do not match the request: %+v", pb)
+	}
+
+	if len(pb.Bars) < s.Fixture.MinBars {
+		t.Fatalf("expected at least %d bars, got %d", s.Fixture.MinBars, len(pb.Bars))
+	}
+
+	if pb.NoData != (len(pb.Bars) == 0) {
+		t.Errorf("NoData is inconsistent with %d bars", len(pb.Bars))
+	}
+
+	from := adapter.IntDateToTime(rq.From, time.UTC)
+	to   := adapter.IntDateToTime(adapter.AddDays(rq.To, 1), time.UTC)
+
+	for i,b := range pb.Bars {
+		ts := b.TimeStamp.UTC()
+		if ts.Before(from) || !ts.Before(to) {
+			t.Errorf("bar %d is out of the requested range: %v", i, ts)
+		}
+
+		if i > 0 && ts.Before(pb.Bars[i-1].TimeStamp) {
+			t.Errorf("bar %d is not in chronological order: %v", i, ts)
+		}
+
+		if b.High < max(b.Open, b.Close) || b.Low > min(b.Open, b.Close) {
+			t.Errorf("bar %d has inconsistent prices: %+v", i, b)
+		}
+	}
+}
+
+//=============================================================================
+
+func testAccounts(t *testing.T, s *Setup) {
+	if !s.Adapter.GetInfo().SupportsBroker || s.Fixture.Accounts == nil {
+		t.Skip("no broker support or no accounts in the fixture")
+	}
+
+	ctx := Connect(t, s)
+
+	list,err := ctx.GetAccounts()
+	if err != nil {
+		t.Fatalf("GetAccounts failed: %v", err)
+	}
+
+	for _,code := range s.Fixture.Accounts {
+		if !slices.ContainsFunc(list, func(a *adapter.Account) bool { return a.Code == code }) {
+			t.Errorf("account %v not returned: %d accounts found", code, len(list))
+		}
+	}
+}
+
+//=============================================================================
+
+func testOrders(t *testing.T, s *Setup) {
+	if !s.Adapter.GetInfo().SupportsBroker {
+		t.Skip("no broker support")
+	}
+
+	ctx := Connect(t, s)
+
+	list,err := ctx.GetOrders()
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	for _,o := range list {
+		if o.Id == "" || o.Symbol == "" {
+			t.Errorf("order without id or symbol: %+v", o)
+		}
+	}
+
+	for _,id := range s.Fixture.Orders {
+		if findOrder(list, id) == nil {
+			t.Errorf("order %v not returned: %d orders found", id, len(list))
+		}
+	}
+
+	if s.Fixture.NewOrder == nil {
+		return
+	}
+
+	//--- Place & cancel
+
+	no := *s.Fixture.NewOrder
+	err = no.Validate()
+	if err != nil {
+		t.Fatalf("the fixture order is not valid: %v", err)
+	}
+
+	po,err := ctx.PlaceOrder(&no)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	if po.Id == "" || !po.IsOpen() {
+		t.Fatalf("a placed order must have an id and be open: %+v", po)
+	}
+
+	list,err = ctx.GetOrders()
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	if findOrder(list, po.Id) == nil {
+		t.Fatalf("placed order %v not returned by GetOrders", po.Id)
+	}
+
+	err = ctx.CancelOrder(po.Id)
+	if err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+
+	list,err = ctx.GetOrders()
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	if o := findOrder(list, po.Id); o != nil && o.IsOpen() {
+		t.Errorf("cancelled order %v is still open: %v", po.Id, o.Status)
+	}
+}
+
+//=============================================================================
+
+func testPositions(t *testing.T, s *Setup) {
+	if !s.Adapter.GetInfo().SupportsBroker {
+		t.Skip("no broker support")
+	}
+
+	ctx := Connect(t, s)
+
+	list,err := ctx.GetPositions()
+	if err != nil {
+		t.Fatalf("GetPositions failed: %v", err)
+	}
+
+	for _,p := range list {
+		if p.Account == "" || p.Symbol == "" || p.Quantity == 0 {
+			t.Errorf("bad position: %+v", p)
+		}
+	}
+
+	for _,symbol := range s.Fixture.Positions {
+		if !slices.ContainsFunc(list, func(p *adapter.Position) bool { return p.Symbol == symbol }) {
+			t.Errorf("position on %v not returned: %d positions found", symbol, len(list))
+		}
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func findOrder(list []*adapter.Order, id string) *adapter.Order {
+	for _,o := range list {
+		if o.Id == id {
+			return o
+		}
+	}
+
+	return nil
+}
+
+//=============================================================================
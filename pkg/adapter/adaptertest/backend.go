@@ -0,0 +1,106 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adaptertest
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+//===
+//=== Fake backend: a TLS server that receives the requests sent to any host.
+//=== Handlers can dispatch on r.Host, which keeps the original broker host
+//===
+//=============================================================================
+
+type Backend struct {
+	*httptest.Server
+}
+
+//=============================================================================
+//--- The test server certificate is valid for example.com, which is used to
+//--- verify all hosts
+
+func NewBackend(t *testing.T, handler http.Handler) *Backend {
+	server := httptest.NewTLSServer(handler)
+	addr   := server.Listener.Addr().String()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+
+	dialer := &net.Dialer{}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		TLSClientConfig: &tls.Config{
+			RootCAs   : pool,
+			ServerName: "example.com",
+		},
+	}
+
+	old := adapter.SetHttpTransport(transport)
+
+	t.Cleanup(func() {
+		adapter.SetHttpTransport(old)
+		transport.CloseIdleConnections()
+		server.Close()
+	})
+
+	return &Backend{ server }
+}
+
+//=============================================================================
+//===
+//=== Helpers for fake handlers
+//===
+//=============================================================================
+
+func WriteJson(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+//=============================================================================
+
+func WriteHtml(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_,_ = w.Write([]byte(body))
+}
+
+//=============================================================================
@@ -0,0 +1,187 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//=============================================================================
+//===
+//=== Aggregation of 1-minute bars into higher timeframes:
+//===
+//===    <n>m     n minutes (n must divide a day)
+//===    <n>h     n hours
+//===    1d       one bar per trading session if the request has a calendar,
+//===             otherwise per UTC day
+//===    session  one bar per trading session (needs a calendar)
+//===
+//=== Like the source bars, aggregated bars are timestamped at their close.
+//=== Intraday bars are aligned to the UTC clock and never span two sessions
+//===
+//=============================================================================
+
+const TimeframeSession = "session"
+
+//=============================================================================
+
+type Timeframe struct {
+	Code    string
+	Minutes int   // 0 for daily and session bars
+	Session bool  // a calendar is required
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func ParseTimeframe(value string) (*Timeframe, error) {
+	code := strings.ToLower(value)
+
+	switch code {
+		case TimeframeSession:
+			return &Timeframe{ Code: code, Session: true }, nil
+		case "d", "1d":
+			return &Timeframe{ Code: code }, nil
+	}
+
+	if len(code) < 2 {
+		return nil, errors.New("invalid timeframe : "+ value)
+	}
+
+	n, err := strconv.Atoi(code[:len(code)-1])
+	if err != nil || n < 1 {
+		return nil, errors.New("invalid timeframe : "+ value)
+	}
+
+	switch code[len(code)-1] {
+		case 'm':
+		case 'h':
+			n *= 60
+		default:
+			return nil, errors.New("invalid timeframe unit (expected m, h, d or session) : "+ value)
+	}
+
+	if (24*60) % n != 0 {
+		return nil, errors.New("timeframe must divide a day : "+ value)
+	}
+
+	return &Timeframe{ Code: code, Minutes: n }, nil
+}
+
+//=============================================================================
+//--- Bars must be sorted. If sessions are given, bars outside them must have
+//--- been removed
+
+func AggregateBars(bars []*PriceBar, tf *Timeframe, sessions []*TradingDay) []*PriceBar {
+	list := []*PriceBar{}
+	si   := 0
+
+	var curr *PriceBar
+
+	for _, b := range bars {
+		end := tf.getBucketEnd(b.TimeStamp)
+
+		if sessions != nil {
+			for si < len(sessions) && b.TimeStamp.After(*sessions[si].End) {
+				si++
+			}
+
+			if si == len(sessions) {
+				break
+			}
+
+			if tf.IsDaily() || end.After(*sessions[si].End) {
+				end = *sessions[si].End
+			}
+		}
+
+		if curr == nil || !curr.TimeStamp.Equal(end) {
+			curr = &PriceBar{
+				TimeStamp: end,
+				Open     : b.Open,
+				High     : b.High,
+				Low      : b.Low,
+			}
+			list = append(list, curr)
+		}
+
+		curr.High          = max(curr.High, b.High)
+		curr.Low           = min(curr.Low,  b.Low)
+		curr.Close         = b.Close
+		curr.UpVolume     += b.UpVolume
+		curr.DownVolume   += b.DownVolume
+		curr.UpTicks      += b.UpTicks
+		curr.DownTicks    += b.DownTicks
+		curr.OpenInterest  = b.OpenInterest
+	}
+
+	return list
+}
+
+//=============================================================================
+//===
+//=== Methods
+//===
+//=============================================================================
+
+func (tf *Timeframe) IsDaily() bool {
+	return tf.Minutes == 0
+}
+
+//=============================================================================
+//--- Unit and interval that describe the aggregated bars
+
+func (tf *Timeframe) GetUnit() (BarUnit, int) {
+	if tf.IsDaily() {
+		return BarUnitDaily, 1
+	}
+
+	return BarUnitMinute, tf.Minutes
+}
+
+//=============================================================================
+
+func (tf *Timeframe) getBucketEnd(ts time.Time) time.Time {
+	size := 24*time.Hour
+	if !tf.IsDaily() {
+		size = time.Duration(tf.Minutes) * time.Minute
+	}
+
+	end := ts.Truncate(size)
+	if !end.Equal(ts) {
+		end = end.Add(size)
+	}
+
+	return end
+}
+
+//=============================================================================
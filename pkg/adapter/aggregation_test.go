@@ -0,0 +1,108 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"testing"
+	"time"
+)
+
+//=============================================================================
+
+func TestParseTimeframe(t *testing.T) {
+	for value, minutes := range map[string]int{ "5m": 5, "15M": 15, "1h": 60, "4h": 240, "1d": 0, "session": 0 } {
+		tf, err := ParseTimeframe(value)
+		if err != nil || tf.Minutes != minutes {
+			t.Errorf("%s: expected %d minutes, got %v (error: %v)", value, minutes, tf, err)
+		}
+	}
+
+	for _, value := range []string{ "", "m", "0m", "7m", "5x", "2d" } {
+		if _, err := ParseTimeframe(value); err == nil {
+			t.Errorf("%s: expected an error", value)
+		}
+	}
+}
+
+//=============================================================================
+
+func TestAggregateBars(t *testing.T) {
+	start := time.Date(2025, 1, 2, 14, 31, 0, 0, time.UTC)
+
+	var bars []*PriceBar
+	for i := 0; i < 10; i++ {
+		p := 100 + float64(i)
+		bars = append(bars, &PriceBar{
+			TimeStamp   : start.Add(time.Duration(i) * time.Minute),
+			Open        : p,
+			High        : p + 1,
+			Low         : p - 1,
+			Close       : p + 0.5,
+			UpVolume    : 1,
+			DownVolume  : 2,
+			UpTicks     : 3,
+			DownTicks   : 4,
+			OpenInterest: i,
+		})
+	}
+
+	//--- Bars closing from 14:31 to 14:40 fall into the 14:35 and 14:40 buckets
+
+	tf,_ := ParseTimeframe("5m")
+	list := AggregateBars(bars, tf, nil)
+
+	if len(list) != 2 {
+		t.Fatalf("expected 2 bars, got %d", len(list))
+	}
+
+	b := list[0]
+	if !b.TimeStamp.Equal(start.Add(4*time.Minute)) || b.Open != 100 || b.High != 105 || b.Low != 99 || b.Close != 104.5 {
+		t.Errorf("unexpected prices: %+v", *b)
+	}
+
+	if b.UpVolume != 5 || b.DownVolume != 10 || b.UpTicks != 15 || b.DownTicks != 20 || b.OpenInterest != 4 {
+		t.Errorf("unexpected volumes: %+v", *b)
+	}
+
+	//--- A session closing at 14:38 cuts the second bucket
+
+	sessionEnd   := start.Add(7*time.Minute)
+	sessionStart := start.Add(-time.Hour)
+	sessions     := []*TradingDay{{ Date: 20250102, Trading: true, Start: &sessionStart, End: &sessionEnd }}
+
+	list = AggregateBars(bars[:8], tf, sessions)
+	if len(list) != 2 || !list[1].TimeStamp.Equal(sessionEnd) {
+		t.Errorf("expected the last bar to close with the session, got %d bars", len(list))
+	}
+
+	tf,_ = ParseTimeframe(TimeframeSession)
+	list = AggregateBars(bars[:8], tf, sessions)
+	if len(list) != 1 || list[0].Open != 100 || list[0].Close != 107.5 || list[0].UpVolume != 8 {
+		t.Errorf("expected a single session bar, got %d bars", len(list))
+	}
+}
+
+//=============================================================================
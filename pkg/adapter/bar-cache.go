@@ -0,0 +1,307 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== On-disk cache of intraday price bars, one file per day:
+//===
+//===    <path>/<adapter>/<symbol>/<unit>-<interval>/<yyyymmdd>.json
+//===
+//=== Only completed days are cached, and they are kept until invalidated.
+//=== Days are in UTC, like the adapters' requests
+//===
+//=============================================================================
+//--- Time to wait after the end of a day before caching it, so that late
+//--- corrections from the broker are included
+
+const BarCacheSettleTime = time.Hour
+
+//=============================================================================
+
+var barCache = struct {
+	sync.RWMutex
+	path string
+}{}
+
+//=============================================================================
+
+type barCacheKey struct {
+	adapter  string
+	symbol   string
+	unit     BarUnit
+	interval int
+	date     datatype.IntDate
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func InitBarCache(cfg *app.BarCache) {
+	barCache.Lock()
+	defer barCache.Unlock()
+
+	barCache.path = cfg.Path
+}
+
+//=============================================================================
+
+func IsBarCacheEnabled() bool {
+	return barCachePath() != ""
+}
+
+//=============================================================================
+//--- Removes the cached days matching the filter. Empty strings and zero dates
+//--- match everything. Returns the number of removed days
+
+func InvalidateBarCache(adapterCode, symbol string, from, to datatype.IntDate) (int, error) {
+	path := barCachePath()
+	if path == "" {
+		return 0, errors.New("the bar cache is not enabled")
+	}
+
+	adapters,err := barCacheDirs(path, adapterCode)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, adapterDir := range adapters {
+		symbols,err := barCacheDirs(adapterDir, symbol)
+		if err != nil {
+			return count, err
+		}
+
+		for _, symbolDir := range symbols {
+			units,err := barCacheDirs(symbolDir, "")
+			if err != nil {
+				return count, err
+			}
+
+			for _, unitDir := range units {
+				n,err := invalidateDays(unitDir, from, to)
+				count += n
+				if err != nil {
+					return count, err
+				}
+			}
+		}
+	}
+
+	return count, nil
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func barCachePath() string {
+	barCache.RLock()
+	defer barCache.RUnlock()
+
+	return barCache.path
+}
+
+//=============================================================================
+//--- Daily and weekly bars span several days, so they are not cached. The key
+//--- has no connection, so adapters whose data depends on it are not cached
+
+func isBarCacheable(info *Info, rq *PriceBarsRequest) bool {
+	if !IsBarCacheEnabled() || !info.SharedData {
+		return false
+	}
+
+	return rq.Unit.IsIntraday()
+}
+
+//=============================================================================
+
+func isDayCompleted(date datatype.IntDate, now time.Time) bool {
+	end := IntDateToTime(AddDays(date, 1), time.UTC).Add(BarCacheSettleTime)
+	return !now.Before(end)
+}
+
+//=============================================================================
+
+func newBarCacheKey(adapterCode string, rq *PriceBarsRequest, date datatype.IntDate) *barCacheKey {
+	return &barCacheKey{
+		adapter : adapterCode,
+		symbol  : rq.Symbol,
+		unit    : rq.Unit,
+		interval: rq.Interval,
+		date    : date,
+	}
+}
+
+//=============================================================================
+
+func (k *barCacheKey) file() string {
+	return filepath.Join(barCachePath(),
+		escapePathElement(k.adapter),
+		escapePathElement(k.symbol),
+		escapePathElement(string(k.unit)) +"-"+ strconv.Itoa(k.interval),
+		strconv.Itoa(int(k.date)) +".json")
+}
+
+//=============================================================================
+
+func readCachedBars(k *barCacheKey) (*PriceBars, bool) {
+	data,err := os.ReadFile(k.file())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("readCachedBars: Cannot read a cached day", "file", k.file(), "error", err.Error())
+		}
+		return nil, false
+	}
+
+	var pb PriceBars
+	err = json.Unmarshal(data, &pb)
+	if err != nil {
+		slog.Warn("readCachedBars: Corrupted cached day", "file", k.file(), "error", err.Error())
+		return nil, false
+	}
+
+	return &pb, true
+}
+
+//=============================================================================
+//--- A failed write only means that the day will be asked again to the broker
+
+func writeCachedBars(k *barCacheKey, pb *PriceBars) {
+	data,err := json.Marshal(pb)
+	if err == nil {
+		file := k.file()
+		err = os.MkdirAll(filepath.Dir(file), 0o755)
+		if err == nil {
+			tmp := file +".tmp"
+			err = os.WriteFile(tmp, data, 0o644)
+			if err == nil {
+				err = os.Rename(tmp, file)
+			}
+		}
+	}
+
+	if err != nil {
+		slog.Warn("writeCachedBars: Cannot cache a day", "file", k.file(), "error", err.Error())
+	}
+}
+
+//=============================================================================
+//--- Returns the subdirectories of dir, or only the given one if name is set
+
+func barCacheDirs(dir, name string) ([]string, error) {
+	if name != "" {
+		path := filepath.Join(dir, escapePathElement(name))
+		if _,err := os.Stat(path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+			return nil, err
+		}
+
+		return []string{ path }, nil
+	}
+
+	entries,err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var list []string
+	for _, e := range entries {
+		if e.IsDir() {
+			list = append(list, filepath.Join(dir, e.Name()))
+		}
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+
+func invalidateDays(dir string, from, to datatype.IntDate) (int, error) {
+	entries,err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, e := range entries {
+		day,err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil || e.IsDir() {
+			continue
+		}
+
+		date := datatype.IntDate(day)
+		if (from != 0 && date < from) || (to != 0 && date > to) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+//=============================================================================
+//--- Symbols can contain '/' (like currencies) and must not escape the cache
+
+func escapePathElement(name string) string {
+	return strings.ReplaceAll(url.PathEscape(name), ".", "%2E")
+}
+
+//=============================================================================
@@ -0,0 +1,517 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== Exchange trading calendars. A session template gives the local open and
+//=== close time of a trading day: when the open is not before the close (like
+//=== CME Globex, 17:00-16:00 Chicago time) the session starts the previous
+//=== calendar day. Trading days are Monday to Friday, minus holidays.
+//===
+//=== Holidays and early closes are computed from built-in rules and can be
+//=== extended (or new exchanges added) with files in the configured directory:
+//===
+//===    <path>/<EXCHANGE>.json
+//===
+//=============================================================================
+//--- Requests with this calendar take the exchange from the symbol
+
+const CalendarAuto = "auto"
+
+//=============================================================================
+
+type SessionTemplate struct {
+	Timezone string `json:"timezone"`  // IANA name, like America/Chicago
+	Open     string `json:"open"`      // local time, hh:mm
+	Close    string `json:"close"`     // local time, hh:mm
+}
+
+//=============================================================================
+
+type TradingDay struct {
+	Date       int        `json:"date"`
+	Trading    bool       `json:"trading"`
+	Start      *time.Time `json:"start,omitempty"`
+	End        *time.Time `json:"end,omitempty"`
+	EarlyClose bool       `json:"earlyClose"`
+	Holiday    string     `json:"holiday,omitempty"`
+}
+
+//=============================================================================
+
+type TradingCalendar struct {
+	sync.Mutex
+	Exchange    string
+	Template    SessionTemplate
+	location    *time.Location
+	open        time.Duration
+	close       time.Duration
+	rules       holidayRules
+	holidays    map[datatype.IntDate]string   // date --> name (from config)
+	earlyCloses map[datatype.IntDate]string   // date --> close time (from config)
+	years       map[int]*calendarYear
+}
+
+//=============================================================================
+
+type calendarYear struct {
+	holidays    map[datatype.IntDate]string
+	earlyCloses map[datatype.IntDate]string
+}
+
+//-----------------------------------------------------------------------------
+
+type holidayRules func(year int, cy *calendarYear)
+
+//-----------------------------------------------------------------------------
+
+type calendarFile struct {
+	SessionTemplate
+	Holidays    map[int]string `json:"holidays"`     // yyyymmdd --> name
+	EarlyCloses map[int]string `json:"earlyCloses"`  // yyyymmdd --> hh:mm
+}
+
+//=============================================================================
+
+var calendars = struct {
+	sync.RWMutex
+	exchanges map[string]*TradingCalendar
+}{}
+
+//-----------------------------------------------------------------------------
+//--- Exchange names used by brokers that share the calendar of another one
+
+var exchangeAliases = map[string]string{
+	"GLOBEX": "CME",
+	"ECBOT" : "CBOT",
+	"DTB"   : "EUREX",
+}
+
+//=============================================================================
+//--- Built-in calendars are available even without configuration
+
+func init() {
+	if err := InitCalendars(&app.Calendars{}); err != nil {
+		panic(err)
+	}
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func InitCalendars(cfg *app.Calendars) error {
+	exchanges := map[string]*TradingCalendar{}
+
+	usTemplate    := SessionTemplate{ Timezone: "America/Chicago", Open: "17:00", Close: "16:00" }
+	eurexTemplate := SessionTemplate{ Timezone: "Europe/Berlin",   Open: "01:10", Close: "22:00" }
+
+	for _, code := range []string{ "CME", "CBOT", "NYMEX", "COMEX" } {
+		exchanges[code] = newTradingCalendar(code, usTemplate, usHolidayRules)
+	}
+
+	exchanges["EUREX"] = newTradingCalendar("EUREX", eurexTemplate, eurexHolidayRules)
+
+	if cfg.Path != "" {
+		if err := loadCalendarFiles(cfg.Path, exchanges); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range exchanges {
+		if err := c.init(); err != nil {
+			return errors.New("bad session template for "+ c.Exchange +": "+ err.Error())
+		}
+	}
+
+	calendars.Lock()
+	calendars.exchanges = exchanges
+	calendars.Unlock()
+
+	return nil
+}
+
+//=============================================================================
+
+func GetTradingCalendar(exchange string) (*TradingCalendar, error) {
+	code := strings.ToUpper(exchange)
+	if alias, ok := exchangeAliases[code]; ok {
+		code = alias
+	}
+
+	calendars.RLock()
+	defer calendars.RUnlock()
+
+	if c, ok := calendars.exchanges[code]; ok {
+		return c, nil
+	}
+
+	return nil, errors.New("no trading calendar for exchange : "+ exchange)
+}
+
+//=============================================================================
+//===
+//=== Methods
+//===
+//=============================================================================
+
+func (c *TradingCalendar) GetTradingDay(date datatype.IntDate) *TradingDay {
+	td := &TradingDay{ Date: int(date) }
+
+	day := IntDateToTime(date, c.location)
+	if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return td
+	}
+
+	cy := c.getYear(date)
+	if name, ok := c.holidays[date]; ok {
+		td.Holiday = name
+		return td
+	}
+
+	if name, ok := cy.holidays[date]; ok {
+		td.Holiday = name
+		return td
+	}
+
+	closeTime := c.close
+	if ec, ok := c.earlyCloses[date]; ok {
+		closeTime, _  = parseClock(ec)
+		td.EarlyClose = true
+	} else if ec, ok := cy.earlyCloses[date]; ok {
+		closeTime, _  = parseClock(ec)
+		td.EarlyClose = true
+	}
+
+	start := atClock(day, c.open)
+	if c.open >= c.close {
+		start = atClock(day.AddDate(0, 0, -1), c.open)
+	}
+
+	end := atClock(day, closeTime)
+
+	td.Trading = true
+	td.Start   = &start
+	td.End     = &end
+
+	return td
+}
+
+//=============================================================================
+
+func (c *TradingCalendar) GetTradingDays(from, to datatype.IntDate) []*TradingDay {
+	var list []*TradingDay
+
+	for day := from; day <= to; day = AddDays(day, 1) {
+		list = append(list, c.GetTradingDay(day))
+	}
+
+	return list
+}
+
+//=============================================================================
+//===
+//=== Private methods
+//===
+//=============================================================================
+
+func (c *TradingCalendar) init() error {
+	loc, err := time.LoadLocation(c.Template.Timezone)
+	if err != nil {
+		return err
+	}
+
+	c.location = loc
+
+	if c.open, err = parseClock(c.Template.Open); err != nil {
+		return err
+	}
+
+	if c.close, err = parseClock(c.Template.Close); err != nil {
+		return err
+	}
+
+	for date, ec := range c.earlyCloses {
+		if _, err = parseClock(ec); err != nil {
+			return errors.New("bad early close at "+ date.String() +": "+ err.Error())
+		}
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func (c *TradingCalendar) getYear(date datatype.IntDate) *calendarYear {
+	c.Lock()
+	defer c.Unlock()
+
+	year := int(date) / 10000
+	cy, ok := c.years[year]
+	if !ok {
+		cy = &calendarYear{
+			holidays   : map[datatype.IntDate]string{},
+			earlyCloses: map[datatype.IntDate]string{},
+		}
+
+		if c.rules != nil {
+			c.rules(year, cy)
+		}
+
+		c.years[year] = cy
+	}
+
+	return cy
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func newTradingCalendar(exchange string, template SessionTemplate, rules holidayRules) *TradingCalendar {
+	return &TradingCalendar{
+		Exchange   : exchange,
+		Template   : template,
+		rules      : rules,
+		holidays   : map[datatype.IntDate]string{},
+		earlyCloses: map[datatype.IntDate]string{},
+		years      : map[int]*calendarYear{},
+	}
+}
+
+//=============================================================================
+//--- A file for a built-in exchange adds to its holidays and can change its
+//--- session template. A file for a new exchange must provide the template
+
+func loadCalendarFiles(path string, exchanges map[string]*TradingCalendar) error {
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		var cf calendarFile
+		if err = json.Unmarshal(data, &cf); err != nil {
+			return errors.New("bad calendar file "+ file +": "+ err.Error())
+		}
+
+		code := strings.ToUpper(strings.TrimSuffix(filepath.Base(file), ".json"))
+		c, ok := exchanges[code]
+		if !ok {
+			c = newTradingCalendar(code, cf.SessionTemplate, nil)
+			exchanges[code] = c
+		} else {
+			if cf.Timezone != "" {
+				c.Template.Timezone = cf.Timezone
+			}
+			if cf.Open != "" {
+				c.Template.Open = cf.Open
+			}
+			if cf.Close != "" {
+				c.Template.Close = cf.Close
+			}
+		}
+
+		for date, name := range cf.Holidays {
+			c.holidays[datatype.IntDate(date)] = name
+		}
+
+		for date, ec := range cf.EarlyCloses {
+			c.earlyCloses[datatype.IntDate(date)] = ec
+		}
+
+		slog.Info("loadCalendarFiles: Loaded trading calendar", "exchange", code, "holidays", len(cf.Holidays), "earlyCloses", len(cf.EarlyCloses))
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func parseClock(value string) (time.Duration, error) {
+	h, m, found := strings.Cut(value, ":")
+	hours,   err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+
+	if !found || err1 != nil || err2 != nil || hours < 0 || hours > 24 || minutes < 0 || minutes > 59 {
+		return 0, errors.New("invalid time (expected hh:mm) : "+ value)
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+//=============================================================================
+//--- Local wall clock time, that is not midnight plus a duration on DST changes
+
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+//=============================================================================
+//===
+//=== Holiday rules
+//===
+//=============================================================================
+//--- CME Group (Globex): closed on New Year, Good Friday and Christmas, early
+//--- close on the other US federal holidays and around Thanksgiving and Christmas
+
+func usHolidayRules(year int, cy *calendarYear) {
+	newYear := newIntDate(year, time.January, 1)
+	if IntDateToTime(newYear, time.UTC).Weekday() != time.Saturday {
+		cy.holidays[observed(newYear)] = "New Year's Day"
+	}
+
+	cy.holidays[AddDays(easter(year), -2)] = "Good Friday"
+
+	christmas := observed(newIntDate(year, time.December, 25))
+	cy.holidays[christmas] = "Christmas"
+
+	//--- MLK, Presidents', Memorial, Independence, Labor and Thanksgiving days
+
+	for _, d := range []datatype.IntDate{
+		nthWeekday (year, time.January,   time.Monday,   3),
+		nthWeekday (year, time.February,  time.Monday,   3),
+		lastWeekday(year, time.May,       time.Monday),
+		observed(newIntDate(year, time.July, 4)),
+		nthWeekday (year, time.September, time.Monday,   1),
+		nthWeekday (year, time.November,  time.Thursday, 4),
+	} {
+		cy.earlyCloses[d] = "12:00"
+	}
+
+	if year >= 2022 {
+		cy.earlyCloses[observed(newIntDate(year, time.June, 19))] = "12:00"
+	}
+
+	cy.earlyCloses[AddDays(nthWeekday(year, time.November, time.Thursday, 4), 1)] = "12:15"
+
+	christmasEve := newIntDate(year, time.December, 24)
+	if christmasEve != christmas {
+		cy.earlyCloses[christmasEve] = "12:15"
+	}
+}
+
+//=============================================================================
+
+func eurexHolidayRules(year int, cy *calendarYear) {
+	e := easter(year)
+
+	cy.holidays[newIntDate(year, time.January,  1)]  = "New Year's Day"
+	cy.holidays[AddDays(e, -2)]                      = "Good Friday"
+	cy.holidays[AddDays(e,  1)]                      = "Easter Monday"
+	cy.holidays[newIntDate(year, time.May,      1)]  = "Labour Day"
+	cy.holidays[newIntDate(year, time.December, 24)] = "Christmas Eve"
+	cy.holidays[newIntDate(year, time.December, 25)] = "Christmas"
+	cy.holidays[newIntDate(year, time.December, 26)] = "Boxing Day"
+	cy.holidays[newIntDate(year, time.December, 31)] = "New Year's Eve"
+}
+
+//=============================================================================
+
+func newIntDate(year int, month time.Month, day int) datatype.IntDate {
+	return datatype.IntDate(year*10000 + int(month)*100 + day)
+}
+
+//=============================================================================
+//--- Holidays falling on Saturday are observed on Friday, on Sunday on Monday
+
+func observed(d datatype.IntDate) datatype.IntDate {
+	switch IntDateToTime(d, time.UTC).Weekday() {
+		case time.Saturday:
+			return AddDays(d, -1)
+		case time.Sunday:
+			return AddDays(d, 1)
+	}
+
+	return d
+}
+
+//=============================================================================
+
+func nthWeekday(year int, month time.Month, wd time.Weekday, n int) datatype.IntDate {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	delta := (int(wd) - int(first.Weekday()) + 7) % 7
+	return TimeToIntDate(first.AddDate(0, 0, delta + (n-1)*7))
+}
+
+//=============================================================================
+
+func lastWeekday(year int, month time.Month, wd time.Weekday) datatype.IntDate {
+	last  := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+	delta := (int(last.Weekday()) - int(wd) + 7) % 7
+	return TimeToIntDate(last.AddDate(0, 0, -delta))
+}
+
+//=============================================================================
+//--- Easter Sunday (anonymous Gregorian algorithm)
+
+func easter(year int) datatype.IntDate {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+
+	month := (h + l - 7*m + 114) / 31
+	day   := (h + l - 7*m + 114) % 31 + 1
+
+	return newIntDate(year, time.Month(month), day)
+}
+
+//=============================================================================
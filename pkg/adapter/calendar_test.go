@@ -0,0 +1,137 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+
+func TestCalendarSessions(t *testing.T) {
+	cme := getTestCalendar(t, "GLOBEX")
+
+	//--- Monday session opens on Sunday at 17:00 Chicago time (first day of DST)
+
+	checkSession(t, cme, 20250310, "2025-03-09T22:00:00Z", "2025-03-10T21:00:00Z", false)
+
+	//--- Thanksgiving closes early
+
+	checkSession(t, cme, 20251127, "2025-11-26T23:00:00Z", "2025-11-27T18:00:00Z", true)
+
+	checkClosed(t, cme, 20250308, "")
+	checkClosed(t, cme, 20250418, "Good Friday")
+	checkClosed(t, cme, 20251225, "Christmas")
+
+	eurex := getTestCalendar(t, "EUREX")
+	checkSession(t, eurex, 20250422, "2025-04-21T23:10:00Z", "2025-04-22T20:00:00Z", false)
+	checkClosed (t, eurex, 20250421, "Easter Monday")
+}
+
+//=============================================================================
+
+func TestCalendarFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"CME.json"  : `{ "holidays": { "20250310": "Test closure" }, "earlyCloses": { "20250311": "12:00" } }`,
+		"OTHER.json": `{ "timezone": "Asia/Tokyo", "open": "08:45", "close": "15:45" }`,
+	}
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := InitCalendars(&app.Calendars{ Path: dir }); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = InitCalendars(&app.Calendars{}) })
+
+	cme := getTestCalendar(t, "CME")
+	checkClosed (t, cme, 20250310, "Test closure")
+	checkSession(t, cme, 20250311, "2025-03-10T22:00:00Z", "2025-03-11T17:00:00Z", true)
+	checkClosed (t, cme, 20250418, "Good Friday")
+
+	other := getTestCalendar(t, "other")
+	checkSession(t, other, 20250310, "2025-03-09T23:45:00Z", "2025-03-10T06:45:00Z", false)
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func getTestCalendar(t *testing.T, exchange string) *TradingCalendar {
+	t.Helper()
+
+	c, err := GetTradingCalendar(exchange)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c
+}
+
+//=============================================================================
+
+func checkSession(t *testing.T, c *TradingCalendar, date datatype.IntDate, start, end string, earlyClose bool) {
+	t.Helper()
+
+	td := c.GetTradingDay(date)
+	if !td.Trading {
+		t.Fatalf("%v: expected a trading day, got holiday '%s'", date, td.Holiday)
+	}
+
+	s := td.Start.UTC().Format(time.RFC3339)
+	e := td.End  .UTC().Format(time.RFC3339)
+
+	if s != start || e != end || td.EarlyClose != earlyClose {
+		t.Errorf("%v: expected session %s - %s (early close: %v), got %s - %s (%v)", date, start, end, earlyClose, s, e, td.EarlyClose)
+	}
+}
+
+//=============================================================================
+
+func checkClosed(t *testing.T, c *TradingCalendar, date datatype.IntDate, holiday string) {
+	t.Helper()
+
+	td := c.GetTradingDay(date)
+	if td.Trading || td.Holiday != holiday {
+		t.Errorf("%v: expected closed day '%s', got trading: %v, holiday: '%s'", date, holiday, td.Trading, td.Holiday)
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,718 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== Record/replay of the HTTP traffic of a connection. In record mode the
+//=== sanitized request/response pairs are saved into a cassette file, in
+//=== replay mode the adapter is served entirely from the cassette. Cassettes
+//=== are enabled per connection through the config params below and are
+//=== kept per user and connection:
+//===
+//===    <path>/<username>/<connection>/<name>.jsonl
+//===
+//=== so a user can never replay (and read) another user's session. The file
+//=== has one interaction per line, appended as soon as it completes. Bodies
+//=== are sanitized: JSON and form fields through the deny lists, input values
+//=== of HTML pages are always redacted and other bodies are not recorded
+//===
+//=============================================================================
+
+const (
+	ParamCassetteMode = "cassetteMode"
+	ParamCassetteName = "cassetteName"
+)
+
+//-----------------------------------------------------------------------------
+
+type CassetteMode string
+
+const (
+	CassetteModeOff    CassetteMode = "off"
+	CassetteModeRecord CassetteMode = "record"
+	CassetteModeReplay CassetteMode = "replay"
+)
+
+//-----------------------------------------------------------------------------
+//--- Bodies are truncated to this size (streams can be endless)
+
+const MaxCassetteBodySize = 8 * 1024 * 1024
+
+//=============================================================================
+
+var ParamDefCassetteMode = &ParamDef{
+	Name     : ParamCassetteMode,
+	Type     : ParamTypeString,
+	DefValue : string(CassetteModeOff),
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 8,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var ParamDefCassetteName = &ParamDef{
+	Name     : ParamCassetteName,
+	Type     : ParamTypeString,
+	DefValue : "",
+	Nullable : true,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//=============================================================================
+
+var cassettes = struct {
+	sync.RWMutex
+	path string
+}{}
+
+var cassetteNameRegex = regexp.MustCompile("^[A-Za-z0-9_-]+$")
+var htmlInputValueRegex = regexp.MustCompile(`(?is)(<input\b[^>]*?\bvalue\s*=\s*)("[^"]*"|'[^']*'|[^\s>]+)`)
+
+//--- Redacted values change with the process key (and are redacted again when
+//--- a replayed value is sent back), so urls are matched without them
+
+var redactedValueRegex = regexp.MustCompile(`(\[|%5B)redacted(:|%3A)[0-9a-f]+(\]|%5D)`)
+
+//=============================================================================
+
+type Cassette struct {
+	sync.Mutex
+	mode         CassetteMode
+	name         string
+	username     string
+	connection   string
+	loaded       bool
+	err          error
+	interactions []*Interaction
+	used         []bool
+}
+
+//=============================================================================
+//--- Cassettes recorded before JSON lines (<name>.json). They can still be
+//--- replayed
+
+type CassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+//=============================================================================
+
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+//=============================================================================
+
+type CassetteRequest struct {
+	Method string        `json:"method"`
+	Url    string        `json:"url"`
+	Header http.Header   `json:"header,omitempty"`
+	Body   *CassetteBody `json:"body,omitempty"`
+}
+
+//=============================================================================
+
+type CassetteResponse struct {
+	StatusCode int           `json:"statusCode"`
+	Header     http.Header   `json:"header,omitempty"`
+	Body       *CassetteBody `json:"body,omitempty"`
+}
+
+//=============================================================================
+//--- Text bodies are stored as they are, binary bodies in base64
+
+type CassetteBody struct {
+	Encoding  string `json:"encoding,omitempty"`
+	Data      string `json:"data"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Omitted   bool   `json:"omitted,omitempty"`   // content type not recorded
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func InitCassettes(cfg *app.Cassette) {
+	cassettes.Lock()
+	defer cassettes.Unlock()
+
+	cassettes.path = cfg.Path
+}
+
+//=============================================================================
+//--- Returns nil if the connection does not use a cassette. Errors (like a bad
+//--- name or a missing file) are returned by the first request
+
+func NewCassette(values map[string]any) *Cassette {
+	mode := CassetteMode(strings.ToLower(ParamDefCassetteMode.GetString(values)))
+	if mode == "" || mode == CassetteModeOff {
+		return nil
+	}
+
+	return &Cassette{
+		mode: mode,
+		name: ParamDefCassetteName.GetString(values),
+	}
+}
+
+//=============================================================================
+//===
+//=== Methods
+//===
+//=============================================================================
+//--- Wraps the given transport. A nil cassette returns the transport itself
+
+func (c *Cassette) Wrap(next http.RoundTripper) http.RoundTripper {
+	if c == nil {
+		return next
+	}
+
+	return &cassetteTransport{
+		cassette: c,
+		next    : next,
+	}
+}
+
+//=============================================================================
+//--- Called by the connection context before the first request
+
+func (c *Cassette) SetOwner(username, connectionCode string) {
+	if c == nil {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.username   = username
+	c.connection = connectionCode
+}
+
+//=============================================================================
+
+func (c *Cassette) Mode() CassetteMode {
+	if c == nil {
+		return CassetteModeOff
+	}
+
+	return c.mode
+}
+
+//=============================================================================
+//===
+//=== Private methods
+//===
+//=============================================================================
+//--- Validates the cassette and, in replay mode, loads it. Called on the first
+//--- request. The cassette lock must be held
+
+func (c *Cassette) load() error {
+	if c.loaded {
+		return c.err
+	}
+
+	c.loaded = true
+	c.err    = c.doLoad()
+
+	return c.err
+}
+
+//=============================================================================
+
+func (c *Cassette) doLoad() error {
+	if c.mode != CassetteModeRecord && c.mode != CassetteModeReplay {
+		return errors.New("invalid cassette mode: "+ string(c.mode))
+	}
+
+	if !cassetteNameRegex.MatchString(c.name) {
+		return errors.New("invalid cassette name: "+ c.name)
+	}
+
+	if cassettePath() == "" {
+		return errors.New("cassettes are not enabled (missing cassette path in config)")
+	}
+
+	if c.username == "" || c.connection == "" {
+		return errors.New("cassette without owner: "+ c.name)
+	}
+
+	if c.mode == CassetteModeRecord {
+		slog.Info("Cassette: Recording", "cassette", c.name)
+		return c.create()
+	}
+
+	list, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	c.interactions = list
+	c.used         = make([]bool, len(list))
+
+	slog.Info("Cassette: Replaying", "cassette", c.name, "interactions", len(c.interactions))
+	return nil
+}
+
+//=============================================================================
+
+func (c *Cassette) dir() string {
+	return filepath.Join(cassettePath(), escapePathElement(c.username), escapePathElement(c.connection))
+}
+
+//=============================================================================
+
+func (c *Cassette) file() string {
+	return filepath.Join(c.dir(), c.name +".jsonl")
+}
+
+//=============================================================================
+//--- Starts a new recording, dropping the previous one
+
+func (c *Cassette) create() error {
+	if err := os.MkdirAll(c.dir(), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.file(), nil, 0o600)
+}
+
+//=============================================================================
+//--- Interactions are appended as they complete, so that a crash does not lose
+//--- the session
+
+func (c *Cassette) add(i *Interaction) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.append(i); err != nil {
+		slog.Error("Cassette: Cannot save the interaction", "cassette", c.name, "error", err.Error())
+	}
+}
+
+//=============================================================================
+
+func (c *Cassette) append(i *Interaction) error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(c.file(), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+//=============================================================================
+
+func (c *Cassette) read() ([]*Interaction, error) {
+	f, err := os.Open(c.file())
+	if errors.Is(err, os.ErrNotExist) {
+		return c.readLegacy()
+	}
+	if err != nil {
+		return nil, errors.New("cannot read cassette: "+ err.Error())
+	}
+	defer f.Close()
+
+	var list []*Interaction
+	decoder := json.NewDecoder(f)
+
+	for {
+		var i Interaction
+		err = decoder.Decode(&i)
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, errors.New("invalid cassette: "+ err.Error())
+		}
+
+		list = append(list, &i)
+	}
+}
+
+//=============================================================================
+
+func (c *Cassette) readLegacy() ([]*Interaction, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir(), c.name +".json"))
+	if err != nil {
+		return nil, errors.New("cannot read cassette: "+ err.Error())
+	}
+
+	var cf CassetteFile
+	if err = json.Unmarshal(data, &cf); err != nil {
+		return nil, errors.New("invalid cassette: "+ err.Error())
+	}
+
+	return cf.Interactions, nil
+}
+
+//=============================================================================
+//--- Requests are matched on method and (redacted) url, in recording order
+
+func (c *Cassette) find(rq *http.Request) *Interaction {
+	c.Lock()
+	defer c.Unlock()
+
+	url := matchableUrl(RedactUrl(rq.URL))
+
+	for idx, i := range c.interactions {
+		if !c.used[idx] && i.Request.Method == rq.Method && matchableUrl(i.Request.Url) == url {
+			c.used[idx] = true
+			return i
+		}
+	}
+
+	return nil
+}
+
+//=============================================================================
+//===
+//=== Transport
+//===
+//=============================================================================
+
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+//=============================================================================
+
+func (t *cassetteTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	t.cassette.Lock()
+	err := t.cassette.load()
+	t.cassette.Unlock()
+
+	if err != nil {
+		if rq.Body != nil {
+			_ = rq.Body.Close()
+		}
+		return nil, err
+	}
+
+	if t.cassette.mode == CassetteModeReplay {
+		return t.replay(rq)
+	}
+
+	return t.record(rq)
+}
+
+//=============================================================================
+
+func (t *cassetteTransport) replay(rq *http.Request) (*http.Response, error) {
+	if rq.Body != nil {
+		_ = rq.Body.Close()
+	}
+
+	i := t.cassette.find(rq)
+	if i == nil {
+		return nil, errors.New("cassette "+ t.cassette.name +": no recorded interaction for "+ rq.Method +" "+ RedactUrl(rq.URL))
+	}
+
+	body, err := i.Response.Body.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status       : strconv.Itoa(i.Response.StatusCode) +" "+ http.StatusText(i.Response.StatusCode),
+		StatusCode   : i.Response.StatusCode,
+		Proto        : "HTTP/1.1",
+		ProtoMajor   : 1,
+		ProtoMinor   : 1,
+		Header       : i.Response.Header.Clone(),
+		Body         : io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request      : rq,
+	}, nil
+}
+
+//=============================================================================
+
+func (t *cassetteTransport) record(rq *http.Request) (*http.Response, error) {
+	var rqBody []byte
+
+	if rq.Body != nil {
+		data, err := io.ReadAll(rq.Body)
+		_ = rq.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		rqBody  = data
+		rq      = rq.Clone(rq.Context())
+		rq.Body = io.NopCloser(bytes.NewReader(data))
+	}
+
+	res, err := t.next.RoundTrip(rq)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Interaction{
+		Request: CassetteRequest{
+			Method: rq.Method,
+			Url   : RedactUrl(rq.URL),
+			Header: redactCassetteHeader(rq.Header),
+			Body  : newCassetteBody(rqBody, rq.Header.Get("Content-Type"), false),
+		},
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header    : redactCassetteHeader(res.Header),
+		},
+	}
+
+	//--- The interaction is saved when the body is closed: this works for
+	//--- streams too, that are read incrementally
+
+	res.Body = &recordingBody{
+		ReadCloser : res.Body,
+		cassette   : t.cassette,
+		interaction: i,
+		contentType: res.Header.Get("Content-Type"),
+	}
+
+	return res, nil
+}
+
+//=============================================================================
+//===
+//=== Recording body
+//===
+//=============================================================================
+
+type recordingBody struct {
+	io.ReadCloser
+	sync.Once
+	cassette    *Cassette
+	interaction *Interaction
+	contentType string
+	buffer      bytes.Buffer
+	truncated   bool
+}
+
+//=============================================================================
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if n > 0 {
+		room := MaxCassetteBodySize - b.buffer.Len()
+		if room >= n {
+			b.buffer.Write(p[:n])
+		} else {
+			b.buffer.Write(p[:max(room, 0)])
+			b.truncated = true
+		}
+	}
+
+	return n, err
+}
+
+//=============================================================================
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.Do(func() {
+		b.interaction.Response.Body = newCassetteBody(b.buffer.Bytes(), b.contentType, b.truncated)
+		b.cassette.add(b.interaction)
+	})
+
+	return err
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func cassettePath() string {
+	cassettes.RLock()
+	defer cassettes.RUnlock()
+
+	return cassettes.path
+}
+
+//=============================================================================
+//--- Cookies keep their structure (name and attributes), so that the cookie
+//--- jar still works during a replay
+
+func redactCassetteHeader(header http.Header) http.Header {
+	res := http.Header{}
+
+	for name, values := range header {
+		for _, value := range values {
+			switch http.CanonicalHeaderKey(name) {
+				case "Set-Cookie":
+					if c, err := http.ParseSetCookie(value); err == nil {
+						c.Value = RedactCookie(c.Name, c.Value)
+						value   = c.String()
+					} else {
+						value = RedactValue(value)
+					}
+
+				case "Cookie":
+					var list []string
+					if cookies, err := http.ParseCookie(value); err == nil {
+						for _, c := range cookies {
+							list = append(list, c.Name +"="+ RedactCookie(c.Name, c.Value))
+						}
+					}
+					value = strings.Join(list, "; ")
+
+				default:
+					value = RedactHeader(name, value)
+			}
+
+			res.Add(name, value)
+		}
+	}
+
+	return res
+}
+
+//=============================================================================
+//--- JSON bodies are sanitized through the redaction deny lists. Form values
+//--- and HTML input values (like hidden login tokens) are always redacted:
+//--- replays only need the page structure. Other content types are not kept
+
+func newCassetteBody(data []byte, contentType string, truncated bool) *CassetteBody {
+	if len(data) == 0 {
+		return nil
+	}
+
+	switch {
+		case strings.Contains(contentType, "json"):
+			if redacted, ok := RedactJson(data); ok {
+				data = redacted
+			}
+
+		case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+			data = []byte(redactFormValues(string(data)))
+
+		case strings.HasPrefix(contentType, "text/html"):
+			data = redactHtmlInputs(data)
+
+		default:
+			return &CassetteBody{ Omitted: true }
+	}
+
+	if utf8.Valid(data) {
+		return &CassetteBody{ Data: string(data), Truncated: truncated }
+	}
+
+	return &CassetteBody{ Encoding: "base64", Data: base64.StdEncoding.EncodeToString(data), Truncated: truncated }
+}
+
+//=============================================================================
+
+func (b *CassetteBody) decode() ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	if b.Encoding == "base64" {
+		return base64.StdEncoding.DecodeString(b.Data)
+	}
+
+	return []byte(b.Data), nil
+}
+
+//=============================================================================
+
+func matchableUrl(url string) string {
+	return redactedValueRegex.ReplaceAllString(url, RedactedValue)
+}
+
+//=============================================================================
+
+func redactFormValues(body string) string {
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return RedactValue(body)
+	}
+
+	for name, list := range values {
+		for i, v := range list {
+			list[i] = RedactValue(v)
+		}
+		values[name] = list
+	}
+
+	return values.Encode()
+}
+
+//=============================================================================
+
+func redactHtmlInputs(body []byte) []byte {
+	return htmlInputValueRegex.ReplaceAllFunc(body, func(match []byte) []byte {
+		m := htmlInputValueRegex.FindSubmatch(match)
+		value := strings.Trim(string(m[2]), `"'`)
+		return append(m[1], []byte(`"`+ RedactValue(value) +`"`)...)
+	})
+}
+
+//=============================================================================
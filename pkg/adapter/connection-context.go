@@ -25,20 +25,28 @@ THE SOFTWARE.
 package adapter
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"errors"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/metrics"
 )
 
 //=============================================================================
 
 const (
-	RefreshRetries   = 5
-	PriceBarsRetries = 5
+	RefreshRetries    = 5
+	RefreshBackoff    = 5 * time.Second
+	RefreshMaxBackoff = 2 * time.Minute
+	RefreshMaxMargin  = 5 * 60
+	PriceBarsRetries  = 5
 )
 
 //=============================================================================
@@ -53,8 +61,27 @@ const (
 	ContextStatusDisconnected = 0
 	ContextStatusConnecting   = 1
 	ContextStatusConnected    = 2
+	ContextStatusReconnecting = 3
 )
 
+//-----------------------------------------------------------------------------
+
+func (s ContextStatus) String() string {
+	switch s {
+		case ContextStatusDisconnected: return "disconnected"
+		case ContextStatusConnecting:   return "connecting"
+		case ContextStatusConnected:    return "connected"
+		case ContextStatusReconnecting: return "reconnecting"
+	}
+
+	return "unknown"
+}
+
+//-----------------------------------------------------------------------------
+//--- Provides the connect params needed to reconnect without the user
+
+type CredentialProvider func() (map[string]any, error)
+
 //=============================================================================
 
 type ConnectionContext struct {
@@ -62,11 +89,20 @@ type ConnectionContext struct {
 	ConnectionCode  string
 	Host            string
 	ConnectedSince  time.Time
+	CredentialId    string
+	instanceCode    string
 	//-------------------------------
 	status          ContextStatus
 	lastRefreshTime time.Time
+	nextRefreshTime time.Time
 	refreshRetries  int
+	reconnAttempts  int
+	nextReconnTime  time.Time
+	configParams    map[string]any
+	credentials     CredentialProvider
+	feed            PriceFeed
 	adapter         Adapter
+	streams         *streamHub
 	sync.RWMutex
 }
 
@@ -83,14 +119,48 @@ func NewConnectionContext(username string, connectionCode string, host string, a
 		return nil, err
 	}
 
-	return &ConnectionContext{
+	slog.Debug("NewConnectionContext: Creating context", "username", username, "connection", connectionCode, "adapter", a.GetInfo().Code,
+		"configParams",  RedactParams(a.GetInfo().ConfigParams,  configParams),
+		"connectParams", RedactParams(a.GetInfo().ConnectParams, connectParams))
+
+	cc := &ConnectionContext{
 		Username      : username,
 		ConnectionCode: connectionCode,
 		Host          : host,
+		configParams  : configParams,
 		adapter       : a.Clone(configParams, connectParams),
 		status        : ContextStatusDisconnected,
 		refreshRetries: RefreshRetries,
-	},nil
+		streams       : newStreamHub(),
+	}
+
+	cc.setCassetteOwner()
+	return cc,nil
+}
+
+//=============================================================================
+//--- Builds a context from a stored connection. Connect params are not stored
+//--- so the adapter must be able to restore its session from the refresh token
+
+func RestoreConnectionContext(username string, connectionCode string, host string, a Adapter, configParams map[string]any) (*ConnectionContext,error) {
+	err := validateParameters(a.GetInfo().ConfigParams, configParams)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := &ConnectionContext{
+		Username      : username,
+		ConnectionCode: connectionCode,
+		Host          : host,
+		configParams  : configParams,
+		adapter       : a.Clone(configParams, map[string]any{}),
+		status        : ContextStatusDisconnected,
+		refreshRetries: RefreshRetries,
+		streams       : newStreamHub(),
+	}
+
+	cc.setCassetteOwner()
+	return cc,nil
 }
 
 //=============================================================================
@@ -107,6 +177,25 @@ func (cc *ConnectionContext) GetAdapterAuthUrl() string {
 
 //=============================================================================
 
+func (cc *ConnectionContext) GetConfigParams() map[string]any {
+	return cc.configParams
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) GetRefreshToken() string {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	if sk,ok := cc.adapter.(SessionKeeper); ok {
+		return sk.GetRefreshToken()
+	}
+
+	return ""
+}
+
+//=============================================================================
+
 func (cc *ConnectionContext) GetStatus() ContextStatus {
 	return cc.status
 }
@@ -131,8 +220,22 @@ func (cc *ConnectionContext) IsDisconnected() bool {
 
 //=============================================================================
 
+func (cc *ConnectionContext) IsReconnecting() bool {
+	return cc.status == ContextStatusReconnecting
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) SetCredentialProvider(cp CredentialProvider) {
+	cc.credentials = cp
+}
+
+//=============================================================================
+
 func (cc *ConnectionContext) Connect() (ConnectionResult, error) {
+	start  := time.Now()
 	cr,err := cc.adapter.Connect(cc)
+	cc.observe("Connect", start, err)
 
 	if err == nil {
 		switch cr {
@@ -140,6 +243,7 @@ func (cc *ConnectionContext) Connect() (ConnectionResult, error) {
 				cc.status          = ContextStatusConnected
 				cc.ConnectedSince  = time.Now()
 				cc.lastRefreshTime = cc.ConnectedSince
+				cc.scheduleRefresh()
 
 			case ConnectionResultOpenUrl:
 				cc.status = ContextStatusConnecting
@@ -156,27 +260,77 @@ func (cc *ConnectionContext) Connect() (ConnectionResult, error) {
 
 func (cc *ConnectionContext) Disconnect() error {
 	cc.status = ContextStatusDisconnected
+	cc.streams.removeAll()
 	return cc.adapter.Disconnect(cc)
 }
 
 //=============================================================================
+//--- Brings a restored context back to the connected state without asking the
+//--- user to log in again. Adapters without a session are simply reconnected
 
-func (cc *ConnectionContext) NeedsRefresh() bool {
-	now := time.Now()
-	sec := cc.adapter.GetTokenExpSeconds()
+func (cc *ConnectionContext) Restore(refreshToken string) error {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if sk,ok := cc.adapter.(SessionKeeper); ok {
+		err := sk.SetRefreshToken(refreshToken)
+		if err != nil {
+			return err
+		}
 
-	if sec == 0 || !cc.IsConnected(){
+		err = cc.adapter.RefreshToken()
+		if err != nil {
+			return err
+		}
+	} else {
+		cr,err := cc.adapter.Connect(cc)
+		if err != nil {
+			return err
+		}
+
+		if cr != ConnectionResultConnected {
+			return errors.New("Connection cannot be restored without a new login: "+ cc.ConnectionCode)
+		}
+	}
+
+	cc.status          = ContextStatusConnected
+	cc.ConnectedSince  = time.Now()
+	cc.lastRefreshTime = cc.ConnectedSince
+	cc.refreshRetries  = RefreshRetries
+	cc.scheduleRefresh()
+
+	return nil
+}
+
+//=============================================================================
+//--- A context locked for writing is being refreshed or reconnected right now
+
+func (cc *ConnectionContext) NeedsRefresh() bool {
+	if !cc.TryRLock() {
 		return false
 	}
+	defer cc.RUnlock()
 
-	//--- We remove the last 2 mins to allow a few retries and to avoid to be borderline
-	if sec >= 150 {
-		sec -= 2*60
+	if cc.nextRefreshTime.IsZero() || !cc.IsConnected() {
+		return false
 	}
 
-	delta := int(now.Sub(cc.lastRefreshTime) / time.Second)
+	return time.Now().After(cc.nextRefreshTime)
+}
+
+//=============================================================================
+//--- Waits for a running refresh, so that its new schedule is returned
+
+func (cc *ConnectionContext) GetNextRefreshTime() *time.Time {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	if cc.nextRefreshTime.IsZero() || !cc.IsConnected() {
+		return nil
+	}
 
-	return delta > sec
+	t := cc.nextRefreshTime
+	return &t
 }
 
 //=============================================================================
@@ -188,7 +342,61 @@ func (cc *ConnectionContext) IsWebLoginCompleted(httpCode int, path string) bool
 //=============================================================================
 
 func (cc *ConnectionContext) InitFromWebLogin(reqHeader *http.Header, resCookies []*http.Cookie) error {
-	return cc.adapter.InitFromWebLogin(reqHeader, resCookies)
+	cc.Lock()
+	defer cc.Unlock()
+
+	err := cc.adapter.InitFromWebLogin(reqHeader, resCookies)
+	if err != nil {
+		return err
+	}
+
+	cc.status          = ContextStatusConnected
+	cc.ConnectedSince  = time.Now()
+	cc.lastRefreshTime = cc.ConnectedSince
+	cc.instanceCode    = ""
+	cc.scheduleRefresh()
+
+	return nil
+}
+
+//=============================================================================
+//--- The instance code identifies the context during a web login, so it must
+//--- be unguessable. Its expiration is kept by the caller
+
+func (cc *ConnectionContext) NewInstanceCode() (string,error) {
+	b := make([]byte, 32)
+	_,err := crand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.instanceCode = hex.EncodeToString(b)
+
+	return cc.instanceCode, nil
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) GetInstanceCode() string {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	return cc.instanceCode
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) ExpireWebLogin() {
+	cc.Lock()
+	defer cc.Unlock()
+
+	cc.instanceCode = ""
+	if cc.IsConnecting() {
+		cc.status = ContextStatusDisconnected
+	}
 }
 
 //=============================================================================
@@ -206,11 +414,17 @@ func (cc *ConnectionContext) RefreshToken() error {
 	if err == nil {
 		cc.lastRefreshTime = time.Now()
 		cc.refreshRetries  = RefreshRetries
+		cc.scheduleRefresh()
 	} else {
 		cc.refreshRetries--
+		if errors.Is(err, ErrSessionExpired) {
+			cc.refreshRetries = 0
+		}
+
 		if cc.refreshRetries > 0 {
-			slog.Warn("RefreshToken: The adapter cannot refresh the token. Retrying...", "username", cc.Username, "connection", cc.ConnectionCode, "error", err.Error())
-			return nil
+			cc.nextRefreshTime = time.Now().Add(refreshBackoff(RefreshRetries - cc.refreshRetries))
+		} else if cc.canReconnect() {
+			cc.startReconnection()
 		} else {
 			cc.status = ContextStatusDisconnected
 		}
@@ -219,13 +433,64 @@ func (cc *ConnectionContext) RefreshToken() error {
 	return err
 }
 
+//=============================================================================
+
+func (cc *ConnectionContext) NeedsReconnect() bool {
+	if !cc.TryRLock() {
+		return false
+	}
+	defer cc.RUnlock()
+
+	return cc.IsReconnecting() && time.Now().After(cc.nextReconnTime)
+}
+
+//=============================================================================
+//--- Runs one reconnection attempt. On failure the next attempt is scheduled
+//--- until the adapter policy is exhausted, then the context is disconnected
+
+func (cc *ConnectionContext) Reconnect() error {
+	cc.Lock()
+	defer cc.Unlock()
+
+	if !cc.IsReconnecting() {
+		return nil
+	}
+
+	cc.reconnAttempts++
+	err := cc.reconnect()
+
+	if err == nil {
+		cc.status          = ContextStatusConnected
+		cc.ConnectedSince  = time.Now()
+		cc.lastRefreshTime = cc.ConnectedSince
+		cc.refreshRetries  = RefreshRetries
+		cc.scheduleRefresh()
+		return nil
+	}
+
+	policy := &cc.adapter.GetInfo().Reconnect
+
+	if cc.reconnAttempts >= policy.MaxAttempts {
+		cc.status = ContextStatusDisconnected
+	} else {
+		cc.nextReconnTime = time.Now().Add(policy.GetDelay(cc.reconnAttempts +1))
+	}
+
+	return err
+}
+
+
 //=============================================================================
 
 func (cc *ConnectionContext) GetRootSymbols(filter string) ([]*RootSymbol,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetRootSymbols(filter)
+	start   := time.Now()
+	res,err := cc.adapter.GetRootSymbols(filter)
+	cc.observe("GetRootSymbols", start, err)
+
+	return res,err
 }
 
 //=============================================================================
@@ -234,7 +499,11 @@ func (cc *ConnectionContext) GetRootSymbol(root string) (*RootSymbol,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetRootSymbol(root)
+	start   := time.Now()
+	res,err := cc.adapter.GetRootSymbol(root)
+	cc.observe("GetRootSymbol", start, err)
+
+	return res,err
 }
 
 //=============================================================================
@@ -243,31 +512,53 @@ func (cc *ConnectionContext) GetInstruments(root string) ([]*Instrument,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetInstruments(root)
+	start   := time.Now()
+	res,err := cc.adapter.GetInstruments(root)
+	cc.observe("GetInstruments", start, err)
+
+	if err == nil {
+		cc.setCanonicalSymbols(res, "")
+	}
+
+	return res,err
 }
 
 //=============================================================================
+//--- Completes the canonical symbols of the instruments without an exchange,
+//--- which callers take from the root (usually from the catalog)
 
-func (cc *ConnectionContext) GetPriceBars(symbol string, date datatype.IntDate) (*PriceBars,error) {
+func (cc *ConnectionContext) SetCanonicalSymbols(list []*Instrument, rootExchange string) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	counter := 0
+	cc.setCanonicalSymbols(list, rootExchange)
+}
 
-	for {
-		pc,err := cc.adapter.GetPriceBars(symbol, date)
+//=============================================================================
 
-		if err != nil || !pc.Timeout {
-			return pc,err
-		}
+func (cc *ConnectionContext) GetPriceBars(rq *PriceBarsRequest) (*PriceBars,error) {
+	cc.RLock()
+	defer cc.RUnlock()
 
-		counter++
-		slog.Warn("GetPriceBars: Got timeout from adapter", "adapter", cc.adapter.GetInfo().Name, "counter", counter)
+	symbol,err := cc.resolveSymbol(rq.Symbol)
+	if err != nil {
+		return nil,err
+	}
 
-		if counter == PriceBarsRetries {
-			return nil,errors.New("Maximum number of retries exceeded: "+ cc.adapter.GetInfo().Name)
-		}
+	rqCopy := *rq
+	rqCopy.Symbol = symbol
+	rq = &rqCopy
+
+	if rq.Timeframe != "" {
+		return cc.getAggregatedPriceBars(rq)
 	}
+
+	if rq.Calendar != "" {
+		res,_,err := cc.getSessionPriceBars(rq)
+		return res,err
+	}
+
+	return cc.fetchPriceBars(rq)
 }
 
 //=============================================================================
@@ -276,25 +567,140 @@ func (cc *ConnectionContext) GetAccounts() ([]*Account,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetAccounts()
+	start   := time.Now()
+	res,err := cc.adapter.GetAccounts()
+	cc.observe("GetAccounts", start, err)
+
+	return res,err
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) GetOrders() ([]*Order,error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	start   := time.Now()
+	res,err := cc.adapter.GetOrders()
+	cc.observe("GetOrders", start, err)
+
+	return res,err
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) PlaceOrder(o *Order) (*Order,error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	symbol,err := cc.resolveSymbol(o.Symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	oCopy := *o
+	oCopy.Symbol = symbol
+	o = &oCopy
+
+	start   := time.Now()
+	res,err := cc.adapter.PlaceOrder(o)
+	cc.observe("PlaceOrder", start, err)
+
+	return res,err
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) ModifyOrder(id string, oc *OrderChange) (*Order,error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	start   := time.Now()
+	res,err := cc.adapter.ModifyOrder(id, oc)
+	cc.observe("ModifyOrder", start, err)
+
+	return res,err
 }
 
 //=============================================================================
 
-func (cc *ConnectionContext) GetOrders() (any,error) {
+func (cc *ConnectionContext) CancelOrder(id string) error {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetOrders()
+	start := time.Now()
+	err   := cc.adapter.CancelOrder(id)
+	cc.observe("CancelOrder", start, err)
+
+	return err
 }
 
 //=============================================================================
 
-func (cc *ConnectionContext) GetPositions() (any,error) {
+func (cc *ConnectionContext) GetPositions() ([]*Position,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.GetPositions()
+	start   := time.Now()
+	res,err := cc.adapter.GetPositions()
+	cc.observe("GetPositions", start, err)
+
+	return res,err
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) Subscribe(symbol string, st StreamType) (*StreamClient,error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	symbol,err := cc.resolveSymbol(symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	return cc.streams.add(symbol, st, cc.adapter)
+}
+
+//=============================================================================
+
+//--- The write lock waits for the calls that are using the old feed
+
+func (cc *ConnectionContext) SetPriceFeed(feed PriceFeed) error {
+	cc.Lock()
+	defer cc.Unlock()
+
+	fc,ok := cc.adapter.(FeedConsumer)
+	if !ok {
+		return NewNotSupportedError(cc.adapter, "SetPriceFeed")
+	}
+
+	fc.SetPriceFeed(feed)
+	cc.feed = feed
+	return nil
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) Replay(symbol string, date datatype.IntDate) (*ReplayResult,error) {
+	cc.RLock()
+	defer cc.RUnlock()
+
+	sim,ok := cc.adapter.(Simulator)
+	if !ok {
+		return nil, NewNotSupportedError(cc.adapter, "Replay")
+	}
+
+	symbol,err := cc.resolveSymbol(symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	start   := time.Now()
+	res,err := sim.Replay(symbol, date)
+	cc.observe("Replay", start, err)
+
+	return res,err
 }
 
 //=============================================================================
@@ -303,13 +709,401 @@ func (cc *ConnectionContext) TestAdapter(service, query string) (string,error) {
 	cc.RLock()
 	defer cc.RUnlock()
 
-	return cc.adapter.TestService(service, query)
+	start   := time.Now()
+	res,err := cc.adapter.TestService(service, query)
+	cc.observe("TestService", start, err)
+
+	return res,err
+}
+
+//=============================================================================
+//===
+//=== Private methods
+//===
+//=============================================================================
+
+func (cc *ConnectionContext) observe(service string, start time.Time, err error) {
+	metrics.ObserveAdapterCall(cc.adapter.GetInfo().Code, service, start, err)
+}
+
+//=============================================================================
+//--- Canonical symbols (like ES:CME:202503) are converted to the adapter's
+//--- format. Other symbols are passed through unchanged
+
+func (cc *ConnectionContext) resolveSymbol(symbol string) (string,error) {
+	if !IsCanonicalSymbol(symbol) {
+		return symbol,nil
+	}
+
+	sm,ok := cc.adapter.(SymbolMapper)
+	if !ok {
+		return "", NewNotSupportedError(cc.adapter, "canonical symbols")
+	}
+
+	cs,err := ParseCanonicalSymbol(symbol)
+	if err != nil {
+		return "", req.NewBadRequestError("Invalid symbol: %v", err.Error())
+	}
+
+	res,err := sm.ToAdapterSymbol(cs)
+	if err != nil {
+		return "", req.NewBadRequestError("Cannot map symbol: %v", err.Error())
+	}
+
+	return res,nil
+}
+
+//=============================================================================
+//--- Instruments without an exchange get the default one, if any. Those that
+//--- already have a canonical symbol are left unchanged
+
+func (cc *ConnectionContext) setCanonicalSymbols(list []*Instrument, defExchange string) {
+	sm,ok := cc.adapter.(SymbolMapper)
+	if !ok {
+		return
+	}
+
+	for _, i := range list {
+		exchange := i.Exchange
+		if exchange == "" {
+			exchange = defExchange
+		}
+
+		if i.Canonical != "" || exchange == "" {
+			continue
+		}
+
+		if cs,err := sm.ToCanonicalSymbol(i.Name, exchange); err == nil {
+			i.Canonical = cs.String()
+		}
+	}
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) fetchPriceBars(rq *PriceBarsRequest) (*PriceBars,error) {
+	if !isBarCacheable(cc.adapter.GetInfo(), rq) {
+		return cc.getPriceBars(rq)
+	}
+
+	return cc.getCachedPriceBars(rq)
+}
+
+//=============================================================================
+//--- Higher timeframes are built from 1-minute bars, so that they are consistent
+//--- across adapters and can be served from the bar cache
+
+func (cc *ConnectionContext) getAggregatedPriceBars(rq *PriceBarsRequest) (*PriceBars,error) {
+	tf,err := ParseTimeframe(rq.Timeframe)
+	if err != nil {
+		return nil, req.NewBadRequestError("%v", err.Error())
+	}
+
+	sub := *rq
+	sub.Timeframe = ""
+	sub.Unit      = BarUnitMinute
+	sub.Interval  = 1
+
+	var pb       *PriceBars
+	var sessions []*TradingDay
+
+	if rq.Calendar != "" {
+		pb,sessions,err = cc.getSessionPriceBars(&sub)
+	} else if tf.Session {
+		return nil, req.NewBadRequestError("The session timeframe requires a calendar")
+	} else {
+		pb,err = cc.fetchPriceBars(&sub)
+	}
+
+	if err != nil {
+		return nil,err
+	}
+
+	res := NewPriceBars(rq)
+	res.Unit, res.Interval = tf.GetUnit()
+	res.Timeframe   = tf.Code
+	res.Bars        = AggregateBars(pb.Bars, tf, sessions)
+	res.NoData      = len(res.Bars) == 0
+	res.Holiday     = pb.Holiday
+	res.HolidayName = pb.HolidayName
+
+	return res,nil
+}
+
+//=============================================================================
+//--- Trading dates are converted to the UTC days covered by their sessions, then
+//--- the bars outside the sessions are dropped. Daily and weekly bars already
+//--- refer to trading dates, so for them only closed days are checked
+
+func (cc *ConnectionContext) getSessionPriceBars(rq *PriceBarsRequest) (*PriceBars,[]*TradingDay,error) {
+	cal,err := GetTradingCalendar(rq.Calendar)
+	if err != nil {
+		return nil,nil, req.NewBadRequestError("%v", err.Error())
+	}
+
+	var sessions []*TradingDay
+	res     := NewPriceBars(rq)
+	holiday := ""
+
+	for _, td := range cal.GetTradingDays(rq.From, rq.To) {
+		if td.Trading {
+			sessions = append(sessions, td)
+		} else if td.Holiday != "" {
+			holiday = td.Holiday
+		}
+	}
+
+	//--- Weekends are closed too, but they are not holidays
+
+	if len(sessions) == 0 {
+		res.NoData      = true
+		res.Holiday     = holiday != ""
+		res.HolidayName = holiday
+		return res,nil,nil
+	}
+
+	sub := *rq
+	sub.Calendar = ""
+
+	if rq.Unit.IsIntraday() {
+		sub.From = TimeToIntDate(sessions[0].Start.UTC())
+		sub.To   = TimeToIntDate(sessions[len(sessions)-1].End.UTC())
+	}
+
+	pb,err := cc.fetchPriceBars(&sub)
+	if err != nil {
+		return nil,nil,err
+	}
+
+	res.Bars = pb.Bars
+	if rq.Unit.IsIntraday() {
+		res.Bars = filterSessionBars(pb.Bars, sessions)
+	}
+
+	res.NoData = len(res.Bars) == 0
+	return res,sessions,nil
+}
+
+//=============================================================================
+//--- Cassettes are kept per user and connection
+
+func (cc *ConnectionContext) setCassetteOwner() {
+	if co,ok := cc.adapter.(CassetteOwner); ok {
+		co.SetCassetteOwner(cc.Username, cc.ConnectionCode)
+	}
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) getPriceBars(rq *PriceBarsRequest) (*PriceBars,error) {
+	counter := 0
+
+	for {
+		start  := time.Now()
+		pc,err := cc.adapter.GetPriceBars(rq)
+		cc.observe("GetPriceBars", start, err)
+
+		if err != nil || !pc.Timeout {
+			return pc,err
+		}
+
+		counter++
+		metrics.ObservePriceBarsTimeout(cc.adapter.GetInfo().Code)
+		slog.Warn("GetPriceBars: Got timeout from adapter", "adapter", cc.adapter.GetInfo().Name, "counter", counter)
+
+		if counter == PriceBarsRetries {
+			return nil,errors.New("Maximum number of retries exceeded: "+ cc.adapter.GetInfo().Name)
+		}
+	}
+}
+
+//=============================================================================
+//--- Completed days are served from the cache and each run of consecutive missing
+//--- days is asked to the broker with one request, which the adapter splits into
+//--- chunks if needed. The last run is merged with the current session, that is
+//--- never cached
+
+func (cc *ConnectionContext) getCachedPriceBars(rq *PriceBarsRequest) (*PriceBars,error) {
+	code := cc.adapter.GetInfo().Code
+	now  := time.Now()
+	res  := NewPriceBars(rq)
+	from := datatype.IntDate(0)
+	day  := rq.From
+
+	for ; day <= rq.To && isDayCompleted(day, now); day = AddDays(day, 1) {
+		pb,ok := readCachedBars(newBarCacheKey(code, rq, day))
+		metrics.ObserveBarCache(code, ok)
+
+		if !ok {
+			if from == 0 {
+				from = day
+			}
+			continue
+		}
+
+		if from != 0 {
+			bars,err := cc.fetchAndCacheDays(rq, from, AddDays(day, -1), now)
+			if err != nil {
+				return nil,err
+			}
+
+			res.Bars = append(res.Bars, bars...)
+			from = 0
+		}
+
+		res.Bars = append(res.Bars, pb.Bars...)
+	}
+
+	if from == 0 && day <= rq.To {
+		from = day
+	}
+
+	if from != 0 {
+		bars,err := cc.fetchAndCacheDays(rq, from, rq.To, now)
+		if err != nil {
+			return nil,err
+		}
+
+		res.Bars = append(res.Bars, bars...)
+	}
+
+	res.NoData = len(res.Bars) == 0
+	return res,nil
+}
+
+//=============================================================================
+//--- Bars are assigned to the UTC day of their timestamp. Completed days are
+//--- cached even when empty, so that weekends and holidays are not asked again
+
+func (cc *ConnectionContext) fetchAndCacheDays(rq *PriceBarsRequest, from, to datatype.IntDate, now time.Time) ([]*PriceBar,error) {
+	sub := *rq
+	sub.From = from
+	sub.To   = to
+
+	pb,err := cc.getPriceBars(&sub)
+	if err != nil {
+		return nil,err
+	}
+
+	days := map[datatype.IntDate][]*PriceBar{}
+	for _, bar := range pb.Bars {
+		date := TimeToIntDate(bar.TimeStamp.UTC())
+		days[date] = append(days[date], bar)
+	}
+
+	var bars []*PriceBar
+
+	for day := from; day <= to; day = AddDays(day, 1) {
+		if isDayCompleted(day, now) {
+			sub.From = day
+			sub.To   = day
+
+			dpb := NewPriceBars(&sub)
+			dpb.Bars   = days[day]
+			dpb.NoData = len(dpb.Bars) == 0
+			writeCachedBars(newBarCacheKey(cc.adapter.GetInfo().Code, rq, day), dpb)
+		}
+
+		bars = append(bars, days[day]...)
+	}
+
+	return bars,nil
+}
+
+//=============================================================================
+//--- The refresh is scheduled ahead of the token expiration, leaving enough
+//--- margin for all retries
+
+func (cc *ConnectionContext) scheduleRefresh() {
+	sec := cc.adapter.GetTokenExpSeconds()
+	if sec == 0 {
+		cc.nextRefreshTime = time.Time{}
+		return
+	}
+
+	sec -= min(sec/4, RefreshMaxMargin)
+	cc.nextRefreshTime = cc.lastRefreshTime.Add(time.Duration(sec) * time.Second)
+}
+
+//=============================================================================
+//--- Adapters that need connect params can be reconnected only when they can
+//--- be retrieved without the user
+
+func (cc *ConnectionContext) canReconnect() bool {
+	if cc.adapter.GetInfo().Reconnect.MaxAttempts == 0 {
+		return false
+	}
+
+	return cc.credentials != nil || len(cc.adapter.GetInfo().ConnectParams) == 0
+}
+
+//=============================================================================
+
+func (cc *ConnectionContext) startReconnection() {
+	cc.status         = ContextStatusReconnecting
+	cc.reconnAttempts = 0
+	cc.nextReconnTime = time.Now().Add(cc.adapter.GetInfo().Reconnect.GetDelay(1))
+	cc.streams.removeAll()
+}
+
+//=============================================================================
+//--- The session is rebuilt on a fresh clone, as adapters drop their secrets
+//--- after login. The price feed, if any, must be set again
+
+func (cc *ConnectionContext) reconnect() error {
+	connectParams := map[string]any{}
+
+	if cc.credentials != nil {
+		var err error
+		connectParams,err = cc.credentials()
+		if err != nil {
+			return err
+		}
+	}
+
+	a := cc.adapter.Clone(cc.configParams, connectParams)
+
+	if cc.feed != nil {
+		if fc,ok := a.(FeedConsumer); ok {
+			fc.SetPriceFeed(cc.feed)
+		}
+	}
+
+	cr,err := a.Connect(cc)
+	if err != nil {
+		return err
+	}
+
+	if cr != ConnectionResultConnected {
+		return errors.New("Connection cannot be re-established without a new login: "+ cc.ConnectionCode)
+	}
+
+	_ = cc.adapter.Disconnect(cc)
+	cc.adapter = a
+
+	return nil
 }
 
 //=============================================================================
 //===
 //=== Functions
 //===
+//=============================================================================
+//--- Exponential backoff with a +/-20% jitter, so that many connections failing
+//--- together do not retry in lockstep
+
+func refreshBackoff(attempt int) time.Duration {
+	delay := RefreshBackoff
+	for i:=1; i<attempt && delay < RefreshMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	delay   = min(delay, RefreshMaxBackoff)
+	jitter := time.Duration(rand.Int64N(int64(delay) * 2 / 5)) - delay/5
+
+	return delay + jitter
+}
+
 //=============================================================================
 
 func validateParameters(params []*ParamDef, values map[string]any) error {
@@ -324,3 +1118,28 @@ func validateParameters(params []*ParamDef, values map[string]any) error {
 }
 
 //=============================================================================
+
+func filterSessionBars(bars []*PriceBar, sessions []*TradingDay) []*PriceBar {
+	var list []*PriceBar
+	i := 0
+
+	//--- Bars are timestamped at their close, so a session includes the bars in (start, end]
+
+	for _, b := range bars {
+		for i < len(sessions) && b.TimeStamp.After(*sessions[i].End) {
+			i++
+		}
+
+		if i == len(sessions) {
+			break
+		}
+
+		if b.TimeStamp.After(*sessions[i].Start) {
+			list = append(list, b)
+		}
+	}
+
+	return list
+}
+
+//=============================================================================
@@ -0,0 +1,430 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
+)
+
+//=============================================================================
+//===
+//=== Continuous futures. Individual contracts of a root are stitched together
+//=== following a roll rule, then back-adjusted so that the most recent
+//=== contract keeps its real prices:
+//===
+//===    expiration   : roll RollDays calendar days before expiration
+//===    volume       : roll the day after the next contract trades more volume
+//===    openInterest : roll the day after the next contract has more open interest
+//===
+//=== Crossover rules never roll later than the expiration rule. Only contracts
+//=== with an expiration date are used, so continuous symbols (like @ES) are
+//=== ignored and the result does not depend on the broker's own adjustment
+//===
+//=============================================================================
+
+type RollMethod string
+
+const (
+	RollMethodExpiration   RollMethod = "expiration"
+	RollMethodVolume       RollMethod = "volume"
+	RollMethodOpenInterest RollMethod = "openInterest"
+)
+
+//-----------------------------------------------------------------------------
+
+type AdjustMethod string
+
+const (
+	AdjustMethodNone       AdjustMethod = "none"
+	AdjustMethodDifference AdjustMethod = "difference"
+	AdjustMethodRatio      AdjustMethod = "ratio"
+)
+
+//-----------------------------------------------------------------------------
+
+const (
+	DefaultRollDays = 5
+
+	//--- Days before the latest roll date where a crossover is searched
+	CrossoverWindowDays = 30
+)
+
+//=============================================================================
+
+type ContinuousRequest struct {
+	PriceBarsRequest
+	Roll     RollMethod
+	RollDays int
+	Adjust   AdjustMethod
+}
+
+//-----------------------------------------------------------------------------
+
+func (r *ContinuousRequest) Validate() error {
+	switch r.Roll {
+		case RollMethodExpiration, RollMethodVolume, RollMethodOpenInterest:
+		default:
+			return errors.New("invalid roll method : "+ string(r.Roll))
+	}
+
+	switch r.Adjust {
+		case AdjustMethodNone, AdjustMethodDifference, AdjustMethodRatio:
+		default:
+			return errors.New("invalid adjustment method : "+ string(r.Adjust))
+	}
+
+	if r.RollDays < 0 {
+		return errors.New("rollDays cannot be negative")
+	}
+
+	return r.PriceBarsRequest.Validate()
+}
+
+//=============================================================================
+
+type ContinuousSegment struct {
+	Symbol     string  `json:"symbol"`
+	From       int     `json:"from"`
+	To         int     `json:"to"`
+	Adjustment float64 `json:"adjustment"` // added (difference) or multiplied (ratio) to prices
+}
+
+//-----------------------------------------------------------------------------
+
+type ContinuousBars struct {
+	Root      string               `json:"root"`
+	Date      int                  `json:"date"`
+	To        int                  `json:"to"`
+	Unit      BarUnit              `json:"unit"`
+	Interval  int                  `json:"interval"`
+	Timeframe string               `json:"timeframe,omitempty"`
+	Roll      RollMethod           `json:"roll"`
+	RollDays  int                  `json:"rollDays"`
+	Adjust    AdjustMethod         `json:"adjust"`
+	Segments  []*ContinuousSegment `json:"segments"`
+	Bars      []*PriceBar          `json:"bars"`
+}
+
+//=============================================================================
+
+type rollPoint struct {
+	date     datatype.IntDate  // first day of the next contract
+	oldClose float64
+	newClose float64
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func BuildContinuousBars(feed PriceFeed, instruments []*Instrument, rq *ContinuousRequest) (*ContinuousBars, error) {
+	contracts := getExpiringContracts(instruments)
+	if len(contracts) == 0 {
+		return nil, req.NewBadRequestError("No contracts with an expiration date for root: %v", rq.Symbol)
+	}
+
+	res := &ContinuousBars{
+		Root    : rq.Symbol,
+		Date    : int(rq.From),
+		To      : int(rq.To),
+		Unit    : rq.Unit,
+		Interval: rq.Interval,
+		Roll    : rq.Roll,
+		RollDays: rq.RollDays,
+		Adjust  : rq.Adjust,
+		Segments: []*ContinuousSegment{},
+		Bars    : []*PriceBar{},
+	}
+
+	if rq.Timeframe != "" {
+		tf, err := ParseTimeframe(rq.Timeframe)
+		if err != nil {
+			return nil, req.NewBadRequestError("Invalid timeframe: %v", err.Error())
+		}
+
+		res.Unit, res.Interval = tf.GetUnit()
+		res.Timeframe = tf.Code
+	}
+
+	var barsList [][]*PriceBar
+	var rolls    []*rollPoint
+
+	from := rq.From
+
+	for i, c := range contracts {
+		if from > rq.To {
+			break
+		}
+
+		to := rq.To
+		var rp *rollPoint
+
+		if i < len(contracts)-1 {
+			//--- The roll happens at the latest RollDays before expiration, so contracts
+			//--- rolled before the range are skipped without asking their bars
+
+			if AddDays(TimeToIntDate(*c.ExpirationDate), -rq.RollDays) <= from {
+				continue
+			}
+
+			var err error
+			rp, err = findRollPoint(feed, c, contracts[i+1], rq)
+			if err != nil {
+				return nil, err
+			}
+
+			if rp != nil {
+				if rp.date <= from {
+					continue
+				}
+
+				to = min(to, AddDays(rp.date, -1))
+			}
+		}
+
+		bars, err := getSegmentBars(feed, c.Name, from, to, rq)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Segments = append(res.Segments, &ContinuousSegment{
+			Symbol: c.Name,
+			From  : int(from),
+			To    : int(to),
+		})
+
+		barsList = append(barsList, bars)
+
+		if rp == nil {
+			break
+		}
+
+		rolls = append(rolls, rp)
+		from  = rp.date
+	}
+
+	adjustSegments(res, barsList, rolls)
+
+	for _, bars := range barsList {
+		res.Bars = append(res.Bars, bars...)
+	}
+
+	return res, nil
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func getExpiringContracts(instruments []*Instrument) []*Instrument {
+	var list []*Instrument
+
+	for _, i := range instruments {
+		if !i.Continuous && i.ExpirationDate != nil {
+			list = append(list, i)
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].ExpirationDate.Before(*list[j].ExpirationDate)
+	})
+
+	return list
+}
+
+//=============================================================================
+//--- Returns nil when the roll happens after the requested range
+
+func findRollPoint(feed PriceFeed, curr, next *Instrument, rq *ContinuousRequest) (*rollPoint, error) {
+	latest := AddDays(TimeToIntDate(*curr.ExpirationDate), -rq.RollDays)
+	first  := latest
+
+	if rq.Roll != RollMethodExpiration {
+		first = AddDays(latest, -CrossoverWindowDays)
+	}
+
+	if first > rq.To {
+		return nil, nil
+	}
+
+	//--- Daily bars are needed to find the crossover and to measure the gap between
+	//--- the two contracts. The window ends before the roll date, so there is no lookahead
+
+	winFrom := AddDays(first, -CrossoverWindowDays)
+	winTo   := min(AddDays(latest, -1), rq.To)
+
+	currDays, err := getDailyBars(feed, curr.Name, winFrom, winTo)
+	if err != nil {
+		return nil, err
+	}
+
+	nextDays, err := getDailyBars(feed, next.Name, winFrom, winTo)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &rollPoint{ date: latest }
+
+	if rq.Roll != RollMethodExpiration {
+		for day := first; day <= winTo; day = AddDays(day, 1) {
+			cb, nb := currDays[day], nextDays[day]
+			if cb != nil && nb != nil && getRollMetric(nb, rq.Roll) > getRollMetric(cb, rq.Roll) {
+				rp.date = AddDays(day, 1)
+				break
+			}
+		}
+	}
+
+	if rp.date > rq.To {
+		return nil, nil
+	}
+
+	//--- The gap is measured on the last day both contracts traded before the roll
+
+	for day := AddDays(rp.date, -1); day >= winFrom; day = AddDays(day, -1) {
+		cb, nb := currDays[day], nextDays[day]
+		if cb != nil && nb != nil {
+			rp.oldClose = cb.Close
+			rp.newClose = nb.Close
+			return rp, nil
+		}
+	}
+
+	slog.Warn("findRollPoint: No common day to measure the roll gap. Prices will not be adjusted", "from", curr.Name, "to", next.Name, "date", rp.date)
+	return rp, nil
+}
+
+//=============================================================================
+
+func getRollMetric(b *PriceBar, method RollMethod) int {
+	if method == RollMethodOpenInterest {
+		return b.OpenInterest
+	}
+
+	return b.UpVolume + b.DownVolume
+}
+
+//=============================================================================
+
+func getDailyBars(feed PriceFeed, symbol string, from, to datatype.IntDate) (map[datatype.IntDate]*PriceBar, error) {
+	res := map[datatype.IntDate]*PriceBar{}
+	if from > to {
+		return res, nil
+	}
+
+	pb, err := feed.GetPriceBars(&PriceBarsRequest{
+		Symbol  : symbol,
+		Unit    : BarUnitDaily,
+		Interval: 1,
+		From    : from,
+		To      : to,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range pb.Bars {
+		res[TimeToIntDate(b.TimeStamp)] = b
+	}
+
+	return res, nil
+}
+
+//=============================================================================
+
+func getSegmentBars(feed PriceFeed, symbol string, from, to datatype.IntDate, rq *ContinuousRequest) ([]*PriceBar, error) {
+	//--- The calendar and the timeframe of the request apply to each segment
+
+	sub := rq.PriceBarsRequest
+	sub.Symbol = symbol
+	sub.From   = from
+	sub.To     = to
+
+	pb, err := feed.GetPriceBars(&sub)
+	if err != nil {
+		return nil, err
+	}
+
+	//--- Bars are copied because they are modified by the adjustment and could be
+	//--- shared with the adapter
+
+	list := make([]*PriceBar, len(pb.Bars))
+	for i, b := range pb.Bars {
+		bar := *b
+		list[i] = &bar
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+//--- Back-adjustment: going backwards, each roll gap is accumulated and applied
+//--- to all the previous segments
+
+func adjustSegments(res *ContinuousBars, barsList [][]*PriceBar, rolls []*rollPoint) {
+	offset := 0.0
+	factor := 1.0
+
+	for i := len(res.Segments)-1; i >= 0; i-- {
+		if i < len(rolls) {
+			rp := rolls[i]
+			if rp.oldClose != 0 {
+				offset += rp.newClose - rp.oldClose
+				factor *= rp.newClose / rp.oldClose
+			}
+		}
+
+		switch res.Adjust {
+			case AdjustMethodDifference:
+				res.Segments[i].Adjustment = offset
+				for _, b := range barsList[i] {
+					b.Open  += offset
+					b.High  += offset
+					b.Low   += offset
+					b.Close += offset
+				}
+
+			case AdjustMethodRatio:
+				res.Segments[i].Adjustment = factor
+				for _, b := range barsList[i] {
+					b.Open  *= factor
+					b.High  *= factor
+					b.Low   *= factor
+					b.Close *= factor
+				}
+		}
+	}
+}
+//=============================================================================
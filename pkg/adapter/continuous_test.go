@@ -0,0 +1,169 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/core/datatype"
+)
+
+//=============================================================================
+
+func TestContinuousExpirationRoll(t *testing.T) {
+	res,_ := buildTestContinuous(t, RollMethodExpiration, AdjustMethodDifference, 20250301, 20250331)
+
+	checkSegments(t, res, "ESH25", 20250301, 20250315, "ESM25", 20250316, 20250331)
+
+	if res.Segments[0].Adjustment != 10 || res.Bars[0].Close != 110 || res.Bars[len(res.Bars)-1].Close != 110 {
+		t.Errorf("expected old prices shifted by the 10 points gap, got %v", res.Segments[0].Adjustment)
+	}
+}
+
+//=============================================================================
+
+func TestContinuousVolumeRoll(t *testing.T) {
+	res,_ := buildTestContinuous(t, RollMethodVolume, AdjustMethodRatio, 20250301, 20250331)
+
+	checkSegments(t, res, "ESH25", 20250301, 20250310, "ESM25", 20250311, 20250331)
+
+	if math.Abs(res.Bars[0].Close - 110) > 1e-9 || math.Abs(res.Segments[0].Adjustment - 1.1) > 1e-9 {
+		t.Errorf("expected old prices multiplied by 1.1, got %v", res.Segments[0].Adjustment)
+	}
+}
+
+//=============================================================================
+
+func TestContinuousSkipsRolledContracts(t *testing.T) {
+	res,feed := buildTestContinuous(t, RollMethodExpiration, AdjustMethodNone, 20250401, 20250410)
+
+	if len(res.Segments) != 1 || res.Segments[0].Symbol != "ESM25" || len(res.Bars) != 10 || res.Bars[0].Close != 110 {
+		t.Errorf("expected only the ESM25 segment, got %d segments", len(res.Segments))
+	}
+
+	if feed.requests["ESH25"] != 0 {
+		t.Errorf("the bars of a contract rolled before the range must not be asked, got %d requests", feed.requests["ESH25"])
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+type testFeed struct {
+	prices   map[string]float64
+	requests map[string]int
+}
+
+//=============================================================================
+//--- One bar per day. ESH25 volume drops below ESM25 from the 10th of March
+
+func (f *testFeed) GetPriceBars(rq *PriceBarsRequest) (*PriceBars, error) {
+	f.requests[rq.Symbol]++
+	res := NewPriceBars(rq)
+
+	for day := rq.From; day <= rq.To; day = AddDays(day, 1) {
+		volume := 500
+		if rq.Symbol == "ESH25" && day < 20250310 {
+			volume = 1000
+		} else if rq.Symbol == "ESH25" {
+			volume = 100
+		}
+
+		p := f.prices[rq.Symbol]
+		res.Bars = append(res.Bars, &PriceBar{
+			TimeStamp: IntDateToTime(day, time.UTC).Add(12 * time.Hour),
+			Open     : p,
+			High     : p,
+			Low      : p,
+			Close    : p,
+			UpVolume : volume,
+		})
+	}
+
+	return res, nil
+}
+
+//=============================================================================
+
+func buildTestContinuous(t *testing.T, roll RollMethod, adjust AdjustMethod, from, to datatype.IntDate) (*ContinuousBars, *testFeed) {
+	expH := time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC)
+	expM := time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)
+
+	instruments := []*Instrument{
+		{ Name: "@ES",   Root: "ES", Continuous: true },
+		{ Name: "ESM25", Root: "ES", ExpirationDate: &expM },
+		{ Name: "ESH25", Root: "ES", ExpirationDate: &expH },
+	}
+
+	rq := &ContinuousRequest{
+		PriceBarsRequest: PriceBarsRequest{ Symbol: "ES", Unit: BarUnitMinute, Interval: 1, From: from, To: to },
+		Roll            : roll,
+		RollDays        : DefaultRollDays,
+		Adjust          : adjust,
+	}
+
+	if err := rq.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	feed := &testFeed{
+		prices  : map[string]float64{ "ESH25": 100, "ESM25": 110 },
+		requests: map[string]int{},
+	}
+
+	res, err := BuildContinuousBars(feed, instruments, rq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res, feed
+}
+
+//=============================================================================
+
+func checkSegments(t *testing.T, res *ContinuousBars, sym1 string, from1, to1 int, sym2 string, from2, to2 int) {
+	t.Helper()
+
+	if len(res.Segments) != 2 {
+		t.Fatalf("expected 2 segments, got %d", len(res.Segments))
+	}
+
+	s1, s2 := res.Segments[0], res.Segments[1]
+	if s1.Symbol != sym1 || s1.From != from1 || s1.To != to1 || s2.Symbol != sym2 || s2.From != from2 || s2.To != to2 {
+		t.Errorf("unexpected segments: %+v, %+v", *s1, *s2)
+	}
+
+	if len(res.Bars) != DaysBetween(datatype.IntDate(from1), datatype.IntDate(to2)) +1 {
+		t.Errorf("expected one bar per day, got %d", len(res.Bars))
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,58 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package adapter
+
+import (
+	"time"
+
+	"github.com/bit-fever/core/datatype"
+)
+
+//=============================================================================
+
+func IntDateToTime(date datatype.IntDate, loc *time.Location) time.Time {
+	d := int(date)
+	return time.Date(d/10000, time.Month(d/100%100), d%100, 0, 0, 0, 0, loc)
+}
+
+//=============================================================================
+
+func TimeToIntDate(t time.Time) datatype.IntDate {
+	return datatype.IntDate(t.Year()*10000 + int(t.Month())*100 + t.Day())
+}
+
+//=============================================================================
+
+func AddDays(date datatype.IntDate, days int) datatype.IntDate {
+	return TimeToIntDate(IntDateToTime(date, time.UTC).AddDate(0, 0, days))
+}
+
+//=============================================================================
+
+func DaysBetween(from, to datatype.IntDate) int {
+	return int(IntDateToTime(to, time.UTC).Sub(IntDateToTime(from, time.UTC)) / (24*time.Hour))
+}
+
+//=============================================================================
@@ -0,0 +1,266 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/app"
+	"github.com/bit-fever/system-adapter/pkg/metrics"
+)
+
+//=============================================================================
+//===
+//=== HTTP client factory: all clients share one transport, so connections are
+//=== pooled and TLS settings (CA bundles, SPKI pins, proxy) are applied once
+//===
+//=============================================================================
+
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 10
+	IdleConnTimeout            = 90 * time.Second
+	TLSHandshakeTimeout        = 10 * time.Second
+)
+
+//=============================================================================
+
+//--- Replaced by InitHttpClients and by tests while requests are running
+
+var transport atomic.Pointer[http.Transport]
+
+//-----------------------------------------------------------------------------
+
+func init() {
+	transport.Store(newTransport(&tls.Config{ MinVersion: tls.VersionTLS12 }, http.ProxyFromEnvironment, DefaultMaxIdleConns, DefaultMaxIdleConnsPerHost))
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func InitHttpClients(cfg *app.HttpClient) error {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if cfg.CaFile != "" {
+		pool,err := buildCertPool(cfg.CaFile, cfg.UseSystemRoots)
+		if err != nil {
+			return err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if len(cfg.Pins) > 0 {
+		pins,err := buildPins(cfg.Pins)
+		if err != nil {
+			return err
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPins(pins, cs)
+		}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyUrl,err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return errors.New("invalid proxy url : "+ cfg.Proxy)
+		}
+		proxy = http.ProxyURL(proxyUrl)
+	}
+
+	maxIdle := cfg.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = DefaultMaxIdleConns
+	}
+
+	maxIdlePerHost := cfg.MaxIdleConnsPerHost
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	transport.Store(newTransport(tlsConfig, proxy, maxIdle, maxIdlePerHost))
+
+	slog.Info("InitHttpClients: HTTP clients configured", "caFile", cfg.CaFile, "pinnedHosts", len(cfg.Pins), "proxy", cfg.Proxy)
+	return nil
+}
+
+//=============================================================================
+
+func NewHttpClient(jar http.CookieJar, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Jar      : jar,
+		Timeout  : timeout,
+		Transport: &meteredTransport{},
+	}
+}
+
+//=============================================================================
+
+func GetHttpTransport() *http.Transport {
+	return transport.Load()
+}
+
+//=============================================================================
+//--- Replaces the shared transport and returns the previous one. Used by tests
+//--- to route the broker calls to a fake backend
+
+func SetHttpTransport(t *http.Transport) *http.Transport {
+	return transport.Swap(t)
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+//--- Records the status codes returned by the brokers. The shared transport is
+//--- looked up on each request, so clients follow InitHttpClients
+
+type meteredTransport struct {
+}
+
+//=============================================================================
+
+func (t *meteredTransport) RoundTrip(rq *http.Request) (*http.Response, error) {
+	res, err := transport.Load().RoundTrip(rq)
+	if err == nil {
+		metrics.ObserveBrokerResponse(rq.URL.Host, res.StatusCode)
+	}
+
+	return res, err
+}
+
+//=============================================================================
+
+func newTransport(tlsConfig *tls.Config, proxy func(*http.Request) (*url.URL, error), maxIdle, maxIdlePerHost int) *http.Transport {
+	return &http.Transport{
+		Proxy                : proxy,
+		DialContext          : (&net.Dialer{ Timeout: 30 * time.Second, KeepAlive: 30 * time.Second }).DialContext,
+		TLSClientConfig      : tlsConfig,
+		TLSHandshakeTimeout  : TLSHandshakeTimeout,
+		ForceAttemptHTTP2    : true,
+		MaxIdleConns         : maxIdle,
+		MaxIdleConnsPerHost  : maxIdlePerHost,
+		IdleConnTimeout      : IdleConnTimeout,
+	}
+}
+
+//=============================================================================
+
+func buildCertPool(caFile string, useSystemRoots bool) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	if useSystemRoots {
+		sys,err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		pool = sys
+	}
+
+	data,err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in CA file : "+ caFile)
+	}
+
+	return pool, nil
+}
+
+//=============================================================================
+//--- Pins are the base64 encoded SHA-256 of the certificate SubjectPublicKeyInfo
+
+func buildPins(cfg []app.HostPins) (map[string]map[string]bool, error) {
+	pins := map[string]map[string]bool{}
+
+	for _, hp := range cfg {
+		host := strings.ToLower(hp.Host)
+		if host == "" {
+			return nil, errors.New("missing host in SPKI pins")
+		}
+
+		set := pins[host]
+		if set == nil {
+			set = map[string]bool{}
+			pins[host] = set
+		}
+
+		for _,pin := range hp.Pins {
+			raw,err := base64.StdEncoding.DecodeString(pin)
+			if err != nil || len(raw) != sha256.Size {
+				return nil, errors.New("invalid SPKI pin for host "+ hp.Host +" : "+ pin)
+			}
+			set[pin] = true
+		}
+	}
+
+	return pins, nil
+}
+
+//=============================================================================
+//--- Runs after the standard chain verification. Only certificates of the verified
+//--- chains are checked: the peer could send extra ones that were never verified.
+//--- Hosts without pins are accepted
+
+func verifyPins(pins map[string]map[string]bool, cs tls.ConnectionState) error {
+	set,found := pins[strings.ToLower(cs.ServerName)]
+	if !found {
+		return nil
+	}
+
+	for _,chain := range cs.VerifiedChains {
+		for _,cert := range chain {
+			sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+			if set[base64.StdEncoding.EncodeToString(sum[:])] {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("certificate pin mismatch for host : "+ cs.ServerName)
+}
+
+//=============================================================================
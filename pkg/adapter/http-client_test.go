@@ -0,0 +1,73 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"testing"
+
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+
+func TestPinsIgnoreUnverifiedCertificates(t *testing.T) {
+	pinned := &x509.Certificate{ RawSubjectPublicKeyInfo: []byte("pinned key") }
+	other  := &x509.Certificate{ RawSubjectPublicKeyInfo: []byte("other key") }
+
+	sum    := sha256.Sum256(pinned.RawSubjectPublicKeyInfo)
+	pins,err := buildPins([]app.HostPins{{ Host: "API.example.com", Pins: []string{ base64.StdEncoding.EncodeToString(sum[:]) } }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//--- The pinned certificate is sent by the peer but it is not part of the verified chain
+
+	cs := tls.ConnectionState{
+		ServerName      : "api.example.com",
+		PeerCertificates: []*x509.Certificate{ other, pinned },
+		VerifiedChains  : [][]*x509.Certificate{{ other }},
+	}
+
+	if verifyPins(pins, cs) == nil {
+		t.Errorf("an unverified certificate must not satisfy the pins")
+	}
+
+	cs.VerifiedChains = [][]*x509.Certificate{{ other, pinned }}
+	if err = verifyPins(pins, cs); err != nil {
+		t.Errorf("a pinned certificate in the verified chain must be accepted: %v", err)
+	}
+
+	cs.ServerName = "unpinned.example.com"
+	if err = verifyPins(pins, cs); err != nil {
+		t.Errorf("hosts without pins must be accepted: %v", err)
+	}
+}
+
+//=============================================================================
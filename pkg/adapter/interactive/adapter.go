@@ -26,14 +26,17 @@ package interactive
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/bit-fever/core/datatype"
 	"github.com/bit-fever/core/req"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -60,6 +63,11 @@ func (a *ib) GetAuthUrl() string {
 func (a *ib) Clone(configParams map[string]any, connectParams map[string]any) adapter.Adapter {
 	b := *a
 	b.configParams = retrieveParams(configParams)
+	b.cassette     = adapter.NewCassette(configParams)
+	b.contracts    = &contracts{
+		conids: map[string]int{},
+		infos : map[int]*ContractInfo{},
+	}
 	return &b
 }
 
@@ -67,8 +75,19 @@ func (a *ib) Clone(configParams map[string]any, connectParams map[string]any) ad
 
 func (a *ib) Connect(ctx *adapter.ConnectionContext) (adapter.ConnectionResult,error) {
 	if a.configParams.NoAuth {
-		//TODO: we should check if the connection actually works...
-		//---   connection to the gateway
+		//--- The gateway is already authenticated: just check that the session is alive
+
+		a.header = &http.Header{}
+		a.client = newClient(a.cassette)
+
+		status,err := a.authStatus()
+		if err != nil {
+			return adapter.ConnectionResultError, err
+		}
+
+		if !status.Authenticated {
+			return adapter.ConnectionResultError, errors.New("gateway session is not authenticated: "+ status.Message)
+		}
 
 		return adapter.ConnectionResultConnected,nil
 	}
@@ -96,13 +115,7 @@ func (a *ib) InitFromWebLogin(reqHeader *http.Header, resCookies []*http.Cookie)
 	}
 
 	a.header = header
-	a.client = &http.Client{
-		Timeout: time.Minute * 3,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-			},
-		},
-	}
+	a.client = newClient(a.cassette)
 
 	res, err := a.ssoValidate()
 
@@ -114,22 +127,39 @@ func (a *ib) InitFromWebLogin(reqHeader *http.Header, resCookies []*http.Cookie)
 		return errors.New("session is invalid")
 	}
 
-//	orders,err := a.getAccountOrders()
-//	pnl,err := a.getAccountProfitAndLoss()
-//	tic,err := a.tickle()
-//	fmt.Println("RES:"+tic.Session)
 	return nil
 }
 
 //=============================================================================
+//--- There is no token: the session is kept alive by tickling the gateway
 
 func (a *ib) GetTokenExpSeconds() int {
-	return 0;
+	return SessionTimeout
 }
 
 //=============================================================================
 
 func (a *ib) RefreshToken() error {
+	res,err := a.tickle()
+	if err != nil {
+		return err
+	}
+
+	//--- ssoExpires is the time left (in millis) before the SSO session expires
+
+	if res.Session == "" || res.SsoExpires <= 0 {
+		return fmt.Errorf("%w: the SSO session has expired", adapter.ErrSessionExpired)
+	}
+
+	status,err := a.authStatus()
+	if err != nil {
+		return err
+	}
+
+	if !status.Authenticated {
+		return fmt.Errorf("%w: the gateway is no longer authenticated (%s)", adapter.ErrSessionExpired, status.Message)
+	}
+
 	return nil
 }
 
@@ -140,43 +170,223 @@ func (a *ib) RefreshToken() error {
 //=============================================================================
 
 func (a *ib) GetRootSymbols(filter string) ([]*adapter.RootSymbol,error) {
-	return nil, nil
+	list,err := a.searchFutures(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*adapter.RootSymbol
+
+	for _,cf := range list {
+		roots = append(roots, convertRootSymbol(cf))
+	}
+
+	return roots, nil
 }
 
 //=============================================================================
 
 func (a *ib) GetRootSymbol(root string) (*adapter.RootSymbol,error) {
-	return nil, nil
+	list,err := a.searchFutures(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _,cf := range list {
+		if cf.Symbol == root {
+			return convertRootSymbol(cf), nil
+		}
+	}
+
+	return nil, req.NewNotFoundError("Root symbol not found: %v", root)
 }
 
 //=============================================================================
 
 func (a *ib) GetInstruments(root string) ([]*adapter.Instrument,error) {
-	return nil, nil
+	futures,err := a.getFutures(root)
+	if err != nil {
+		return nil, req.NewServiceUnavailableError("Cannot get instruments: %v", err)
+	}
+
+	var instruments []*adapter.Instrument
+
+	for _,fc := range futures {
+		info,err := a.getContractInfo(fc.ContractId)
+		if err != nil {
+			return nil, req.NewServiceUnavailableError("Cannot get contract details: %v", err)
+		}
+
+		month   := getContractMonth(fc, info)
+		expDate := adapter.IntDateToTime(datatype.IntDate(fc.ExpirationDate), time.UTC)
+		cs      := newContractSymbol(root, month)
+
+		i := &adapter.Instrument{
+			Name          : adapter.FormatFuturesSymbol(cs),
+			Description   : root +" "+ adapter.IntDateToTime(datatype.IntDate(month*100 +1), time.UTC).Format("Jan 2006"),
+			Exchange      : info.Exchange,
+			Root          : root,
+			ExpirationDate: &expDate,
+			PointValue    : int(toOptFloat64(info.Multiplier)),
+			Month         : cs.MonthCode(),
+		}
+
+		if info.Rules != nil {
+			i.MinMove = info.Rules.Increment
+		}
+
+		instruments = append(instruments, i)
+	}
+
+	return instruments, nil
 }
 
 //=============================================================================
 
-func (a *ib) GetPriceBars(symbol string, date datatype.IntDate) (*adapter.PriceBars,error) {
-	return nil, nil
+func (a *ib) GetPriceBars(rq *adapter.PriceBarsRequest) (*adapter.PriceBars,error) {
+	bar,err := toIbBarSize(rq)
+	if err != nil {
+		return nil, err
+	}
+
+	conid,err := a.findContractId(rq.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	priceBars := adapter.NewPriceBars(rq)
+
+	//--- History requests are limited in data points, so the range is split into chunks that
+	//--- are requested backwards from their end time
+
+	barLen := getBarLength(rq)
+
+	for _,ch := range buildChunks(rq, barLen) {
+		var res HistoryResponse
+		err = a.doGet(a.buildUrl(UrlMarketDataHistory) +"?conid="+ strconv.Itoa(conid) +"&bar="+ bar +"&period="+ ch.period +
+			"&startTime="+ ch.end.Format("20060102-15:04:05") +"&outsideRth=true", &res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _,hb := range res.Data {
+			pb := convertBar(hb, barLen)
+			if isInRange(pb.TimeStamp, rq.From, rq.To) {
+				priceBars.Bars = append(priceBars.Bars, pb)
+			}
+		}
+	}
+
+	priceBars.NoData = len(priceBars.Bars) == 0
+
+	return priceBars, nil
 }
 
 //=============================================================================
 
 func (a *ib) GetAccounts() ([]*adapter.Account,error) {
-	return nil, nil
+	var res AccountsResponse
+	err := a.doGet(a.buildUrl(UrlAccounts), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	pnl,err := a.getAccountProfitAndLoss()
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []*adapter.Account
+
+	for _,code := range res.Accounts {
+		acc := &adapter.Account{
+			Code: code,
+			Type: adapter.AccountTypeFutures,
+		}
+
+		//--- Partitioned P&L is keyed by <account>.<model>, like U1234567.Core
+
+		if upnl,ok := pnl.UpdatedPnL[code +".Core"]; ok {
+			acc.Equity               = upnl.NetLiquidity
+			acc.UnrealizedProfitLoss = upnl.UnrealizedPnL
+			acc.MaintenanceMargin    = upnl.NetLiquidity - upnl.ExcessLiquidity
+		}
+
+		accounts = append(accounts, acc)
+	}
+
+	return accounts, nil
 }
 
 //=============================================================================
 
-func (a *ib) GetOrders() (any,error) {
-	return nil, nil
+func (a *ib) GetOrders() ([]*adapter.Order,error) {
+	res,err := a.getAccountOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	var orders []*adapter.Order
+
+	for _,o := range res.Orders {
+		orders = append(orders, convertOrder(o))
+	}
+
+	return orders, nil
+}
+
+//=============================================================================
+
+func (a *ib) PlaceOrder(o *adapter.Order) (*adapter.Order,error) {
+	return nil, adapter.NewNotSupportedError(a, "PlaceOrder")
 }
 
 //=============================================================================
 
-func (a *ib) GetPositions() (any,error) {
-	return nil, nil
+func (a *ib) ModifyOrder(id string, oc *adapter.OrderChange) (*adapter.Order,error) {
+	return nil, adapter.NewNotSupportedError(a, "ModifyOrder")
+}
+
+//=============================================================================
+
+func (a *ib) CancelOrder(id string) error {
+	return adapter.NewNotSupportedError(a, "CancelOrder")
+}
+
+//=============================================================================
+
+func (a *ib) GetPositions() ([]*adapter.Position,error) {
+	var res AccountsResponse
+	err := a.doGet(a.buildUrl(UrlAccounts), &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []*adapter.Position
+
+	for _,code := range res.Accounts {
+		for page:=0; ; page++ {
+			var list []*Position
+			err = a.doGet(a.buildUrl(UrlPortfolio) +"/"+ code +"/positions/"+ strconv.Itoa(page), &list)
+			if err != nil {
+				return nil, err
+			}
+
+			for _,p := range list {
+				if p.Position != 0 {
+					positions = append(positions, convertPosition(p, a.getContractName(p.ContractId, p.Ticker)))
+				}
+			}
+
+			//--- Positions are returned in pages of 100 items
+
+			if len(list) < 100 {
+				break
+			}
+		}
+	}
+
+	return positions, nil
 }
 
 //=============================================================================
@@ -185,6 +395,54 @@ func (a *ib) TestService(path,param string) (string,error) {
 	return "", nil
 }
 
+//=============================================================================
+
+func (a *ib) Subscribe(symbol string, st adapter.StreamType, handler adapter.StreamHandler) error {
+	return adapter.NewNotSupportedError(a, "Subscribe")
+}
+
+//=============================================================================
+
+func (a *ib) Unsubscribe(symbol string, st adapter.StreamType) error {
+	return adapter.NewNotSupportedError(a, "Unsubscribe")
+}
+
+//=============================================================================
+
+func (a *ib) SetCassetteOwner(username, connectionCode string) {
+	a.cassette.SetOwner(username, connectionCode)
+}
+
+//=============================================================================
+//===
+//=== Symbology
+//===
+//=============================================================================
+//--- Contracts are addressed by name (like ESH25) and resolved to a contract id
+//--- when used. There is no continuous contract
+
+func (a *ib) ToAdapterSymbol(cs *adapter.CanonicalSymbol) (string, error) {
+	if cs.IsContinuous() {
+		return "", errors.New("continuous contracts are not supported: "+ cs.String())
+	}
+
+	return adapter.FormatFuturesSymbol(cs), nil
+}
+
+//=============================================================================
+
+func (a *ib) ToCanonicalSymbol(symbol string, exchange string) (*adapter.CanonicalSymbol, error) {
+	if conid,err := strconv.Atoi(symbol); err == nil {
+		symbol = a.findContractName(conid)
+	}
+
+	if cs, ok := adapter.ParseFuturesSymbol(symbol, exchange); ok {
+		return cs, nil
+	}
+
+	return nil, errors.New("not a futures symbol: "+ symbol)
+}
+
 //=============================================================================
 //===
 //=== Private functions
@@ -193,13 +451,23 @@ func (a *ib) TestService(path,param string) (string,error) {
 
 func retrieveParams(values map[string]any) *Params {
 	return &Params{
-		AuthUrl: values[ParamAuthUrl] .(string),
-		ApiUrl : values[ParamApiUrl]  .(string),
+		AuthUrl: paramAuthUrl.GetString(values),
+		ApiUrl : paramApiUrl .GetString(values),
+		NoAuth : paramNoAuth .GetBool  (values),
 	}
 }
 
 //=============================================================================
 
+func newClient(cassette *adapter.Cassette) *http.Client {
+	client := adapter.NewHttpClient(nil, time.Minute * 3)
+	client.Transport = cassette.Wrap(client.Transport)
+
+	return client
+}
+
+//=============================================================================
+
 func buildHttpHeader(reqHeader *http.Header, resCookies []*http.Cookie) (*http.Header, error) {
 	userId, err := findUserId(resCookies)
 	if err != nil {
@@ -279,7 +547,7 @@ func (a *ib) doPost(url string, params any, output any) error {
 //=============================================================================
 
 func (a *ib) ssoValidate() (*Validate, error) {
-	apiUrl := a.configParams.ApiUrl +"/v1/api/sso/validate"
+	apiUrl := a.buildUrl(UrlSsoValidate)
 	var res Validate
 	err := a.doGet(apiUrl, &res)
 
@@ -289,7 +557,7 @@ func (a *ib) ssoValidate() (*Validate, error) {
 //=============================================================================
 
 func (a *ib) getAccountOrders() (*OrdersResponse, error) {
-	apiUrl := a.configParams.ApiUrl +"/v1/api/iserver/account/orders?force=true"
+	apiUrl := a.buildUrl(UrlAccountOrders) +"?force=true"
 	var res OrdersResponse
 	err := a.doGet(apiUrl, &res)
 
@@ -299,7 +567,7 @@ func (a *ib) getAccountOrders() (*OrdersResponse, error) {
 //=============================================================================
 
 func (a *ib) getAccountProfitAndLoss() (*AccountPnLResponse, error) {
-	apiUrl := a.configParams.ApiUrl +"/v1/api/iserver/account/pnl/partitioned"
+	apiUrl := a.buildUrl(UrlAccountPnL)
 	var res AccountPnLResponse
 	err := a.doGet(apiUrl, &res)
 
@@ -309,7 +577,7 @@ func (a *ib) getAccountProfitAndLoss() (*AccountPnLResponse, error) {
 //=============================================================================
 
 func (a *ib) tickle() (*TickleResponse, error) {
-	apiUrl := a.configParams.ApiUrl +"/v1/api/tickle"
+	apiUrl := a.buildUrl(UrlTickle)
 	var res TickleResponse
 	err := a.doPost(apiUrl, "{}", &res)
 
@@ -317,3 +585,445 @@ func (a *ib) tickle() (*TickleResponse, error) {
 }
 
 //=============================================================================
+
+func (a *ib) authStatus() (*AuthStatus, error) {
+	apiUrl := a.buildUrl(UrlAuthStatus)
+	var res AuthStatus
+	err := a.doPost(apiUrl, "{}", &res)
+
+	return &res, err
+}
+
+//=============================================================================
+
+func (a *ib) searchFutures(symbol string) ([]*ContractFound, error) {
+	apiUrl := a.buildUrl(UrlSecDefSearch)
+	params := SearchRequest{
+		Symbol : symbol,
+		Name   : false,
+		SecType: "FUT",
+	}
+
+	var res []*ContractFound
+	err := a.doPost(apiUrl, &params, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*ContractFound
+
+	for _,cf := range res {
+		for _,sec := range cf.Sections {
+			if sec.SecType == "FUT" {
+				list = append(list, cf)
+				break
+			}
+		}
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+//--- Also fills the contract cache, used to resolve instrument names. Names are
+//--- built from the contract month, that the futures list does not provide
+
+func (a *ib) getFutures(root string) ([]*FutureContract, error) {
+	apiUrl := a.buildUrl(UrlFutures) +"?symbols="+ url.QueryEscape(root)
+	var res map[string][]*FutureContract
+	err := a.doGet(apiUrl, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	futures := res[root]
+
+	for _,fc := range futures {
+		info,err := a.getContractInfo(fc.ContractId)
+		if err != nil {
+			return nil, err
+		}
+
+		a.contracts.Lock()
+		a.contracts.conids[adapter.FormatFuturesSymbol(newContractSymbol(root, getContractMonth(fc, info)))] = fc.ContractId
+		a.contracts.Unlock()
+	}
+
+	return futures, nil
+}
+
+//=============================================================================
+//--- Contract details never change, so they are asked only once
+
+func (a *ib) getContractInfo(conid int) (*ContractInfo, error) {
+	a.contracts.Lock()
+	info,found := a.contracts.infos[conid]
+	a.contracts.Unlock()
+
+	if found {
+		return info, nil
+	}
+
+	info = &ContractInfo{}
+	err := a.doGet(a.buildUrl(UrlContract) +"/"+ strconv.Itoa(conid) +"/info-and-rules?isBuy=true", info)
+	if err != nil {
+		return nil, err
+	}
+
+	a.contracts.Lock()
+	a.contracts.infos[conid] = info
+	a.contracts.Unlock()
+
+	return info, nil
+}
+
+//=============================================================================
+//--- Symbols can be either a contract id or an instrument name (like ESH25)
+
+func (a *ib) findContractId(symbol string) (int, error) {
+	if conid,err := strconv.Atoi(symbol); err == nil {
+		return conid, nil
+	}
+
+	a.contracts.Lock()
+	conid,found := a.contracts.conids[symbol]
+	a.contracts.Unlock()
+
+	if found {
+		return conid, nil
+	}
+
+	root := symbol
+	if cs,ok := adapter.ParseFuturesSymbol(symbol, ""); ok {
+		root = cs.Root
+	}
+
+	_,err := a.getFutures(root)
+	if err != nil {
+		return 0, err
+	}
+
+	a.contracts.Lock()
+	defer a.contracts.Unlock()
+
+	conid,found = a.contracts.conids[symbol]
+	if !found {
+		return 0, req.NewNotFoundError("Instrument not found: %v", symbol)
+	}
+
+	return conid, nil
+}
+
+//=============================================================================
+//--- Reverse lookup in the contract cache. Returns an empty string if unknown
+
+func (a *ib) findContractName(conid int) string {
+	a.contracts.Lock()
+	defer a.contracts.Unlock()
+
+	for name, id := range a.contracts.conids {
+		if id == conid {
+			return name
+		}
+	}
+
+	return ""
+}
+
+//=============================================================================
+//--- Positions only have a description (like "ES MAR2025"), so the name is taken
+//--- from the contract cache, filled with the root's futures if needed
+
+func (a *ib) getContractName(conid int, root string) string {
+	if name := a.findContractName(conid); name != "" {
+		return name
+	}
+
+	_,err := a.getFutures(root)
+	if err != nil {
+		slog.Warn("getContractName: Cannot get the futures of root", "root", root, "error", err.Error())
+		return ""
+	}
+
+	return a.findContractName(conid)
+}
+
+//=============================================================================
+
+func (a *ib) buildUrl(path string) string {
+	return a.configParams.ApiUrl + path
+}
+
+//=============================================================================
+//===
+//=== Conversion functions
+//===
+//=============================================================================
+
+func convertRootSymbol(cf *ContractFound) *adapter.RootSymbol {
+	rs := &adapter.RootSymbol{
+		Code      : cf.Symbol,
+		Instrument: cf.CompanyName,
+		Exchange  : cf.Description,
+	}
+
+	for _,sec := range cf.Sections {
+		if sec.SecType == "FUT" && sec.Exchange != "" {
+			rs.Exchange = strings.Split(sec.Exchange, ";")[0]
+			break
+		}
+	}
+
+	return rs
+}
+
+//=============================================================================
+
+func convertOrder(o *Order) *adapter.Order {
+	ao := &adapter.Order{
+		Id            : strconv.Itoa(o.OrderId),
+		Account       : o.AccountId,
+		Symbol        : o.Ticker,
+		Side          : adapter.OrderSideBuy,
+		Quantity      : float64(o.TotalSize),
+		Type          : fromIbOrderType(o.OrderType),
+		TimeInForce   : fromIbTimeInForce(o.TimeInForce),
+		Status        : fromIbOrderStatus(o.Status),
+		FilledQuantity: float64(o.FilledQuantity),
+		AveragePrice  : toOptFloat64(o.AveragePrice),
+	}
+
+	if o.Side == "SELL" {
+		ao.Side = adapter.OrderSideSell
+	}
+
+	if o.LastExecutionTimeR > 0 && ao.FilledQuantity > 0 {
+		ts := time.UnixMilli(o.LastExecutionTimeR)
+		ao.Fills = []*adapter.Fill{
+			{
+				Id       : ao.Id,
+				Quantity : ao.FilledQuantity,
+				Price    : ao.AveragePrice,
+				TimeStamp: ts,
+			},
+		}
+
+		if !ao.IsOpen() {
+			ao.ClosedAt = &ts
+		}
+	}
+
+	return ao
+}
+
+//=============================================================================
+
+func convertPosition(p *Position, name string) *adapter.Position {
+	if name == "" {
+		name = p.ContractDesc
+	}
+
+	return &adapter.Position{
+		Account             : p.AccountId,
+		Symbol              : name,
+		Root                : p.Ticker,
+		Quantity            : p.Position,
+		AveragePrice        : p.AveragePrice,
+		MarketValue         : p.MarketValue,
+		UnrealizedProfitLoss: p.UnrealizedPnL,
+	}
+}
+
+//=============================================================================
+
+//--- IBKR stamps bars with their start time and gives the total volume only, so
+//--- bars are moved to their close time and the volume is split evenly
+
+func convertBar(hb *HistoryBar, barLen time.Duration) *adapter.PriceBar {
+	volume := int(hb.Volume)
+
+	return &adapter.PriceBar{
+		TimeStamp : time.UnixMilli(hb.Time).UTC().Add(barLen),
+		Open      : hb.Open,
+		High      : hb.High,
+		Low       : hb.Low,
+		Close     : hb.Close,
+		UpVolume  : volume - volume/2,
+		DownVolume: volume/2,
+	}
+}
+
+//=============================================================================
+
+func fromIbOrderStatus(status string) adapter.OrderStatus {
+	switch status {
+		case "PendingSubmit", "PreSubmitted":
+			return adapter.OrderStatusPending
+		case "Filled":
+			return adapter.OrderStatusFilled
+		case "Cancelled", "PendingCancel":
+			return adapter.OrderStatusCancelled
+		case "Inactive":
+			return adapter.OrderStatusRejected
+	}
+
+	return adapter.OrderStatusWorking
+}
+
+//=============================================================================
+
+func fromIbOrderType(orderType string) adapter.OrderType {
+	switch strings.ToUpper(orderType) {
+		case "LIMIT", "LMT":
+			return adapter.OrderTypeLimit
+		case "STOP", "STP":
+			return adapter.OrderTypeStop
+		case "STOP_LIMIT", "STOPLIMIT", "STP LMT":
+			return adapter.OrderTypeStopLimit
+	}
+
+	return adapter.OrderTypeMarket
+}
+
+//=============================================================================
+
+func fromIbTimeInForce(tif string) adapter.TimeInForce {
+	switch strings.ToUpper(tif) {
+		case "GTC":
+			return adapter.TimeInForceGTC
+		case "IOC":
+			return adapter.TimeInForceIOC
+		case "FOK":
+			return adapter.TimeInForceFOK
+	}
+
+	return adapter.TimeInForceDay
+}
+
+//=============================================================================
+
+func toIbBarSize(rq *adapter.PriceBarsRequest) (string,error) {
+	switch rq.Unit {
+		case adapter.BarUnitMinute:
+			switch rq.Interval {
+				case 1, 2, 3, 5, 10, 15, 30:
+					return strconv.Itoa(rq.Interval) +"min", nil
+				case 60, 120, 180, 240, 480:
+					return strconv.Itoa(rq.Interval/60) +"h", nil
+			}
+			return "", req.NewBadRequestError("Interval not supported by Interactive Brokers: %v", rq.Interval)
+
+		case adapter.BarUnitDaily, adapter.BarUnitWeekly:
+			if rq.Interval != 1 {
+				return "", req.NewBadRequestError("Only an interval of 1 is supported for unit: %v", rq.Unit)
+			}
+			if rq.Unit == adapter.BarUnitDaily {
+				return "1d", nil
+			}
+			return "1w", nil
+	}
+
+	return "", req.NewBadRequestError("Bar unit not supported by Interactive Brokers: %v", rq.Unit)
+}
+
+//=============================================================================
+
+type chunk struct {
+	end    time.Time
+	period string
+}
+
+//-----------------------------------------------------------------------------
+//--- Minute bars are requested one day at a time (in 8 hours slices for 1 minute
+//--- bars, to stay below the data points limit). Daily and weekly bars in blocks
+//--- of MaxBarsPerRequest days. Requests use start times, so minute slices are
+//--- moved back by the bar length to cover the bars that close in the day
+
+func buildChunks(rq *adapter.PriceBarsRequest, barLen time.Duration) []*chunk {
+	var list []*chunk
+
+	if rq.Unit != adapter.BarUnitMinute {
+		for from := rq.From; from <= rq.To; from = adapter.AddDays(from, MaxBarsPerRequest) {
+			to := min(adapter.AddDays(from, MaxBarsPerRequest -1), rq.To)
+			list = append(list, &chunk{
+				end   : adapter.IntDateToTime(to, time.UTC).Add(24*time.Hour - time.Second),
+				period: strconv.Itoa(adapter.DaysBetween(from, to) +1) +"d",
+			})
+		}
+
+		return list
+	}
+
+	for day := rq.From; day <= rq.To; day = adapter.AddDays(day, 1) {
+		start := adapter.IntDateToTime(day, time.UTC).Add(-barLen)
+
+		if rq.Interval > 1 {
+			list = append(list, &chunk{ end: start.Add(24*time.Hour - time.Second), period: "1d" })
+		} else {
+			for h:=8; h<=24; h+=8 {
+				list = append(list, &chunk{ end: start.Add(time.Duration(h)*time.Hour - time.Second), period: "8h" })
+			}
+		}
+	}
+
+	return list
+}
+
+//=============================================================================
+
+func isInRange(ts time.Time, from, to datatype.IntDate) bool {
+	date := adapter.TimeToIntDate(ts)
+	return date >= from && date <= to
+}
+
+
+//=============================================================================
+//--- Only intraday bars are moved to their close time
+
+func getBarLength(rq *adapter.PriceBarsRequest) time.Duration {
+	if rq.Unit != adapter.BarUnitMinute {
+		return 0
+	}
+
+	return time.Duration(rq.Interval) * time.Minute
+}
+
+//=============================================================================
+//--- Returns the contract month as YYYYMM. The expiration can fall in an earlier
+//--- month (like CL, that expires the month before), so it is only a fallback
+
+func getContractMonth(fc *FutureContract, info *ContractInfo) int {
+	if month,err := strconv.Atoi(info.ContractMonth); err == nil && month%100 >= 1 && month%100 <= 12 {
+		return month
+	}
+
+	return fc.ExpirationDate / 100
+}
+
+//=============================================================================
+
+func newContractSymbol(root string, contractMonth int) *adapter.CanonicalSymbol {
+	return &adapter.CanonicalSymbol{
+		Root : root,
+		Year : contractMonth / 100,
+		Month: contractMonth % 100,
+	}
+}
+
+//=============================================================================
+
+func toOptFloat64(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	val, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		slog.Warn("Interactive: Error converting value to float64", "value", value)
+		return 0
+	}
+
+	return val
+}
+
+//=============================================================================
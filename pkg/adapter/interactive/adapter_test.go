@@ -0,0 +1,415 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package interactive
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/adaptertest"
+)
+
+//=============================================================================
+
+const (
+	testAccount = "U1234567"
+	testConid   = 495512551
+	testClConid = 212921504
+)
+
+//=============================================================================
+//--- CL expires in the month before the contract month
+
+var testFutures = map[string][]*FutureContract{
+	"ES": {
+		{ Symbol: "ES", ContractId: testConid,   ExpirationDate: 20250321 },
+		{ Symbol: "ES", ContractId: testConid+1, ExpirationDate: 20250620 },
+	},
+	"CL": {
+		{ Symbol: "CL", ContractId: testClConid, ExpirationDate: 20250220 },
+	},
+}
+
+var testContracts = map[int]*ContractInfo{
+	testConid  : { ContractId: testConid,   Symbol: "ES", Exchange: "CME",   Multiplier: "50",   ContractMonth: "202503", Rules: &ContractRules{ Increment: 0.25 } },
+	testConid+1: { ContractId: testConid+1, Symbol: "ES", Exchange: "CME",   Multiplier: "50",   ContractMonth: "202506", Rules: &ContractRules{ Increment: 0.25 } },
+	testClConid: { ContractId: testClConid, Symbol: "CL", Exchange: "NYMEX", Multiplier: "1000", ContractMonth: "202503", Rules: &ContractRules{ Increment: 0.01 } },
+}
+
+//=============================================================================
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) *adaptertest.Setup {
+		adaptertest.NewBackend(t, newFakeGateway(t, newGatewaySession()))
+
+		return &adaptertest.Setup{
+			Adapter     : NewAdapter(),
+			ConfigParams: map[string]any{
+				ParamApiUrl: "https://gateway.local",
+				ParamNoAuth: true,
+			},
+			Fixture: adaptertest.Fixture{
+				Root      : "ES",
+				Instrument: "ESH25",
+				Bars      : adapter.NewPriceBarsRequest("ESH25", 20250102),
+				MinBars   : 3,
+				Accounts  : []string{ testAccount },
+				Orders    : []string{ "1001" },
+				Positions : []string{ "ESH25" },
+			},
+		}
+	})
+}
+
+//=============================================================================
+
+func TestInstrumentDetails(t *testing.T) {
+	ctx := connectToGateway(t)
+
+	list,err := ctx.GetInstruments("ES")
+	if err != nil || len(list) != 2 {
+		t.Fatalf("expected 2 instruments, got %v (error: %v)", list, err)
+	}
+
+	i := list[0]
+	if i.Name != "ESH25" || i.Exchange != "CME" || i.PointValue != 50 || i.MinMove != 0.25 {
+		t.Errorf("bad instrument details: %+v", i)
+	}
+
+	//--- The name comes from the contract month, not from the expiration
+
+	list,err = ctx.GetInstruments("CL")
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 instrument, got %v (error: %v)", list, err)
+	}
+
+	i = list[0]
+	if i.Name != "CLH25" || i.Month != "H" || i.Exchange != "NYMEX" || i.PointValue != 1000 || i.MinMove != 0.01 {
+		t.Errorf("bad instrument details: %+v", i)
+	}
+}
+
+//=============================================================================
+
+func TestBarsAreStampedAtClose(t *testing.T) {
+	ctx := connectToGateway(t)
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250102)
+	rq.Interval = 60
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) == 0 {
+		t.Fatalf("expected some bars, got %v (error: %v)", pb, err)
+	}
+
+	for _,b := range pb.Bars {
+		//--- The fake gateway sets the open price from the start hour
+		if start := b.TimeStamp.Add(-time.Hour).Hour(); b.Open != 5900 + float64(start) {
+			t.Fatalf("bar at %v is not stamped at its close time (open: %v)", b.TimeStamp, b.Open)
+		}
+
+		if b.UpVolume + b.DownVolume != 100 {
+			t.Fatalf("bar at %v lost volume: %d up, %d down", b.TimeStamp, b.UpVolume, b.DownVolume)
+		}
+	}
+}
+
+//=============================================================================
+
+func TestPositionsUseInstrumentNames(t *testing.T) {
+	ctx := connectToGateway(t)
+
+	list,err := ctx.GetPositions()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 position, got %v (error: %v)", list, err)
+	}
+
+	if list[0].Symbol != "ESH25" {
+		t.Errorf("expected symbol ESH25, got %s", list[0].Symbol)
+	}
+}
+
+//=============================================================================
+
+func TestRefreshTokenDetectsExpiredSession(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		ssoExpires    int
+		authenticated bool
+	}{
+		{ "sso expired",       0,      true  },
+		{ "not authenticated", 600000, false },
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			session := newGatewaySession()
+			ctx     := connectToSession(t, session)
+
+			if err := ctx.RefreshToken(); err != nil {
+				t.Fatalf("refresh of a valid session failed: %v", err)
+			}
+
+			session.set(tc.ssoExpires, tc.authenticated)
+
+			err := ctx.RefreshToken()
+			if !errors.Is(err, adapter.ErrSessionExpired) {
+				t.Fatalf("expected a session expired error, got %v", err)
+			}
+
+			//--- No retries for an expired session
+
+			if ctx.IsConnected() || ctx.GetStatus() != adapter.ContextStatusDisconnected {
+				t.Errorf("the context must be disconnected, status is %v", ctx.GetStatus())
+			}
+		})
+	}
+}
+
+//=============================================================================
+//===
+//=== Fake Client Portal gateway
+//===
+//=============================================================================
+
+//--- State of the gateway session, changed by the tests to simulate its expiration
+
+type gatewaySession struct {
+	sync.Mutex
+	ssoExpires    int
+	authenticated bool
+}
+
+//-----------------------------------------------------------------------------
+
+func newGatewaySession() *gatewaySession {
+	return &gatewaySession{
+		ssoExpires   : 600000,
+		authenticated: true,
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func (s *gatewaySession) set(ssoExpires int, authenticated bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.ssoExpires    = ssoExpires
+	s.authenticated = authenticated
+}
+
+//-----------------------------------------------------------------------------
+
+func (s *gatewaySession) authStatus() AuthStatus {
+	s.Lock()
+	defer s.Unlock()
+
+	status := AuthStatus{ Authenticated: s.authenticated, Connected: true }
+	if !s.authenticated {
+		status.Message = "not authenticated"
+	}
+
+	return status
+}
+
+//=============================================================================
+
+func newFakeGateway(t *testing.T, session *gatewaySession) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST "+ UrlAuthStatus, func(w http.ResponseWriter, r *http.Request) {
+		status := session.authStatus()
+		adaptertest.WriteJson(w, http.StatusOK, &status)
+	})
+
+	mux.HandleFunc("POST "+ UrlTickle, func(w http.ResponseWriter, r *http.Request) {
+		session.Lock()
+		res := TickleResponse{ Session: "c0ffee", SsoExpires: session.ssoExpires }
+		session.Unlock()
+
+		res.Iserver.AuthStatus = session.authStatus()
+		adaptertest.WriteJson(w, http.StatusOK, &res)
+	})
+
+	mux.HandleFunc("POST "+ UrlSecDefSearch, func(w http.ResponseWriter, r *http.Request) {
+		var rq SearchRequest
+		_ = json.NewDecoder(r.Body).Decode(&rq)
+
+		list := []*ContractFound{}
+		if rq.Symbol == "ES" {
+			list = append(list, &ContractFound{
+				CompanyName: "E-mini S&P 500",
+				Symbol     : "ES",
+				Description: "CME",
+				Sections   : []*Section{
+					{ SecType: "IND" },
+					{ SecType: "FUT", Months: "MAR25;JUN25", Exchange: "CME;" },
+				},
+			})
+		}
+
+		adaptertest.WriteJson(w, http.StatusOK, list)
+	})
+
+	mux.HandleFunc("GET "+ UrlFutures, func(w http.ResponseWriter, r *http.Request) {
+		root := r.URL.Query().Get("symbols")
+		adaptertest.WriteJson(w, http.StatusOK, map[string][]*FutureContract{ root: testFutures[root] })
+	})
+
+	mux.HandleFunc("GET "+ UrlContract +"/{conid}/info-and-rules", func(w http.ResponseWriter, r *http.Request) {
+		conid,_ := strconv.Atoi(r.PathValue("conid"))
+		info,ok := testContracts[conid]
+		if !ok {
+			adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "error": "unknown conid" })
+			return
+		}
+
+		adaptertest.WriteJson(w, http.StatusOK, info)
+	})
+
+	mux.HandleFunc("GET "+ UrlMarketDataHistory, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("conid") != strconv.Itoa(testConid) {
+			adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "error": "unknown conid" })
+			return
+		}
+
+		end,err1   := time.Parse("20060102-15:04:05", q.Get("startTime"))
+		period,err2 := parsePeriod(q.Get("period"))
+		if err1 != nil || err2 != nil {
+			t.Errorf("bad history request: %v", r.URL.RawQuery)
+			adaptertest.WriteJson(w, http.StatusBadRequest, map[string]string{ "error": "bad request" })
+			return
+		}
+
+		adaptertest.WriteJson(w, http.StatusOK, &HistoryResponse{ Symbol: "ES", Data: historyBars(end.Add(-period), end) })
+	})
+
+	mux.HandleFunc("GET "+ UrlAccounts, func(w http.ResponseWriter, r *http.Request) {
+		adaptertest.WriteJson(w, http.StatusOK, &AccountsResponse{ Accounts: []string{ testAccount }, SelectedAccount: testAccount })
+	})
+
+	mux.HandleFunc("GET "+ UrlAccountPnL, func(w http.ResponseWriter, r *http.Request) {
+		adaptertest.WriteJson(w, http.StatusOK, &AccountPnLResponse{
+			UpdatedPnL: map[string]*UpdatedPnL{
+				testAccount +".Core": { NetLiquidity: 100000, UnrealizedPnL: 250, ExcessLiquidity: 90000 },
+			},
+		})
+	})
+
+	mux.HandleFunc("GET "+ UrlAccountOrders, func(w http.ResponseWriter, r *http.Request) {
+		adaptertest.WriteJson(w, http.StatusOK, &OrdersResponse{
+			Orders: []*Order{
+				{
+					AccountId  : testAccount,
+					ContractId : testConid,
+					OrderId    : 1001,
+					Ticker     : "ES",
+					Status     : "Submitted",
+					OrderType  : "Limit",
+					TimeInForce: "GTC",
+					Side       : "BUY",
+					TotalSize  : 1,
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("GET "+ UrlPortfolio +"/{account}/positions/{page}", func(w http.ResponseWriter, r *http.Request) {
+		list := []*Position{}
+		if r.PathValue("account") == testAccount && r.PathValue("page") == "0" {
+			list = append(list,
+				&Position{ AccountId: testAccount, ContractId: testConid,   ContractDesc: "ES MAR2025", Position: 2, AveragePrice: 5900, Ticker: "ES" },
+				&Position{ AccountId: testAccount, ContractId: testConid+1, ContractDesc: "ES JUN2025", Position: 0, Ticker: "ES" },
+			)
+		}
+
+		adaptertest.WriteJson(w, http.StatusOK, list)
+	})
+
+	return mux
+}
+
+//=============================================================================
+
+func connectToGateway(t *testing.T) *adapter.ConnectionContext {
+	return connectToSession(t, newGatewaySession())
+}
+
+//=============================================================================
+
+func connectToSession(t *testing.T, session *gatewaySession) *adapter.ConnectionContext {
+	adaptertest.NewBackend(t, newFakeGateway(t, session))
+
+	return adaptertest.Connect(t, &adaptertest.Setup{
+		Adapter     : NewAdapter(),
+		ConfigParams: map[string]any{
+			ParamApiUrl: "https://gateway.local",
+			ParamNoAuth: true,
+		},
+	})
+}
+
+//=============================================================================
+//--- Returns one bar per hour in (from, to]
+
+func historyBars(from, to time.Time) []*HistoryBar {
+	var list []*HistoryBar
+
+	for ts := from.Truncate(time.Hour).Add(time.Hour); !ts.After(to); ts = ts.Add(time.Hour) {
+		price := 5900 + float64(ts.Hour())
+		list = append(list, &HistoryBar{
+			Open  : price,
+			High  : price + 1,
+			Low   : price - 1,
+			Close : price + 0.5,
+			Volume: 100,
+			Time  : ts.UnixMilli(),
+		})
+	}
+
+	return list
+}
+
+//=============================================================================
+
+func parsePeriod(period string) (time.Duration, error) {
+	unit := time.Hour
+	if strings.HasSuffix(period, "d") {
+		unit = 24 * time.Hour
+	}
+
+	n,err := strconv.Atoi(strings.TrimRight(period, "hd"))
+	return time.Duration(n) * unit, err
+}
+
+//=============================================================================
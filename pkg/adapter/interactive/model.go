@@ -27,6 +27,7 @@ package interactive
 import (
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"net/http"
+	"sync"
 )
 
 //=============================================================================
@@ -37,35 +38,50 @@ const ParamNoAuth  = "noAuth"
 
 //=============================================================================
 
+var paramAuthUrl = &adapter.ParamDef{
+	Name     : ParamAuthUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : "https://www.interactivebrokers.co.uk/sso/Login",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramApiUrl = &adapter.ParamDef{
+	Name     : ParamApiUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : "https://api.ibkr.com",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramNoAuth = &adapter.ParamDef{
+	Name     : ParamNoAuth,
+	Type     : adapter.ParamTypeBool,
+	DefValue : "false",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
 var configParams = []*adapter.ParamDef {
-	{
-		Name     : ParamAuthUrl,
-		Type     : adapter.ParamTypeString,
-		DefValue : "https://www.interactivebrokers.co.uk/sso/Login",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
-	{
-		Name     : ParamApiUrl,
-		Type     : adapter.ParamTypeString,
-		DefValue : "https://api.ibkr.com",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
-	{
-		Name     : ParamNoAuth,
-		Type     : adapter.ParamTypeBool,
-		DefValue : "false",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 0,
-		GroupName: "",
-	},
+	paramAuthUrl,
+	paramApiUrl,
+	paramNoAuth,
+	adapter.ParamDefCassetteMode,
+	adapter.ParamDefCassetteName,
 }
+
 //-----------------------------------------------------------------------------
 
 var connectParams []*adapter.ParamDef
@@ -81,6 +97,8 @@ var info = adapter.Info{
 	SupportsBroker      : true,
 	SupportsMultipleData: false,
 	SupportsInventory   : true,
+	SharedCatalog       : true,
+	SharedData          : true,
 }
 
 //=============================================================================
@@ -95,10 +113,49 @@ type Params struct {
 
 type ib struct {
 	configParams *Params
-	client *http.Client
-	header *http.Header
+	client       *http.Client
+	header       *http.Header
+	contracts    *contracts
+	cassette     *adapter.Cassette
 }
 
+//=============================================================================
+//--- Maps instrument names (like ESH25) to IBKR contract ids, and contract ids
+//--- to their details
+
+type contracts struct {
+	sync.Mutex
+	conids map[string]int
+	infos  map[int]*ContractInfo
+}
+
+//=============================================================================
+
+const (
+	UrlSsoValidate       = "/v1/api/sso/validate"
+	UrlTickle            = "/v1/api/tickle"
+	UrlAuthStatus        = "/v1/api/iserver/auth/status"
+	UrlAccounts          = "/v1/api/iserver/accounts"
+	UrlAccountOrders     = "/v1/api/iserver/account/orders"
+	UrlAccountPnL        = "/v1/api/iserver/account/pnl/partitioned"
+	UrlSecDefSearch      = "/v1/api/iserver/secdef/search"
+	UrlMarketDataHistory = "/v1/api/iserver/marketdata/history"
+	UrlPortfolio         = "/v1/api/portfolio"
+	UrlFutures           = "/v1/api/trsrv/futures"
+	UrlContract          = "/v1/api/iserver/contract"
+)
+
+//-----------------------------------------------------------------------------
+//--- The gateway session expires after a few minutes without a tickle, so
+//--- a short expiry is reported to have it tickled every 90 seconds
+
+const SessionTimeout = 2*60
+
+//-----------------------------------------------------------------------------
+//--- Maximum number of data points returned by a history request
+
+const MaxBarsPerRequest = 1000
+
 //=============================================================================
 //===
 //=== IBKR REST API structures
@@ -181,7 +238,116 @@ type TickleResponse struct {
 	Hmds      struct {
 		Error string `json:"error"`
 	} `json:"hmds"`
+	Iserver struct {
+		AuthStatus AuthStatus `json:"authStatus"`
+	} `json:"iserver"`
+}
+
+//=============================================================================
+
+type AuthStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	Connected     bool   `json:"connected"`
+	Competing     bool   `json:"competing"`
+	Message       string `json:"message"`
+}
+
+//=============================================================================
+
+type AccountsResponse struct {
+	Accounts        []string `json:"accounts"`
+	SelectedAccount string   `json:"selectedAccount"`
+}
+
+//=============================================================================
+
+type Position struct {
+	AccountId     string  `json:"acctId"`
+	ContractId    int     `json:"conid"`
+	ContractDesc  string  `json:"contractDesc"`
+	Position      float64 `json:"position"`
+	MarketPrice   float64 `json:"mktPrice"`
+	MarketValue   float64 `json:"mktValue"`
+	Currency      string  `json:"currency"`
+	AverageCost   float64 `json:"avgCost"`
+	AveragePrice  float64 `json:"avgPrice"`
+	RealizedPnL   float64 `json:"realizedPnl"`
+	UnrealizedPnL float64 `json:"unrealizedPnl"`
+	AssetClass    string  `json:"assetClass"`            // FUT
+	Ticker        string  `json:"ticker"`
+}
+
+//=============================================================================
+
+type SearchRequest struct {
+	Symbol  string `json:"symbol"`
+	Name    bool   `json:"name"`
+	SecType string `json:"secType"`
+}
+
+//-----------------------------------------------------------------------------
+
+type ContractFound struct {
+	CompanyHeader string     `json:"companyHeader"`
+	CompanyName   string     `json:"companyName"`
+	Symbol        string     `json:"symbol"`
+	Description   string     `json:"description"`        // Exchange
+	Sections      []*Section `json:"sections"`
+}
+
+//-----------------------------------------------------------------------------
+
+type Section struct {
+	SecType  string `json:"secType"`                     // FUT
+	Months   string `json:"months"`                      // MAR25;JUN25
+	Exchange string `json:"exchange"`
+}
+
+//=============================================================================
+
+type FutureContract struct {
+	Symbol          string `json:"symbol"`
+	ContractId      int    `json:"conid"`
+	UnderlyingConid int    `json:"underlyingConid"`
+	ExpirationDate  int    `json:"expirationDate"`     // YYYYMMDD
+	LastTradingDay  int    `json:"ltd"`                // YYYYMMDD
+}
+
+//=============================================================================
+
+type ContractInfo struct {
+	ContractId    int            `json:"con_id"`
+	Symbol        string         `json:"symbol"`
+	LocalSymbol   string         `json:"local_symbol"`       // ESH5
+	Exchange      string         `json:"exchange"`
+	Currency      string         `json:"currency"`
+	Multiplier    string         `json:"multiplier"`         // 50
+	ContractMonth string         `json:"contract_month"`     // YYYYMM
+	Rules         *ContractRules `json:"rules"`
+}
+
+//-----------------------------------------------------------------------------
+
+type ContractRules struct {
+	Increment float64 `json:"increment"`
+}
+
+//=============================================================================
+
+type HistoryResponse struct {
+	Symbol string        `json:"symbol"`
+	Data   []*HistoryBar `json:"data"`
+}
+
+//-----------------------------------------------------------------------------
 
+type HistoryBar struct {
+	Open   float64 `json:"o"`
+	Close  float64 `json:"c"`
+	High   float64 `json:"h"`
+	Low    float64 `json:"l"`
+	Volume float64 `json:"v"`
+	Time   int64   `json:"t"`                          // Unix millis, start of the bar
 }
 
 //=============================================================================
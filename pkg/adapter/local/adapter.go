@@ -25,42 +25,22 @@ THE SOFTWARE.
 package local
 
 import (
+	"errors"
+	"net/http"
+
 	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
-	"net/http"
 )
 
 //=============================================================================
 
-var configParams  []*adapter.ParamDef
-var connectParams []*adapter.ParamDef
-
-//-----------------------------------------------------------------------------
-
-var info = adapter.Info{
-	Code                : "LOCAL",
-	Name                : "Local system",
-	ConfigParams        : configParams,
-	ConnectParams       : connectParams,
-	SupportsData        : true,
-	SupportsBroker      : true,
-	SupportsMultipleData: true,
-	SupportsInventory   : false,
-}
-
-//=============================================================================
-
 func NewAdapter() adapter.Adapter {
 	return &local{}
 }
 
 //=============================================================================
 
-type local struct {
-}
-
-//=============================================================================
-
 func (a *local) GetInfo() *adapter.Info {
 	return &info
 }
@@ -75,6 +55,17 @@ func (a *local) GetAuthUrl() string {
 
 func (a *local) Clone(configParams map[string]any, connectParams map[string]any) adapter.Adapter {
 	b := *a
+	b.configParams = retrieveConfigParams(configParams)
+	b.store        = nil
+	b.feed         = nil
+
+	if b.configParams.DataDir != "" {
+		b.store = newFileStore(b.configParams.DataDir)
+		b.feed  = b.store
+	}
+
+	b.broker = newBroker(b.configParams, b.store)
+
 	return &b
 }
 
@@ -121,43 +112,77 @@ func (a *local) RefreshToken() error {
 //=============================================================================
 
 func (a *local) GetRootSymbols(filter string) ([]*adapter.RootSymbol,error) {
-	return nil, nil
+	if a.store == nil {
+		return nil, adapter.NewNotSupportedError(a, "GetRootSymbols")
+	}
+
+	return a.store.getRootSymbols(filter)
 }
 
 //=============================================================================
 
 func (a *local) GetRootSymbol(root string) (*adapter.RootSymbol,error) {
-	return nil, nil
+	if a.store == nil {
+		return nil, adapter.NewNotSupportedError(a, "GetRootSymbol")
+	}
+
+	return a.store.getRootSymbol(root)
 }
 
 //=============================================================================
 
 func (a *local) GetInstruments(root string) ([]*adapter.Instrument,error) {
-	return nil, nil
+	if a.store == nil {
+		return nil, adapter.NewNotSupportedError(a, "GetInstruments")
+	}
+
+	return a.store.getInstruments(root)
 }
 
 //=============================================================================
 
-func (a *local) GetPriceBars(symbol string, date datatype.IntDate) (*adapter.PriceBars,error) {
-	return nil, nil
+func (a *local) GetPriceBars(rq *adapter.PriceBarsRequest) (*adapter.PriceBars,error) {
+	if a.feed == nil {
+		return nil, adapter.NewNotSupportedError(a, "GetPriceBars")
+	}
+
+	return a.feed.GetPriceBars(rq)
 }
 
 //=============================================================================
 
 func (a *local) GetAccounts() ([]*adapter.Account,error) {
-	return nil, nil
+	return a.broker.getAccounts(), nil
 }
 
 //=============================================================================
 
-func (a *local) GetOrders() (any,error) {
-	return nil, nil
+func (a *local) GetOrders() ([]*adapter.Order,error) {
+	return a.broker.getOrders(), nil
 }
 
 //=============================================================================
 
-func (a *local) GetPositions() (any,error) {
-	return nil, nil
+func (a *local) PlaceOrder(o *adapter.Order) (*adapter.Order,error) {
+	return a.broker.placeOrder(o)
+}
+
+//=============================================================================
+
+func (a *local) ModifyOrder(id string, oc *adapter.OrderChange) (*adapter.Order,error) {
+	return a.broker.modifyOrder(id, oc)
+}
+
+//=============================================================================
+
+func (a *local) CancelOrder(id string) error {
+	return a.broker.cancelOrder(id)
+}
+
+//=============================================================================
+
+func (a *local) GetPositions() ([]*adapter.Position,error) {
+	return a.broker.getPositions(), nil
 }
 
 //=============================================================================
@@ -167,3 +192,81 @@ func (a *local) TestService(path,param string) (string,error) {
 }
 
 //=============================================================================
+
+func (a *local) Subscribe(symbol string, st adapter.StreamType, handler adapter.StreamHandler) error {
+	return adapter.NewNotSupportedError(a, "Subscribe")
+}
+
+//=============================================================================
+
+func (a *local) Unsubscribe(symbol string, st adapter.StreamType) error {
+	return adapter.NewNotSupportedError(a, "Unsubscribe")
+}
+
+//=============================================================================
+//===
+//=== Symbology
+//===
+//=============================================================================
+//--- Instruments are named like ESH25. There is no continuous contract
+
+func (a *local) ToAdapterSymbol(cs *adapter.CanonicalSymbol) (string, error) {
+	if cs.IsContinuous() {
+		return "", errors.New("continuous contracts are not supported: "+ cs.String())
+	}
+
+	return adapter.FormatFuturesSymbol(cs), nil
+}
+
+//=============================================================================
+
+func (a *local) ToCanonicalSymbol(symbol string, exchange string) (*adapter.CanonicalSymbol, error) {
+	if cs, ok := adapter.ParseFuturesSymbol(symbol, exchange); ok {
+		return cs, nil
+	}
+
+	return nil, errors.New("not a futures symbol: "+ symbol)
+}
+
+//=============================================================================
+//===
+//=== Simulation
+//===
+//=============================================================================
+
+//--- Without a feed connection, bars are read from the data directory (if any)
+
+func (a *local) SetPriceFeed(feed adapter.PriceFeed) {
+	if feed == nil && a.store != nil {
+		feed = a.store
+	}
+
+	a.feed = feed
+}
+
+//=============================================================================
+
+func (a *local) Replay(symbol string, date datatype.IntDate) (*adapter.ReplayResult,error) {
+	if a.feed == nil {
+		return nil, req.NewBadRequestError("No price feed configured for the local adapter")
+	}
+
+	pb,err := a.feed.GetPriceBars(adapter.NewPriceBarsRequest(symbol, date))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &adapter.ReplayResult{
+		Symbol: symbol,
+		Date  : int(date),
+	}
+
+	if pb != nil {
+		res.Bars  = len(pb.Bars)
+		res.Fills = a.broker.processBars(symbol, pb.Bars)
+	}
+
+	return res, nil
+}
+
+//=============================================================================
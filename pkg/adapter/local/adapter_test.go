@@ -0,0 +1,225 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/adaptertest"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+
+const testRoot = `{ "code": "ES", "instrument": "E-mini S&P 500", "exchange": "CME", "pointValue": 50, "increment": 0.25, "currency": "USD" }`
+
+const testInstruments = `[
+	{ "name": "ESH25", "description": "E-mini S&P 500 Mar 2025", "exchange": "CME", "root": "ES", "pointValue": 50, "minMove": 0.25, "month": "H" },
+	{ "name": "ESM25", "description": "E-mini S&P 500 Jun 2025", "exchange": "CME", "root": "ES", "pointValue": 50, "minMove": 0.25, "month": "M" }
+]`
+
+const testBars = `timestamp,open,high,low,close,upVolume,downVolume,upTicks,downTicks,openInterest
+2025-01-02T14:30:00Z,5900,5902.25,5899,5901.5,10,5,3,2,0
+2025-01-02T14:31:00Z,5901.5,5903,5900.75,5902,8,7,4,3,0
+2025-01-02T14:32:00Z,5902,5902.5,5898,5898.25,4,12,2,6,0
+`
+
+//=============================================================================
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) *adaptertest.Setup {
+		return &adaptertest.Setup{
+			Adapter     : NewAdapter(),
+			ConfigParams: map[string]any{
+				ParamAccounts: "SIM1",
+				ParamDataDir : writeDataDir(t),
+			},
+			Fixture: adaptertest.Fixture{
+				Root      : "ES",
+				Instrument: "ESH25",
+				Bars      : adapter.NewPriceBarsRequest("ESH25", 20250102),
+				MinBars   : 3,
+				Accounts  : []string{ "SIM1" },
+				NewOrder  : &adapter.Order{
+					Account   : "SIM1",
+					Symbol    : "ESH25",
+					Side      : adapter.OrderSideBuy,
+					Quantity  : 1,
+					Type      : adapter.OrderTypeLimit,
+					LimitPrice: 5000,
+				},
+			},
+		}
+	})
+}
+
+//=============================================================================
+
+func TestBarCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	adapter.InitBarCache(&app.BarCache{ Path: cacheDir })
+	t.Cleanup(func() { adapter.InitBarCache(&app.BarCache{}) })
+
+	dataDir := writeDataDir(t)
+	ctx     := adaptertest.Connect(t, &adaptertest.Setup{
+		Adapter     : NewAdapter(),
+		ConfigParams: map[string]any{ ParamDataDir: dataDir },
+	})
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250102)
+	rq.From = 20250101
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 3 {
+		t.Fatalf("expected 3 bars from the data directory, got %v (error: %v)", pb, err)
+	}
+
+	//--- Local data depends on the connection's data directory, so it is never cached
+
+	err = os.Remove(filepath.Join(dataDir, "bars", "ESH25", "20250102.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || !pb.NoData {
+		t.Fatalf("expected no data once the file is removed, got %v (error: %v)", pb, err)
+	}
+
+	n,err := adapter.InvalidateBarCache("LOCAL", "", 0, 0)
+	if err != nil || n != 0 {
+		t.Fatalf("expected no cached days, got %d (error: %v)", n, err)
+	}
+}
+
+//=============================================================================
+
+func TestSessionPriceBars(t *testing.T) {
+	dataDir := writeDataDir(t)
+
+	//--- The CME session of January 2nd starts on January 1st at 23:00 UTC and
+	//--- ends at 22:00 UTC
+
+	extra := map[string]string{
+		"20250101.csv": "timestamp,open,high,low,close,upVolume,downVolume,upTicks,downTicks,openInterest\n2025-01-01T23:30:00Z,5890,5891,5889,5890,1,1,1,1,0\n",
+		"20250102.csv": testBars +"2025-01-02T22:30:00Z,5900,5901,5899,5900,1,1,1,1,0\n",
+	}
+
+	for name, data := range extra {
+		if err := os.WriteFile(filepath.Join(dataDir, "bars", "ESH25", name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := adaptertest.Connect(t, &adaptertest.Setup{
+		Adapter     : NewAdapter(),
+		ConfigParams: map[string]any{ ParamDataDir: dataDir },
+	})
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250102)
+	rq.Calendar = "CME"
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 4 || pb.Bars[0].Close != 5890 || pb.Holiday {
+		t.Fatalf("expected the 4 bars of the session, got %v (error: %v)", pb, err)
+	}
+
+	rq.From, rq.To = 20250101, 20250101
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || !pb.NoData || !pb.Holiday || pb.HolidayName != "New Year's Day" {
+		t.Fatalf("expected a holiday, got %v (error: %v)", pb, err)
+	}
+
+	rq.From, rq.To = 20250104, 20250105
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || !pb.NoData || pb.Holiday || pb.HolidayName != "" {
+		t.Fatalf("a weekend is not a holiday, got %v (error: %v)", pb, err)
+	}
+}
+
+//=============================================================================
+
+func TestAggregatedPriceBars(t *testing.T) {
+	ctx := adaptertest.Connect(t, &adaptertest.Setup{
+		Adapter     : NewAdapter(),
+		ConfigParams: map[string]any{ ParamDataDir: writeDataDir(t) },
+	})
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250102)
+	rq.Timeframe = "5m"
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 2 || pb.Unit != adapter.BarUnitMinute || pb.Interval != 5 {
+		t.Fatalf("expected 2 bars of 5 minutes, got %v (error: %v)", pb, err)
+	}
+
+	rq.Timeframe = adapter.TimeframeSession
+	rq.Calendar  = "CME"
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 1 || pb.Unit != adapter.BarUnitDaily {
+		t.Fatalf("expected 1 session bar, got %v (error: %v)", pb, err)
+	}
+
+	b := pb.Bars[0]
+	if b.Open != 5900 || b.High != 5903 || b.Low != 5898 || b.Close != 5898.25 || b.UpVolume != 22 || b.DownVolume != 24 {
+		t.Errorf("unexpected session bar: %+v", *b)
+	}
+}
+
+//=============================================================================
+
+func writeDataDir(t *testing.T) string {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join("roots",       "ES.json")             : testRoot,
+		filepath.Join("instruments", "ES.json")             : testInstruments,
+		filepath.Join("bars",        "ESH25", "20250102.csv"): testBars,
+	}
+
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0o755)
+		if err == nil {
+			err = os.WriteFile(path, []byte(data), 0o644)
+		}
+
+		if err != nil {
+			t.Fatalf("cannot write the data directory: %v", err)
+		}
+	}
+
+	return dir
+}
+
+//=============================================================================
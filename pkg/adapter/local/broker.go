@@ -0,0 +1,497 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package local
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+
+func newBroker(config *ConfigParams, store *fileStore) *broker {
+	b := &broker{
+		config    : config,
+		store     : store,
+		accounts  : map[string]*account{},
+		orders    : map[string]*adapter.Order{},
+		triggered : map[string]bool{},
+		lastPrices: map[string]float64{},
+	}
+
+	for _,code := range config.Accounts {
+		b.accounts[code] = &account{
+			code     : code,
+			cash     : config.StartingCash,
+			positions: map[string]*position{},
+		}
+	}
+
+	return b
+}
+
+//=============================================================================
+//===
+//=== Services
+//===
+//=============================================================================
+
+func (b *broker) getAccounts() []*adapter.Account {
+	b.Lock()
+	defer b.Unlock()
+
+	var list []*adapter.Account
+
+	for _,acc := range b.accounts {
+		unrealized := b.unrealizedProfitLoss(acc)
+
+		list = append(list, &adapter.Account{
+			Code                : acc.code,
+			Type                : adapter.AccountTypeFutures,
+			CurrencyCode        : b.config.Currency,
+			CashBalance         : acc.cash,
+			Equity              : acc.cash + unrealized,
+			RealizedProfitLoss  : acc.realized,
+			UnrealizedProfitLoss: unrealized,
+		})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Code < list[j].Code
+	})
+
+	return list
+}
+
+//=============================================================================
+
+func (b *broker) getOrders() []*adapter.Order {
+	b.Lock()
+	defer b.Unlock()
+
+	var list []*adapter.Order
+
+	for _,o := range b.orders {
+		oo := *o
+		list = append(list, &oo)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(*list[j].CreatedAt)
+	})
+
+	return list
+}
+
+//=============================================================================
+
+func (b *broker) getPositions() []*adapter.Position {
+	b.Lock()
+	defer b.Unlock()
+
+	var list []*adapter.Position
+
+	for _,acc := range b.accounts {
+		for _,p := range acc.positions {
+			openTime := p.openTime
+			last     := b.lastPrice(p)
+
+			list = append(list, &adapter.Position{
+				Account             : acc.code,
+				Symbol              : p.symbol,
+				Root                : b.root(p.symbol),
+				Quantity            : p.quantity,
+				AveragePrice        : p.avgPrice,
+				MarketValue         : last * p.quantity * b.pointValue(p.symbol),
+				UnrealizedProfitLoss: (last - p.avgPrice) * p.quantity * b.pointValue(p.symbol),
+				OpenTime            : &openTime,
+			})
+		}
+	}
+
+	return list
+}
+
+//=============================================================================
+
+func (b *broker) placeOrder(o *adapter.Order) (*adapter.Order,error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _,ok := b.accounts[o.Account]; !ok {
+		return nil, req.NewNotFoundError("Account not found: %v", o.Account)
+	}
+
+	b.nextId++
+	now := time.Now()
+
+	no := *o
+	no.Id             = strconv.Itoa(b.nextId)
+	no.Status         = adapter.OrderStatusWorking
+	no.FilledQuantity = 0
+	no.AveragePrice   = 0
+	no.Fills          = nil
+	no.CreatedAt      = &now
+	no.ClosedAt       = nil
+
+	b.orders[no.Id] = &no
+
+	//--- Market orders are filled immediately if we know the current price of the symbol
+
+	if last,ok := b.lastPrices[no.Symbol]; ok && no.Type == adapter.OrderTypeMarket {
+		b.fill(&no, b.slipped(&no, last), now)
+	}
+
+	oo := no
+	return &oo, nil
+}
+
+//=============================================================================
+
+func (b *broker) modifyOrder(id string, oc *adapter.OrderChange) (*adapter.Order,error) {
+	b.Lock()
+	defer b.Unlock()
+
+	o,ok := b.orders[id]
+	if !ok {
+		return nil, req.NewNotFoundError("Order not found: %v", id)
+	}
+
+	if !o.IsOpen() {
+		return nil, req.NewBadRequestError("Order is not open: %v", id)
+	}
+
+	if oc.Quantity != 0 {
+		if oc.Quantity < o.FilledQuantity {
+			return nil, req.NewBadRequestError("Quantity cannot be less than the filled quantity: %v", id)
+		}
+		o.Quantity = oc.Quantity
+	}
+
+	if oc.LimitPrice != 0 {
+		o.LimitPrice = oc.LimitPrice
+	}
+
+	if oc.StopPrice != 0 {
+		o.StopPrice = oc.StopPrice
+	}
+
+	oo := *o
+	return &oo, nil
+}
+
+//=============================================================================
+
+func (b *broker) cancelOrder(id string) error {
+	b.Lock()
+	defer b.Unlock()
+
+	o,ok := b.orders[id]
+	if !ok {
+		return req.NewNotFoundError("Order not found: %v", id)
+	}
+
+	if !o.IsOpen() {
+		return req.NewBadRequestError("Order is not open: %v", id)
+	}
+
+	b.close(o, adapter.OrderStatusCancelled, time.Now())
+	return nil
+}
+
+//=============================================================================
+//===
+//=== Matching
+//===
+//=============================================================================
+//--- The replayed bars are the clock of the matching only: orders placed or
+//--- cancelled afterwards use the wall clock
+
+func (b *broker) processBars(symbol string, bars []*adapter.PriceBar) int {
+	b.Lock()
+	defer b.Unlock()
+
+	fills   := 0
+	simTime := time.Now()
+
+	for _,bar := range bars {
+		simTime = bar.TimeStamp
+
+		for _,o := range b.openOrders(symbol) {
+			price,ok := b.match(o, bar)
+			if ok {
+				b.fill(o, price, bar.TimeStamp)
+				fills++
+			} else if o.TimeInForce == adapter.TimeInForceIOC || o.TimeInForce == adapter.TimeInForceFOK {
+				b.close(o, adapter.OrderStatusCancelled, bar.TimeStamp)
+			}
+		}
+
+		b.lastPrices[symbol] = bar.Close
+	}
+
+	//--- A replay covers a whole trading day, so day orders cannot survive it
+
+	for _,o := range b.openOrders(symbol) {
+		if o.TimeInForce == adapter.TimeInForceDay {
+			b.close(o, adapter.OrderStatusExpired, simTime)
+		}
+	}
+
+	return fills
+}
+
+//=============================================================================
+
+func (b *broker) match(o *adapter.Order, bar *adapter.PriceBar) (float64,bool) {
+	isBuy := o.Side == adapter.OrderSideBuy
+
+	switch o.Type {
+		case adapter.OrderTypeMarket:
+			return b.slipped(o, bar.Open), true
+
+		case adapter.OrderTypeLimit:
+			return matchLimit(isBuy, o.LimitPrice, bar.Open, bar)
+
+		case adapter.OrderTypeStop:
+			if price,ok := matchStop(isBuy, o.StopPrice, bar); ok {
+				return b.slipped(o, price), true
+			}
+
+		case adapter.OrderTypeStopLimit:
+			if !b.triggered[o.Id] {
+				price,ok := matchStop(isBuy, o.StopPrice, bar)
+				if !ok {
+					return 0, false
+				}
+				b.triggered[o.Id] = true
+				return matchLimit(isBuy, o.LimitPrice, price, bar)
+			}
+
+			return matchLimit(isBuy, o.LimitPrice, bar.Open, bar)
+	}
+
+	return 0, false
+}
+
+//=============================================================================
+
+func (b *broker) fill(o *adapter.Order, price float64, ts time.Time) {
+	acc      := b.accounts[o.Account]
+	quantity := o.Quantity - o.FilledQuantity
+	comm     := quantity * b.config.Commission
+
+	o.AveragePrice   = (o.AveragePrice*o.FilledQuantity + price*quantity) / o.Quantity
+	o.FilledQuantity = o.Quantity
+	o.Fills = append(o.Fills, &adapter.Fill{
+		Id        : o.Id +"-"+ strconv.Itoa(len(o.Fills)+1),
+		Quantity  : quantity,
+		Price     : price,
+		Commission: comm,
+		TimeStamp : ts,
+	})
+
+	b.close(o, adapter.OrderStatusFilled, ts)
+
+	//--- Update position and P&L
+
+	if o.Side == adapter.OrderSideSell {
+		quantity = -quantity
+	}
+
+	realized := b.updatePosition(acc, o.Symbol, quantity, price, ts)
+	acc.realized += realized
+	acc.cash     += realized - comm
+}
+
+//=============================================================================
+
+func (b *broker) updatePosition(acc *account, symbol string, quantity, price float64, ts time.Time) float64 {
+	p,ok := acc.positions[symbol]
+	if !ok {
+		acc.positions[symbol] = &position{
+			symbol  : symbol,
+			quantity: quantity,
+			avgPrice: price,
+			openTime: ts,
+		}
+		return 0
+	}
+
+	//--- Same direction: the position is increased
+
+	if p.quantity * quantity > 0 {
+		p.avgPrice  = (p.avgPrice*p.quantity + price*quantity) / (p.quantity + quantity)
+		p.quantity += quantity
+		return 0
+	}
+
+	//--- Opposite direction: the position is reduced, closed or reversed
+
+	closed   := math.Min(math.Abs(quantity), math.Abs(p.quantity))
+	realized := (price - p.avgPrice) * closed * b.pointValue(symbol)
+	if p.quantity < 0 {
+		realized = -realized
+	}
+
+	p.quantity += quantity
+
+	switch {
+		case p.quantity == 0:
+			delete(acc.positions, symbol)
+
+		case p.quantity * quantity > 0:
+			p.avgPrice = price
+			p.openTime = ts
+	}
+
+	return realized
+}
+
+//=============================================================================
+
+func (b *broker) close(o *adapter.Order, status adapter.OrderStatus, ts time.Time) {
+	o.Status   = status
+	o.ClosedAt = &ts
+	delete(b.triggered, o.Id)
+}
+
+//=============================================================================
+
+func (b *broker) openOrders(symbol string) []*adapter.Order {
+	var list []*adapter.Order
+
+	for _,o := range b.orders {
+		if o.Symbol == symbol && o.IsOpen() {
+			list = append(list, o)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(*list[j].CreatedAt)
+	})
+
+	return list
+}
+
+//=============================================================================
+
+func (b *broker) unrealizedProfitLoss(acc *account) float64 {
+	total := 0.0
+
+	for _,p := range acc.positions {
+		total += (b.lastPrice(p) - p.avgPrice) * p.quantity * b.pointValue(p.symbol)
+	}
+
+	return total
+}
+
+//=============================================================================
+
+func (b *broker) lastPrice(p *position) float64 {
+	if last,ok := b.lastPrices[p.symbol]; ok {
+		return last
+	}
+
+	return p.avgPrice
+}
+
+//=============================================================================
+
+func (b *broker) pointValue(symbol string) float64 {
+	if b.store != nil {
+		if i := b.store.findInstrument(symbol); i != nil && i.PointValue != 0 {
+			return float64(i.PointValue)
+		}
+	}
+
+	return b.config.PointValue
+}
+
+//=============================================================================
+
+func (b *broker) root(symbol string) string {
+	if b.store != nil {
+		if i := b.store.findInstrument(symbol); i != nil {
+			return i.Root
+		}
+	}
+
+	return ""
+}
+
+//=============================================================================
+
+func (b *broker) slipped(o *adapter.Order, price float64) float64 {
+	if o.Side == adapter.OrderSideBuy {
+		return price + b.config.Slippage
+	}
+
+	return price - b.config.Slippage
+}
+
+
+//=============================================================================
+//===
+//=== Functions
+//===
+//=============================================================================
+
+func matchLimit(isBuy bool, limit float64, open float64, bar *adapter.PriceBar) (float64,bool) {
+	if isBuy {
+		if bar.Low <= limit {
+			return math.Min(limit, open), true
+		}
+	} else {
+		if bar.High >= limit {
+			return math.Max(limit, open), true
+		}
+	}
+
+	return 0, false
+}
+
+//=============================================================================
+
+func matchStop(isBuy bool, stop float64, bar *adapter.PriceBar) (float64,bool) {
+	if isBuy {
+		if bar.High >= stop {
+			return math.Max(stop, bar.Open), true
+		}
+	} else {
+		if bar.Low <= stop {
+			return math.Min(stop, bar.Open), true
+		}
+	}
+
+	return 0, false
+}
+
+//=============================================================================
@@ -0,0 +1,266 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package local
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+
+const (
+	testAccount    = "SIM1"
+	testSymbol     = "ESH25"
+	testCash       = 100000.0
+	testSlippage   = 0.25
+	testCommission = 2.0
+)
+
+var testStart = time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC)
+
+//=============================================================================
+
+func TestProcessBars(t *testing.T) {
+	//--- 100/102/99/101, then 101/105/100/104
+
+	bars := []*adapter.PriceBar{
+		newTestBar(0, 100, 102,  99, 101),
+		newTestBar(1, 101, 105, 100, 104),
+	}
+
+	buy,sell := adapter.OrderSideBuy, adapter.OrderSideSell
+	gtc,day  := adapter.TimeInForceGTC, adapter.TimeInForceDay
+	ioc,fok  := adapter.TimeInForceIOC, adapter.TimeInForceFOK
+
+	for _, tc := range []struct {
+		name   string
+		order  adapter.Order
+		status adapter.OrderStatus
+		price  float64
+	}{
+		{ "market buy",          newTestOrder(buy,  adapter.OrderTypeMarket,    0,     0,     gtc), adapter.OrderStatusFilled,    100.25 },
+		{ "market sell",         newTestOrder(sell, adapter.OrderTypeMarket,    0,     0,     gtc), adapter.OrderStatusFilled,    99.75  },
+		{ "limit buy",           newTestOrder(buy,  adapter.OrderTypeLimit,     99.5,  0,     gtc), adapter.OrderStatusFilled,    99.5   },
+		{ "limit buy at open",   newTestOrder(buy,  adapter.OrderTypeLimit,     101,   0,     gtc), adapter.OrderStatusFilled,    100    },
+		{ "limit buy not hit",   newTestOrder(buy,  adapter.OrderTypeLimit,     98,    0,     gtc), adapter.OrderStatusWorking,   0      },
+		{ "limit sell",          newTestOrder(sell, adapter.OrderTypeLimit,     103,   0,     gtc), adapter.OrderStatusFilled,    103    },
+		{ "stop buy",            newTestOrder(buy,  adapter.OrderTypeStop,      0,     104,   gtc), adapter.OrderStatusFilled,    104.25 },
+		{ "stop sell",           newTestOrder(sell, adapter.OrderTypeStop,      0,     99.5,  gtc), adapter.OrderStatusFilled,    99.25  },
+		{ "stop buy not hit",    newTestOrder(buy,  adapter.OrderTypeStop,      0,     106,   gtc), adapter.OrderStatusWorking,   0      },
+		{ "stop limit buy",      newTestOrder(buy,  adapter.OrderTypeStopLimit, 104.5, 104,   gtc), adapter.OrderStatusFilled,    104    },
+		{ "stop limit at limit", newTestOrder(buy,  adapter.OrderTypeStopLimit, 100.5, 101.5, gtc), adapter.OrderStatusFilled,    100.5  },
+		{ "stop limit not hit",  newTestOrder(buy,  adapter.OrderTypeStopLimit, 107,   106,   gtc), adapter.OrderStatusWorking,   0      },
+		{ "ioc filled",          newTestOrder(buy,  adapter.OrderTypeLimit,     99.5,  0,     ioc), adapter.OrderStatusFilled,    99.5   },
+		{ "ioc not filled",      newTestOrder(buy,  adapter.OrderTypeLimit,     98,    0,     ioc), adapter.OrderStatusCancelled, 0      },
+		{ "fok not filled",      newTestOrder(buy,  adapter.OrderTypeLimit,     98,    0,     fok), adapter.OrderStatusCancelled, 0      },
+		{ "day order expired",   newTestOrder(buy,  adapter.OrderTypeLimit,     98,    0,     day), adapter.OrderStatusExpired,   0      },
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newTestBroker()
+
+			po,err := b.placeOrder(&tc.order)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b.processBars(testSymbol, bars)
+
+			o := b.orders[po.Id]
+			if o.Status != tc.status || o.AveragePrice != tc.price {
+				t.Fatalf("expected %v at %v, got %v at %v", tc.status, tc.price, o.Status, o.AveragePrice)
+			}
+
+			if o.IsOpen() != (o.ClosedAt == nil) {
+				t.Errorf("closed orders must have a close time: %+v", o)
+			}
+
+			if tc.status == adapter.OrderStatusExpired && !o.ClosedAt.Equal(bars[len(bars)-1].TimeStamp) {
+				t.Errorf("a day order must expire at the last bar, got %v", o.ClosedAt)
+			}
+		})
+	}
+}
+
+//=============================================================================
+
+func TestFill(t *testing.T) {
+	b := newTestBroker()
+
+	//--- Buy 2 at 100 + slippage, then sell 2 at 101 with a limit
+
+	buy := newTestOrder(adapter.OrderSideBuy, adapter.OrderTypeMarket, 0, 0, adapter.TimeInForceGTC)
+	_,_  = b.placeOrder(&buy)
+	b.processBars(testSymbol, []*adapter.PriceBar{ newTestBar(0, 100, 100, 100, 100) })
+
+	sell := newTestOrder(adapter.OrderSideSell, adapter.OrderTypeLimit, 101, 0, adapter.TimeInForceGTC)
+	po,_ := b.placeOrder(&sell)
+	b.processBars(testSymbol, []*adapter.PriceBar{ newTestBar(1, 100.5, 102, 100.5, 101.5) })
+
+	o := b.orders[po.Id]
+	if len(o.Fills) != 1 || o.Fills[0].Quantity != 2 || o.Fills[0].Price != 101 || o.Fills[0].Commission != 2*testCommission {
+		t.Fatalf("unexpected fills: %+v", o.Fills)
+	}
+
+	realized   := (101 - 100.25) * 2 * 50
+	commission := 2 * 2 * testCommission
+
+	acc := b.getAccounts()[0]
+	if acc.RealizedProfitLoss != realized || acc.CashBalance != testCash + realized - commission || acc.UnrealizedProfitLoss != 0 {
+		t.Errorf("unexpected account: %+v", acc)
+	}
+
+	if len(b.getPositions()) != 0 {
+		t.Errorf("the position must be closed: %+v", b.getPositions())
+	}
+}
+
+//=============================================================================
+
+func TestUpdatePosition(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		quantity float64   // initial position, 0 for none
+		avgPrice float64
+		trade    float64   // signed quantity
+		price    float64
+		realized float64
+		finalQty float64
+		finalAvg float64
+	}{
+		{ "open",           0,   0,  2, 100,     0,  2, 100 },
+		{ "increase",       2, 100,  2, 110,     0,  4, 105 },
+		{ "reduce long",    2, 100, -1, 110,   500,  1, 100 },
+		{ "close long",     2, 100, -2,  90, -1000,  0,   0 },
+		{ "reverse long",   2, 100, -3, 110,  1000, -1, 110 },
+		{ "reduce short",  -2, 100,  1,  90,   500, -1, 100 },
+		{ "reverse short", -1, 100,  2, 105,  -250,  1, 105 },
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			b   := newTestBroker()
+			acc := b.accounts[testAccount]
+
+			if tc.quantity != 0 {
+				acc.positions[testSymbol] = &position{ symbol: testSymbol, quantity: tc.quantity, avgPrice: tc.avgPrice, openTime: testStart }
+			}
+
+			realized := b.updatePosition(acc, testSymbol, tc.trade, tc.price, testStart.Add(time.Hour))
+			if realized != tc.realized {
+				t.Errorf("expected realized %v, got %v", tc.realized, realized)
+			}
+
+			p,ok := acc.positions[testSymbol]
+			if tc.finalQty == 0 {
+				if ok {
+					t.Errorf("the position must be closed: %+v", p)
+				}
+				return
+			}
+
+			if !ok || p.quantity != tc.finalQty || p.avgPrice != tc.finalAvg {
+				t.Fatalf("expected %v at %v, got %+v", tc.finalQty, tc.finalAvg, p)
+			}
+
+			//--- New and reversed positions are opened by the trade
+
+			opened := tc.quantity * tc.finalQty <= 0
+			if opened != p.openTime.Equal(testStart.Add(time.Hour)) {
+				t.Errorf("unexpected open time %v", p.openTime)
+			}
+		})
+	}
+}
+
+//=============================================================================
+
+func TestOrdersAfterReplayUseTheWallClock(t *testing.T) {
+	b := newTestBroker()
+	b.processBars(testSymbol, []*adapter.PriceBar{ newTestBar(0, 100, 102, 99, 101) })
+
+	o := newTestOrder(adapter.OrderSideBuy, adapter.OrderTypeLimit, 90, 0, adapter.TimeInForceGTC)
+	before := time.Now()
+
+	po,err := b.placeOrder(&o)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = b.cancelOrder(po.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	co := b.orders[po.Id]
+	if co.CreatedAt.Before(before) || co.ClosedAt.Before(before) {
+		t.Errorf("orders must not use the replay time: created %v, closed %v", co.CreatedAt, co.ClosedAt)
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func newTestBroker() *broker {
+	return newBroker(&ConfigParams{
+		Accounts    : []string{ testAccount },
+		StartingCash: testCash,
+		PointValue  : 50,
+		Slippage    : testSlippage,
+		Commission  : testCommission,
+	}, nil)
+}
+
+//=============================================================================
+
+func newTestOrder(side adapter.OrderSide, orderType adapter.OrderType, limit, stop float64, tif adapter.TimeInForce) adapter.Order {
+	return adapter.Order{
+		Account    : testAccount,
+		Symbol     : testSymbol,
+		Side       : side,
+		Quantity   : 2,
+		Type       : orderType,
+		LimitPrice : limit,
+		StopPrice  : stop,
+		TimeInForce: tif,
+	}
+}
+
+//=============================================================================
+
+func newTestBar(minute int, open, high, low, close float64) *adapter.PriceBar {
+	return &adapter.PriceBar{
+		TimeStamp: testStart.Add(time.Duration(minute) * time.Minute),
+		Open     : open,
+		High     : high,
+		Low      : low,
+		Close    : close,
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,297 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package local
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+//===
+//=== File store: the data directory has the following layout
+//===
+//===    roots/<root>.json              : root symbol (see adapter.RootSymbol)
+//===    instruments/<root>.json        : list of instruments (see adapter.Instrument)
+//===    bars/<symbol>/<yyyymmdd>.csv   : 1-minute bars of an instrument for one day
+//===
+//=== Each line of a bars file has the format:
+//===    timestamp (RFC3339),open,high,low,close,upVolume,downVolume,upTicks,downTicks,openInterest
+//===
+//=============================================================================
+
+type fileStore struct {
+	sync.Mutex
+	dataDir     string
+	instruments map[string]*adapter.Instrument
+}
+
+//=============================================================================
+
+func newFileStore(dataDir string) *fileStore {
+	return &fileStore{
+		dataDir: dataDir,
+	}
+}
+
+//=============================================================================
+
+func (f *fileStore) getRootSymbols(filter string) ([]*adapter.RootSymbol,error) {
+	files,err := filepath.Glob(filepath.Join(f.dataDir, "roots", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	filter = strings.ToUpper(filter)
+
+	var roots []*adapter.RootSymbol
+
+	for _,file := range files {
+		var rs adapter.RootSymbol
+		err = readJson(file, &rs)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(strings.ToUpper(rs.Code), filter) || strings.Contains(strings.ToUpper(rs.Instrument), filter) {
+			roots = append(roots, &rs)
+		}
+	}
+
+	return roots, nil
+}
+
+//=============================================================================
+
+func (f *fileStore) getRootSymbol(root string) (*adapter.RootSymbol,error) {
+	var rs adapter.RootSymbol
+	err := readJson(filepath.Join(f.dataDir, "roots", root +".json"), &rs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, req.NewNotFoundError("Root symbol not found: %v", root)
+		}
+		return nil, err
+	}
+
+	return &rs, nil
+}
+
+//=============================================================================
+
+func (f *fileStore) getInstruments(root string) ([]*adapter.Instrument,error) {
+	var list []*adapter.Instrument
+	err := readJson(filepath.Join(f.dataDir, "instruments", root +".json"), &list)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, req.NewNotFoundError("Instruments not found for root: %v", root)
+		}
+		return nil, err
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+
+func (f *fileStore) findInstrument(symbol string) *adapter.Instrument {
+	f.Lock()
+	defer f.Unlock()
+
+	if f.instruments == nil {
+		f.instruments = map[string]*adapter.Instrument{}
+
+		files,_ := filepath.Glob(filepath.Join(f.dataDir, "instruments", "*.json"))
+		for _,file := range files {
+			var list []*adapter.Instrument
+			err := readJson(file, &list)
+			if err != nil {
+				slog.Warn("Local: Skipping bad instruments file", "file", file, "error", err.Error())
+				continue
+			}
+
+			for _,i := range list {
+				f.instruments[i.Name] = i
+			}
+		}
+	}
+
+	return f.instruments[symbol]
+}
+
+//=============================================================================
+
+func (f *fileStore) GetPriceBars(rq *adapter.PriceBarsRequest) (*adapter.PriceBars,error) {
+	if rq.Unit != adapter.BarUnitMinute || rq.Interval != 1 {
+		return nil, req.NewBadRequestError("Only 1-minute bars are available in the data directory")
+	}
+
+	priceBars := adapter.NewPriceBars(rq)
+
+	for date := rq.From; date <= rq.To; date = adapter.AddDays(date, 1) {
+		bars,err := f.readBarsFile(rq.Symbol, date)
+		if err != nil {
+			return nil, err
+		}
+
+		priceBars.Bars = append(priceBars.Bars, bars...)
+	}
+
+	priceBars.NoData = len(priceBars.Bars) == 0
+
+	return priceBars, nil
+}
+
+//=============================================================================
+
+func (f *fileStore) readBarsFile(symbol string, date datatype.IntDate) ([]*adapter.PriceBar,error) {
+	file,err := os.Open(f.barsFile(symbol, date))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	defer file.Close()
+
+	bars,err := readBars(file)
+	if err != nil {
+		return nil, errors.New("Bad bars file for "+ symbol +" at "+ date.String() +": "+ err.Error())
+	}
+
+	return bars, nil
+}
+
+//=============================================================================
+
+func (f *fileStore) barsFile(symbol string, date datatype.IntDate) string {
+	return filepath.Join(f.dataDir, "bars", symbol, strconv.Itoa(int(date)) +".csv")
+}
+
+//=============================================================================
+//===
+//=== Functions
+//===
+//=============================================================================
+
+func readJson(file string, output any) error {
+	data,err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(data, output)
+	if err != nil {
+		return errors.New("Bad JSON file "+ file +": "+ err.Error())
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func readBars(r io.Reader) ([]*adapter.PriceBar,error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 10
+	reader.Comment         = '#'
+
+	var bars []*adapter.PriceBar
+
+	for {
+		rec,err := reader.Read()
+		if err == io.EOF {
+			return bars, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		//--- Skip an optional header line
+
+		if len(bars) == 0 && strings.EqualFold(rec[0], "timestamp") {
+			continue
+		}
+
+		bar,err := parseBar(rec)
+		if err != nil {
+			return nil, err
+		}
+
+		bars = append(bars, bar)
+	}
+}
+
+//=============================================================================
+
+func parseBar(rec []string) (*adapter.PriceBar,error) {
+	ts,err := time.Parse(time.RFC3339, rec[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var prices [4]float64
+	for i := range prices {
+		prices[i],err = strconv.ParseFloat(rec[i+1], 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var values [5]int
+	for i := range values {
+		values[i],err = strconv.Atoi(rec[i+5])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &adapter.PriceBar{
+		TimeStamp   : ts,
+		Open        : prices[0],
+		High        : prices[1],
+		Low         : prices[2],
+		Close       : prices[3],
+		UpVolume    : values[0],
+		DownVolume  : values[1],
+		UpTicks     : values[2],
+		DownTicks   : values[3],
+		OpenInterest: values[4],
+	}, nil
+}
+
+//=============================================================================
@@ -0,0 +1,258 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package local
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+//--- Config parameters
+
+const (
+	ParamAccounts     = "accounts"
+	ParamCurrency     = "currency"
+	ParamStartingCash = "startingCash"
+	ParamPointValue   = "pointValue"
+	ParamSlippage     = "slippage"
+	ParamCommission   = "commission"
+	ParamDataDir      = "dataDir"
+)
+
+//=============================================================================
+
+var paramAccounts = &adapter.ParamDef{
+	Name     : ParamAccounts,
+	Type     : adapter.ParamTypeString,
+	DefValue : "SIM1",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 256,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramCurrency = &adapter.ParamDef{
+	Name     : ParamCurrency,
+	Type     : adapter.ParamTypeString,
+	DefValue : "USD",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 3,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramStartingCash = &adapter.ParamDef{
+	Name     : ParamStartingCash,
+	Type     : adapter.ParamTypeFloat,
+	DefValue : "100000",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramPointValue = &adapter.ParamDef{
+	Name     : ParamPointValue,
+	Type     : adapter.ParamTypeFloat,
+	DefValue : "1",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramSlippage = &adapter.ParamDef{
+	Name     : ParamSlippage,
+	Type     : adapter.ParamTypeFloat,
+	DefValue : "0",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramCommission = &adapter.ParamDef{
+	Name     : ParamCommission,
+	Type     : adapter.ParamTypeFloat,
+	DefValue : "0",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramFeedConnection = &adapter.ParamDef{
+	Name     : adapter.ParamFeedConnection,
+	Type     : adapter.ParamTypeString,
+	DefValue : "",
+	Nullable : true,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramDataDir = &adapter.ParamDef{
+	Name     : ParamDataDir,
+	Type     : adapter.ParamTypeString,
+	DefValue : "",
+	Nullable : true,
+	MinValue : 0,
+	MaxValue : 256,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var configParams = []*adapter.ParamDef {
+	paramAccounts,
+	paramCurrency,
+	paramStartingCash,
+	paramPointValue,
+	paramSlippage,
+	paramCommission,
+	paramFeedConnection,
+	paramDataDir,
+}
+
+//-----------------------------------------------------------------------------
+
+var connectParams []*adapter.ParamDef
+
+//-----------------------------------------------------------------------------
+
+var info = adapter.Info{
+	Code                : "LOCAL",
+	Name                : "Local system",
+	ConfigParams        : configParams,
+	ConnectParams       : connectParams,
+	SupportsData        : true,
+	SupportsBroker      : true,
+	SupportsMultipleData: true,
+	SupportsInventory   : false,
+}
+
+//=============================================================================
+
+type ConfigParams struct {
+	Accounts       []string
+	Currency       string
+	StartingCash   float64
+	PointValue     float64
+	Slippage       float64
+	Commission     float64
+	FeedConnection string
+	DataDir        string
+}
+
+//=============================================================================
+
+type local struct {
+	configParams *ConfigParams
+	broker       *broker
+	store        *fileStore
+	feed         adapter.PriceFeed
+}
+
+//=============================================================================
+//===
+//=== Simulated broker structures
+//===
+//=============================================================================
+
+type broker struct {
+	sync.Mutex
+	config     *ConfigParams
+	store      *fileStore
+	accounts   map[string]*account
+	orders     map[string]*adapter.Order
+	triggered  map[string]bool
+	lastPrices map[string]float64
+	nextId     int
+}
+
+//=============================================================================
+
+type account struct {
+	code       string
+	cash       float64
+	realized   float64
+	positions  map[string]*position
+}
+
+//=============================================================================
+
+type position struct {
+	symbol   string
+	quantity float64
+	avgPrice float64
+	openTime time.Time
+}
+
+//=============================================================================
+//===
+//=== Functions
+//===
+//=============================================================================
+
+func retrieveConfigParams(values map[string]any) *ConfigParams {
+	var accounts []string
+	for _,code := range strings.Split(paramAccounts.GetString(values), ",") {
+		code = strings.TrimSpace(code)
+		if code != "" {
+			accounts = append(accounts, code)
+		}
+	}
+
+	return &ConfigParams{
+		Accounts      : accounts,
+		Currency      : paramCurrency      .GetString(values),
+		StartingCash  : paramStartingCash  .GetFloat (values),
+		PointValue    : paramPointValue    .GetFloat (values),
+		Slippage      : paramSlippage      .GetFloat (values),
+		Commission    : paramCommission    .GetFloat (values),
+		FeedConnection: paramFeedConnection.GetString(values),
+		DataDir       : paramDataDir       .GetString(values),
+	}
+}
+
+//=============================================================================
@@ -27,12 +27,14 @@ package adapter
 import (
 	"errors"
 	"log/slog"
+	"math"
 	"net/http"
 	"reflect"
 	"strconv"
 	"time"
 
 	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
 )
 
 //=============================================================================
@@ -42,6 +44,8 @@ const (
 	ParamUsername = "username"
 	ParamPassword = "password"
 	ParamTwoFACode= "twoFACode"
+
+	ParamFeedConnection = "feedConnection"
 )
 
 //=============================================================================
@@ -53,13 +57,14 @@ const (
 	ParamTypePassword ParamType = "password"
 	ParamTypeBool     ParamType = "bool"
 	ParamTypeInt      ParamType = "int"
+	ParamTypeFloat    ParamType = "float"
 )
 
 //=============================================================================
 
 type ParamDef struct {
 	Name      string      `json:"name"`
-	Type      ParamType   `json:"type"`      // string|int|bool|group|password
+	Type      ParamType   `json:"type"`      // string|int|float|bool|group|password
 	DefValue  string      `json:"defValue"`
 	Nullable  bool        `json:"nullable"`
 	MinValue  int         `json:"minValue"`
@@ -92,6 +97,13 @@ func (p *ParamDef) Validate(values map[string]any) error {
 						return errors.New("invalid range for this integer parameter : "+ p.Name)
 					}
 					break;
+
+				case ParamTypeFloat:
+					_, err := strconv.ParseFloat(p.DefValue, 64)
+					if err != nil {
+						return errors.New("invalid value for a float parameter : "+ p.Name)
+					}
+					break;
 			}
 			return nil
 		}
@@ -122,6 +134,17 @@ func (p *ParamDef) Validate(values map[string]any) error {
 					return nil
 				}
 
+			//--- Numbers decoded from JSON are always float64
+
+			case "float64":
+				v := value.(float64)
+				if p.Type == ParamTypeFloat {
+					return nil
+				}
+				if p.Type == ParamTypeInt && v == math.Trunc(v) {
+					return nil
+				}
+
 			default:
 				return errors.New("unknown parameter type : "+ p.Name)
 		}
@@ -132,6 +155,54 @@ func (p *ParamDef) Validate(values map[string]any) error {
 	return nil
 }
 
+//-----------------------------------------------------------------------------
+
+func (p *ParamDef) GetString(values map[string]any) string {
+	if v,ok := values[p.Name].(string); ok {
+		return v
+	}
+
+	return p.DefValue
+}
+
+//-----------------------------------------------------------------------------
+
+func (p *ParamDef) GetBool(values map[string]any) bool {
+	if v,ok := values[p.Name].(bool); ok {
+		return v
+	}
+
+	return p.DefValue == "true"
+}
+
+//-----------------------------------------------------------------------------
+
+func (p *ParamDef) GetInt(values map[string]any) int {
+	switch v := values[p.Name].(type) {
+		case int:
+			return v
+		case float64:
+			return int(v)
+	}
+
+	v,_ := strconv.Atoi(p.DefValue)
+	return v
+}
+
+//-----------------------------------------------------------------------------
+
+func (p *ParamDef) GetFloat(values map[string]any) float64 {
+	switch v := values[p.Name].(type) {
+		case int:
+			return float64(v)
+		case float64:
+			return v
+	}
+
+	v,_ := strconv.ParseFloat(p.DefValue, 64)
+	return v
+}
+
 //=============================================================================
 
 type Info struct {
@@ -141,8 +212,37 @@ type Info struct {
 	SupportsBroker       bool         `json:"supportsBroker"`
 	SupportsMultipleData bool         `json:"supportsMultipleData"`
 	SupportsInventory    bool         `json:"supportsInventory"`
+	SharedCatalog        bool         `json:"sharedCatalog"` // roots and instruments do not depend on the connection
+	SharedData           bool         `json:"sharedData"`    // price bars do not depend on the connection
 	ConfigParams         []*ParamDef  `json:"configParams"`
 	ConnectParams        []*ParamDef  `json:"connectParams"`
+	Reconnect            ReconnectPolicy `json:"reconnect"`
+}
+
+//=============================================================================
+//--- How a connection is re-established when its session is lost. The delay
+//--- doubles at each failed attempt, up to MaxDelaySec. Zero attempts disable it
+
+type ReconnectPolicy struct {
+	MaxAttempts     int `json:"maxAttempts"`
+	InitialDelaySec int `json:"initialDelaySec"`
+	MaxDelaySec     int `json:"maxDelaySec"`
+}
+
+//-----------------------------------------------------------------------------
+
+func (p *ReconnectPolicy) GetDelay(attempt int) time.Duration {
+	delay := p.InitialDelaySec
+
+	for i:=1; i<attempt && delay < p.MaxDelaySec; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelaySec {
+		delay = p.MaxDelaySec
+	}
+
+	return time.Duration(delay) * time.Second
 }
 
 //=============================================================================
@@ -174,11 +274,65 @@ type Adapter interface {
 	GetRootSymbols(filter string) ([]*RootSymbol,error)
 	GetRootSymbol(root string) (*RootSymbol,error)
 	GetInstruments(root string) ([]*Instrument,error)
-	GetPriceBars(symbol string, date datatype.IntDate) (*PriceBars,error)
+	GetPriceBars(rq *PriceBarsRequest) (*PriceBars,error)
 	GetAccounts() ([]*Account,error)
-	GetOrders() (any,error)
-	GetPositions() (any,error)
+	GetOrders() ([]*Order,error)
+	PlaceOrder(o *Order) (*Order,error)
+	ModifyOrder(id string, oc *OrderChange) (*Order,error)
+	CancelOrder(id string) error
+	GetPositions() ([]*Position,error)
 	TestService(path,param string) (string,error)
+
+	//--- Streaming
+
+	Subscribe(symbol string, st StreamType, handler StreamHandler) error
+	Unsubscribe(symbol string, st StreamType) error
+}
+
+//=============================================================================
+//--- Returned by RefreshToken when the session is definitively gone and
+//--- retrying is pointless
+
+var ErrSessionExpired = errors.New("session expired")
+
+//=============================================================================
+
+func NewNotSupportedError(a Adapter, service string) error {
+	return req.NewBadRequestError("Service not supported by adapter %v: %v", a.GetInfo().Code, service)
+}
+
+//=============================================================================
+//=== Optional interfaces for adapters that simulate a broker
+
+type PriceFeed interface {
+	GetPriceBars(rq *PriceBarsRequest) (*PriceBars,error)
+}
+
+//-----------------------------------------------------------------------------
+
+type FeedConsumer interface {
+	SetPriceFeed(feed PriceFeed)
+}
+
+//-----------------------------------------------------------------------------
+
+type Simulator interface {
+	Replay(symbol string, date datatype.IntDate) (*ReplayResult,error)
+}
+
+//=============================================================================
+//=== Optional interface for adapters that can record/replay their traffic
+
+type CassetteOwner interface {
+	SetCassetteOwner(username, connectionCode string)
+}
+
+//=============================================================================
+//=== Optional interface for adapters whose session can survive a restart
+
+type SessionKeeper interface {
+	GetRefreshToken() string
+	SetRefreshToken(token string) error
 }
 
 //=============================================================================
@@ -210,6 +364,178 @@ type Account struct {
 
 //=============================================================================
 
+type OrderSide string
+
+const (
+	OrderSideBuy  OrderSide = "buy"
+	OrderSideSell OrderSide = "sell"
+)
+
+//-----------------------------------------------------------------------------
+
+type OrderType string
+
+const (
+	OrderTypeMarket    OrderType = "market"
+	OrderTypeLimit     OrderType = "limit"
+	OrderTypeStop      OrderType = "stop"
+	OrderTypeStopLimit OrderType = "stop-limit"
+)
+
+//-----------------------------------------------------------------------------
+
+type TimeInForce string
+
+const (
+	TimeInForceDay TimeInForce = "day"
+	TimeInForceGTC TimeInForce = "gtc"
+	TimeInForceIOC TimeInForce = "ioc"
+	TimeInForceFOK TimeInForce = "fok"
+)
+
+//-----------------------------------------------------------------------------
+
+type OrderStatus string
+
+const (
+	OrderStatusPending         OrderStatus = "pending"
+	OrderStatusWorking         OrderStatus = "working"
+	OrderStatusPartiallyFilled OrderStatus = "partially-filled"
+	OrderStatusFilled          OrderStatus = "filled"
+	OrderStatusCancelled       OrderStatus = "cancelled"
+	OrderStatusRejected        OrderStatus = "rejected"
+	OrderStatusExpired         OrderStatus = "expired"
+)
+
+//-----------------------------------------------------------------------------
+
+type Order struct {
+	Id             string       `json:"id"`
+	ClientOrderId  string       `json:"clientOrderId"`
+	Account        string       `json:"account"`
+	Symbol         string       `json:"symbol"`
+	Side           OrderSide    `json:"side"`
+	Quantity       float64      `json:"quantity"`
+	Type           OrderType    `json:"type"`
+	LimitPrice     float64      `json:"limitPrice"`
+	StopPrice      float64      `json:"stopPrice"`
+	TimeInForce    TimeInForce  `json:"timeInForce"`
+	Status         OrderStatus  `json:"status"`
+	FilledQuantity float64      `json:"filledQuantity"`
+	AveragePrice   float64      `json:"averagePrice"`
+	Message        string       `json:"message"`
+	CreatedAt      *time.Time   `json:"createdAt"`
+	ClosedAt       *time.Time   `json:"closedAt"`
+	Fills          []*Fill      `json:"fills"`
+}
+
+//-----------------------------------------------------------------------------
+
+func (o *Order) Validate() error {
+	if o.Account == "" {
+		return errors.New("missing account")
+	}
+
+	if o.Symbol == "" {
+		return errors.New("missing symbol")
+	}
+
+	if o.Side != OrderSideBuy && o.Side != OrderSideSell {
+		return errors.New("invalid order side : "+ string(o.Side))
+	}
+
+	if o.Quantity <= 0 {
+		return errors.New("quantity must be greater than zero")
+	}
+
+	switch o.Type {
+		case OrderTypeMarket:
+
+		case OrderTypeLimit:
+			if o.LimitPrice <= 0 {
+				return errors.New("missing limit price for a limit order")
+			}
+
+		case OrderTypeStop:
+			if o.StopPrice <= 0 {
+				return errors.New("missing stop price for a stop order")
+			}
+
+		case OrderTypeStopLimit:
+			if o.LimitPrice <= 0 || o.StopPrice <= 0 {
+				return errors.New("missing limit or stop price for a stop-limit order")
+			}
+
+		default:
+			return errors.New("invalid order type : "+ string(o.Type))
+	}
+
+	switch o.TimeInForce {
+		case "":
+			o.TimeInForce = TimeInForceDay
+
+		case TimeInForceDay, TimeInForceGTC, TimeInForceIOC, TimeInForceFOK:
+
+		default:
+			return errors.New("invalid time in force : "+ string(o.TimeInForce))
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (o *Order) IsOpen() bool {
+	return o.Status == OrderStatusPending || o.Status == OrderStatusWorking || o.Status == OrderStatusPartiallyFilled
+}
+
+//=============================================================================
+
+type OrderChange struct {
+	Quantity   float64 `json:"quantity"`
+	LimitPrice float64 `json:"limitPrice"`
+	StopPrice  float64 `json:"stopPrice"`
+}
+
+//-----------------------------------------------------------------------------
+
+func (oc *OrderChange) Validate() error {
+	if oc.Quantity < 0 || oc.LimitPrice < 0 || oc.StopPrice < 0 {
+		return errors.New("quantity and prices cannot be negative")
+	}
+
+	if oc.Quantity == 0 && oc.LimitPrice == 0 && oc.StopPrice == 0 {
+		return errors.New("nothing to change")
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+type Fill struct {
+	Id         string    `json:"id"`
+	Quantity   float64   `json:"quantity"`
+	Price      float64   `json:"price"`
+	Commission float64   `json:"commission"`
+	TimeStamp  time.Time `json:"timeStamp"`
+}
+
+//=============================================================================
+
+type Position struct {
+	Account              string     `json:"account"`
+	Symbol               string     `json:"symbol"`
+	Root                 string     `json:"root"`
+	Quantity             float64    `json:"quantity"`
+	AveragePrice         float64    `json:"averagePrice"`
+	MarketValue          float64    `json:"marketValue"`
+	UnrealizedProfitLoss float64    `json:"unrealizedProfitLoss"`
+	OpenTime             *time.Time `json:"openTime"`
+}
+
+//=============================================================================
+
 type Instrument struct {
 	Name            string     `json:"name"`
 	Description     string     `json:"description"`
@@ -221,6 +547,7 @@ type Instrument struct {
 	MinMove         float64    `json:"minMove"`
 	Continuous      bool       `json:"continuous"`
 	Month           string     `json:"month"`
+	Canonical       string     `json:"canonical,omitempty"`
 }
 
 //=============================================================================
@@ -237,12 +564,179 @@ type RootSymbol struct {
 
 //=============================================================================
 
+type BarUnit string
+
+const (
+	BarUnitTick   BarUnit = "tick"
+	BarUnitSecond BarUnit = "second"
+	BarUnitMinute BarUnit = "minute"
+	BarUnitDaily  BarUnit = "daily"
+	BarUnitWeekly BarUnit = "weekly"
+)
+
+//-----------------------------------------------------------------------------
+
+func (u BarUnit) IsIntraday() bool {
+	switch u {
+		case BarUnitTick, BarUnitSecond, BarUnitMinute:
+			return true
+	}
+
+	return false
+}
+
+//=============================================================================
+//--- Longest date range (in days) that can be requested for each unit. Bars
+//--- aggregated into a timeframe are built from minute bars
+
+var maxRangeDays = map[BarUnit]int{
+	BarUnitTick  : 7,
+	BarUnitSecond: 7,
+	BarUnitMinute: 31,
+	BarUnitDaily : 3660,
+	BarUnitWeekly: 3660,
+}
+
+//=============================================================================
+
+type PriceBarsRequest struct {
+	Symbol    string
+	Unit      BarUnit
+	Interval  int
+	From      datatype.IntDate
+	To        datatype.IntDate
+	Calendar  string   // exchange whose trading sessions define the dates. Empty for UTC days
+	Timeframe string   // if set, bars are aggregated from 1-minute bars and Unit/Interval are ignored
+}
+
+//-----------------------------------------------------------------------------
+
+func NewPriceBarsRequest(symbol string, date datatype.IntDate) *PriceBarsRequest {
+	return &PriceBarsRequest{
+		Symbol  : symbol,
+		Unit    : BarUnitMinute,
+		Interval: 1,
+		From    : date,
+		To      : date,
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func (r *PriceBarsRequest) Validate() error {
+	if r.Timeframe != "" {
+		tf, err := ParseTimeframe(r.Timeframe)
+		if err != nil {
+			return err
+		}
+
+		if tf.Session && r.Calendar == "" {
+			return errors.New("the session timeframe requires a calendar")
+		}
+	}
+
+	switch r.Unit {
+		case BarUnitTick, BarUnitSecond, BarUnitMinute, BarUnitDaily, BarUnitWeekly:
+		default:
+			return errors.New("invalid bar unit : "+ string(r.Unit))
+	}
+
+	if r.Interval < 1 {
+		return errors.New("interval must be greater than zero")
+	}
+
+	if r.From <= 0 || r.To < r.From {
+		return errors.New("invalid date range : "+ r.From.String() +" - "+ r.To.String())
+	}
+
+	unit := r.Unit
+	if r.Timeframe != "" {
+		unit = BarUnitMinute
+	}
+
+	if DaysBetween(r.From, r.To) > maxRangeDays[unit] {
+		return errors.New("date range too long : at most "+ strconv.Itoa(maxRangeDays[unit]) +" days are allowed for "+ string(unit) +" bars")
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (r *PriceBarsRequest) IsSingleMinuteDay() bool {
+	return r.Unit == BarUnitMinute && r.Interval == 1 && r.From == r.To
+}
+
+//=============================================================================
+
 type PriceBars struct {
-	Symbol  string      `json:"symbol"`
-	Date    int         `json:"date"`
-	Bars    []*PriceBar `json:"bars"`
-	NoData  bool        `json:"noData"`
-	Timeout bool        `json:"timeout"`
+	Symbol      string      `json:"symbol"`
+	Date        int         `json:"date"`
+	To          int         `json:"to"`
+	Unit        BarUnit     `json:"unit"`
+	Interval    int         `json:"interval"`
+	Timeframe   string      `json:"timeframe,omitempty"`
+	Bars        []*PriceBar `json:"bars"`
+	NoData      bool        `json:"noData"`
+	Holiday     bool        `json:"holiday"`                // the market was closed on all dates, for a holiday
+	HolidayName string      `json:"holidayName,omitempty"`
+	Timeout     bool        `json:"timeout"`
+}
+
+//-----------------------------------------------------------------------------
+
+func NewPriceBars(rq *PriceBarsRequest) *PriceBars {
+	return &PriceBars{
+		Symbol  : rq.Symbol,
+		Date    : int(rq.From),
+		To      : int(rq.To),
+		Unit    : rq.Unit,
+		Interval: rq.Interval,
+	}
+}
+
+//=============================================================================
+
+type StreamType string
+
+const (
+	StreamTypeQuotes StreamType = "quotes"
+	StreamTypeBars   StreamType = "bars"
+)
+
+//-----------------------------------------------------------------------------
+
+type StreamHandler func(e *StreamEvent)
+
+//-----------------------------------------------------------------------------
+
+type StreamEvent struct {
+	Symbol string     `json:"symbol"`
+	Type   StreamType `json:"type"`
+	Quote  *Quote     `json:"quote,omitempty"`
+	Bar    *PriceBar  `json:"bar,omitempty"`
+	Closed bool       `json:"closed"`
+}
+
+//-----------------------------------------------------------------------------
+
+type Quote struct {
+	TimeStamp time.Time `json:"timeStamp"`
+	Bid       float64   `json:"bid"`
+	Ask       float64   `json:"ask"`
+	Last      float64   `json:"last"`
+	BidSize   int       `json:"bidSize"`
+	AskSize   int       `json:"askSize"`
+	Volume    int       `json:"volume"`
+}
+
+//=============================================================================
+
+type ReplayResult struct {
+	Symbol string `json:"symbol"`
+	Date   int    `json:"date"`
+	Bars   int    `json:"bars"`
+	Fills  int    `json:"fills"`
 }
 
 //=============================================================================
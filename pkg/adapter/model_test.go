@@ -0,0 +1,60 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"testing"
+
+	"github.com/bit-fever/core/datatype"
+)
+
+//=============================================================================
+
+func TestPriceBarsRequestRange(t *testing.T) {
+	for _, tc := range []struct {
+		unit      BarUnit
+		timeframe string
+		to        datatype.IntDate
+		valid     bool
+	}{
+		{ BarUnitMinute, "",   20250201, true  },
+		{ BarUnitMinute, "",   20250215, false },
+		{ BarUnitDaily,  "",   20341231, true  },
+		{ BarUnitDaily,  "",   20360101, false },
+		{ BarUnitDaily,  "1h", 20250201, true  },
+		{ BarUnitDaily,  "1h", 20250601, false },
+		{ BarUnitTick,   "",   20250108, true  },
+		{ BarUnitTick,   "",   20250110, false },
+	} {
+		rq := &PriceBarsRequest{ Symbol: "ESH25", Unit: tc.unit, Interval: 1, From: 20250101, To: tc.to, Timeframe: tc.timeframe }
+
+		if err := rq.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%s/%s up to %v: expected valid=%v, got %v", tc.unit, tc.timeframe, tc.to, tc.valid, err)
+		}
+	}
+}
+
+//=============================================================================
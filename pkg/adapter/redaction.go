@@ -0,0 +1,349 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"bytes"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== Redaction of secrets (headers, cookies, params) before they reach the logs.
+//=== Redacted values are replaced by a short HMAC, so that the same token can
+//=== still be correlated across log lines without being disclosed. The key is
+//=== random and lives only in the process, so hashes cannot be brute-forced
+//=== offline. Passwords and 2FA codes are never hashed
+//===
+//=============================================================================
+
+const RedactedValue = "[redacted]"
+
+var defaultRedactedHeaders = []string{
+	"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Auth-Token",
+	"X-Authorization", "X-Id-Token",
+}
+
+var defaultRedactedCookies = []string{
+	"*",
+}
+
+var defaultRedactedParams = []string{
+	"password", "secret", "token", "access_token", "refresh_token", "id_token", "client_secret", "code", "apikey", "api_key",
+	"accessToken", "refreshToken", "idToken", "twoFACode",
+}
+
+//--- Headers holding urls, whose query can carry secrets (like an OAuth code)
+
+var urlHeaders = map[string]bool{
+	"Location": true, "Content-Location": true, "Referer": true,
+}
+
+//=============================================================================
+
+var redactionKey = newRedactionKey()
+
+//=============================================================================
+
+var redaction = struct {
+	sync.RWMutex
+	headers map[string]bool
+	cookies map[string]bool
+	params  map[string]bool
+}{
+	headers: buildDenyList(defaultRedactedHeaders, nil),
+	cookies: buildDenyList(defaultRedactedCookies, nil),
+	params : buildDenyList(defaultRedactedParams,  nil),
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+//--- Configured headers and params are added to the defaults, which cannot be
+//--- removed. Cookies are all redacted unless an explicit list is provided
+
+func InitRedaction(cfg *app.Redaction) {
+	redaction.Lock()
+	defer redaction.Unlock()
+
+	redaction.headers = buildDenyList(defaultRedactedHeaders, cfg.Headers)
+	redaction.params  = buildDenyList(defaultRedactedParams,  cfg.Params)
+
+	if len(cfg.Cookies) > 0 {
+		redaction.cookies = buildDenyList(nil, cfg.Cookies)
+	} else {
+		redaction.cookies = buildDenyList(defaultRedactedCookies, nil)
+	}
+}
+
+//=============================================================================
+
+func RedactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, redactionKey)
+	mac.Write([]byte(value))
+	return "[redacted:"+ hex.EncodeToString(mac.Sum(nil)[:4]) +"]"
+}
+
+//=============================================================================
+
+func RedactHeader(name, value string) string {
+	if urlHeaders[http.CanonicalHeaderKey(name)] {
+		if u, err := url.Parse(value); err == nil {
+			value = RedactUrl(u)
+		} else {
+			value = RedactValue(value)
+		}
+	}
+
+	redaction.RLock()
+	defer redaction.RUnlock()
+
+	if isDenied(redaction.headers, name) {
+		return RedactValue(value)
+	}
+
+	return value
+}
+
+//=============================================================================
+
+func RedactCookie(name, value string) string {
+	redaction.RLock()
+	defer redaction.RUnlock()
+
+	if isDenied(redaction.cookies, name) {
+		return RedactValue(value)
+	}
+
+	return value
+}
+
+//=============================================================================
+
+func RedactQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return RedactValue(query)
+	}
+
+	redaction.RLock()
+	defer redaction.RUnlock()
+
+	for name, list := range values {
+		if isDenied(redaction.params, name) {
+			for i := range list {
+				list[i] = RedactValue(list[i])
+			}
+		}
+	}
+
+	return values.Encode()
+}
+
+//=============================================================================
+
+func RedactUrl(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	clone := *u
+	clone.User     = nil
+	clone.RawQuery = RedactQuery(u.RawQuery)
+
+	return clone.String()
+}
+
+//=============================================================================
+//--- Password params (and the 2FA code) are always redacted, whatever the deny
+//--- list says, and without a hash
+
+func RedactParams(defs []*ParamDef, values map[string]any) map[string]any {
+	passwords := map[string]bool{ ParamTwoFACode: true }
+	for _, def := range defs {
+		if def.Type == ParamTypePassword {
+			passwords[def.Name] = true
+		}
+	}
+
+	redaction.RLock()
+	defer redaction.RUnlock()
+
+	res := map[string]any{}
+	for name, value := range values {
+		if passwords[name] {
+			res[name] = RedactedValue
+		} else if isDenied(redaction.params, name) {
+			if s, ok := value.(string); ok {
+				res[name] = RedactValue(s)
+			} else {
+				res[name] = RedactValue("")
+			}
+		} else {
+			res[name] = value
+		}
+	}
+
+	return res
+}
+
+//=============================================================================
+//--- Redacts the values of denied fields at any depth of a JSON document. The
+//--- body can contain several concatenated documents (like a stream). Returns
+//--- false if the body is not JSON
+
+func RedactJson(body []byte) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+
+	redaction.RLock()
+	defer redaction.RUnlock()
+
+	for {
+		var doc any
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return out.Bytes(), true
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		if err = enc.Encode(redactJsonValue(doc)); err != nil {
+			return nil, false
+		}
+	}
+}
+
+//=============================================================================
+
+func HeaderAttr(key string, header http.Header) slog.Attr {
+	var attrs []any
+
+	for name, list := range header {
+		values := make([]string, len(list))
+		for i, v := range list {
+			values[i] = RedactHeader(name, v)
+		}
+		attrs = append(attrs, slog.String(name, strings.Join(values, " | ")))
+	}
+
+	return slog.Group(key, attrs...)
+}
+
+//=============================================================================
+
+func CookieAttr(key string, cookies []*http.Cookie) slog.Attr {
+	var attrs []any
+
+	for _, cookie := range cookies {
+		attrs = append(attrs, slog.String(cookie.Name, RedactCookie(cookie.Name, cookie.Value)))
+	}
+
+	return slog.Group(key, attrs...)
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func newRedactionKey() []byte {
+	key := make([]byte, 32)
+	_,_  = crand.Read(key)
+	return key
+}
+
+//=============================================================================
+
+func buildDenyList(defaults, extra []string) map[string]bool {
+	list := map[string]bool{}
+
+	for _, name := range defaults {
+		list[strings.ToLower(name)] = true
+	}
+
+	for _, name := range extra {
+		list[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	return list
+}
+
+//=============================================================================
+//--- The "*" entry matches every name
+
+func isDenied(list map[string]bool, name string) bool {
+	return list["*"] || list[strings.ToLower(name)]
+}
+
+//=============================================================================
+
+func redactJsonValue(value any) any {
+	switch v := value.(type) {
+		case map[string]any:
+			for name, item := range v {
+				if isDenied(redaction.params, name) {
+					if s, ok := item.(string); ok {
+						v[name] = RedactValue(s)
+						continue
+					}
+				}
+				v[name] = redactJsonValue(item)
+			}
+
+		case []any:
+			for i, item := range v {
+				v[i] = redactJsonValue(item)
+			}
+	}
+
+	return value
+}
+
+//=============================================================================
@@ -0,0 +1,100 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+//=============================================================================
+
+func TestRedactTwoFACode(t *testing.T) {
+	defs := []*ParamDef{{ Name: ParamTwoFACode, Type: ParamTypeString }}
+	res  := RedactParams(defs, map[string]any{ ParamTwoFACode: "123456", ParamUsername: "tester" })
+
+	if res[ParamTwoFACode] != RedactedValue || res[ParamUsername] != "tester" {
+		t.Errorf("the 2FA code must be redacted without a hash: %v", res)
+	}
+}
+
+//=============================================================================
+
+func TestRedactPasswordParams(t *testing.T) {
+	defs := []*ParamDef{{ Name: "pin", Type: ParamTypePassword }, { Name: "apikey", Type: ParamTypeString }}
+	res  := RedactParams(defs, map[string]any{ "pin": "1234", "apikey": "key-1" })
+
+	if res["pin"] != RedactedValue {
+		t.Errorf("a password must be redacted without a hash, got %v", res["pin"])
+	}
+
+	//--- Other secrets keep a keyed hash, stable within the process
+
+	if res["apikey"] != RedactValue("key-1") || res["apikey"] == RedactedValue {
+		t.Errorf("a secret must be redacted with a hash, got %v", res["apikey"])
+	}
+
+	sum := sha256.Sum256([]byte("key-1"))
+	if strings.Contains(RedactValue("key-1"), hex.EncodeToString(sum[:4])) {
+		t.Errorf("the hash must be keyed")
+	}
+}
+
+//=============================================================================
+
+func TestRedactUrlHeaders(t *testing.T) {
+	for _, name := range []string{ "Location", "referer" } {
+		value := RedactHeader(name, "https://example.com/callback?code=secret-code&state=S1")
+
+		if strings.Contains(value, "secret-code") || !strings.Contains(value, "state=S1") {
+			t.Errorf("%s: expected the code to be redacted, got %s", name, value)
+		}
+	}
+}
+
+//=============================================================================
+
+func TestRedactedUrlsMatchAcrossProcesses(t *testing.T) {
+	u,_ := url.Parse("https://example.com/callback?code=secret-code&state=S1")
+	rec := RedactUrl(u)
+
+	//--- A replay runs in another process, with another key
+
+	key := redactionKey
+	redactionKey = newRedactionKey()
+	t.Cleanup(func() { redactionKey = key })
+
+	rep := RedactUrl(u)
+
+	if rec == rep || matchableUrl(rec) != matchableUrl(rep) {
+		t.Errorf("redacted urls must match whatever the key: %s, %s", rec, rep)
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,204 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package adapter
+
+import (
+	"log/slog"
+	"sync"
+)
+
+//=============================================================================
+
+const StreamClientBufferSize = 256
+
+//=============================================================================
+//===
+//=== StreamClient
+//===
+//=============================================================================
+
+type StreamClient struct {
+	Events chan *StreamEvent
+	topic  string
+	hub    *streamHub
+}
+
+//=============================================================================
+
+func (sc *StreamClient) Close() {
+	sc.hub.remove(sc)
+}
+
+//=============================================================================
+//===
+//=== streamHub: fans out the adapter streams to many clients. The adapter is
+//===            subscribed only once per symbol and stream type
+//===
+//=============================================================================
+//--- The adapter is never called with the hub locked, as its stream handler
+//--- publishes through the same lock. Subscriptions are serialized by subs
+
+type streamHub struct {
+	sync.Mutex
+	subs   sync.Mutex
+	topics map[string]map[*StreamClient]bool
+	unsub  map[string]func() error
+}
+
+//=============================================================================
+
+func newStreamHub() *streamHub {
+	return &streamHub{
+		topics: map[string]map[*StreamClient]bool{},
+		unsub : map[string]func() error{},
+	}
+}
+
+//=============================================================================
+
+func (h *streamHub) add(symbol string, st StreamType, a Adapter) (*StreamClient,error) {
+	h.subs.Lock()
+	defer h.subs.Unlock()
+
+	topic := string(st) +":"+ symbol
+
+	h.Lock()
+	_,ok := h.topics[topic]
+	h.Unlock()
+
+	if !ok {
+		err := a.Subscribe(symbol, st, func(e *StreamEvent) {
+			h.publish(topic, e)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		h.Lock()
+		h.topics[topic] = map[*StreamClient]bool{}
+		h.unsub [topic] = func() error {
+			return a.Unsubscribe(symbol, st)
+		}
+		h.Unlock()
+	}
+
+	sc := &StreamClient{
+		Events: make(chan *StreamEvent, StreamClientBufferSize),
+		topic : topic,
+		hub   : h,
+	}
+
+	h.Lock()
+	h.topics[topic][sc] = true
+	h.Unlock()
+
+	return sc, nil
+}
+
+//=============================================================================
+
+func (h *streamHub) remove(sc *StreamClient) {
+	h.subs.Lock()
+	defer h.subs.Unlock()
+
+	h.Lock()
+	clients,ok := h.topics[sc.topic]
+	if !ok || !clients[sc] {
+		h.Unlock()
+		return
+	}
+
+	delete(clients, sc)
+	close(sc.Events)
+
+	var unsub func() error
+	if len(clients) == 0 {
+		unsub = h.detach(sc.topic)
+	}
+	h.Unlock()
+
+	if unsub != nil {
+		unsubscribe(sc.topic, unsub)
+	}
+}
+
+//=============================================================================
+
+func (h *streamHub) removeAll() {
+	h.subs.Lock()
+	defer h.subs.Unlock()
+
+	h.Lock()
+	unsubs := map[string]func() error{}
+	for topic,clients := range h.topics {
+		for sc := range clients {
+			close(sc.Events)
+		}
+		unsubs[topic] = h.detach(topic)
+	}
+	h.Unlock()
+
+	for topic,unsub := range unsubs {
+		unsubscribe(topic, unsub)
+	}
+}
+
+//=============================================================================
+//--- Must be called with the hub locked. Returns the unsubscribe function
+
+func (h *streamHub) detach(topic string) func() error {
+	unsub := h.unsub[topic]
+
+	delete(h.topics, topic)
+	delete(h.unsub,  topic)
+
+	return unsub
+}
+
+//=============================================================================
+
+func (h *streamHub) publish(topic string, e *StreamEvent) {
+	h.Lock()
+	defer h.Unlock()
+
+	for sc := range h.topics[topic] {
+		select {
+			case sc.Events <- e:
+			default:
+				//--- Slow clients lose events instead of blocking the adapter stream
+		}
+	}
+}
+
+//=============================================================================
+
+func unsubscribe(topic string, unsub func() error) {
+	err := unsub()
+	if err != nil {
+		slog.Warn("StreamHub: Cannot unsubscribe from adapter", "topic", topic, "error", err.Error())
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,256 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"sync"
+	"testing"
+)
+
+//=============================================================================
+
+func TestStreamHubFanOut(t *testing.T) {
+	ctx,ta := newTestStreamContext(t)
+
+	c1 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+	c2 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+	c3 := subscribe(t, ctx, "ESH25", StreamTypeBars)
+
+	if n := ta.count(ta.subscribes); n != 2 {
+		t.Fatalf("expected one adapter subscription per topic, got %d", n)
+	}
+
+	ta.publish("ESH25", StreamTypeQuotes, &StreamEvent{ Symbol: "ESH25", Type: StreamTypeQuotes, Quote: &Quote{ Last: 5000 } })
+
+	for _, sc := range []*StreamClient{ c1, c2 } {
+		e := <-sc.Events
+		if e.Quote == nil || e.Quote.Last != 5000 {
+			t.Errorf("unexpected event: %+v", e)
+		}
+	}
+
+	if len(c3.Events) != 0 {
+		t.Errorf("bar clients must not receive quotes")
+	}
+}
+
+//=============================================================================
+
+func TestStreamHubUnsubscribesWithLastClient(t *testing.T) {
+	ctx,ta := newTestStreamContext(t)
+
+	c1 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+	c2 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+
+	c1.Close()
+	if _,open := <-c1.Events; open {
+		t.Errorf("the events of a closed client must be closed")
+	}
+
+	if n := ta.count(ta.unsubscribes); n != 0 {
+		t.Fatalf("the adapter must stay subscribed while clients remain, got %d unsubscriptions", n)
+	}
+
+	c2.Close()
+	c2.Close()
+
+	if n := ta.count(ta.unsubscribes); n != 1 {
+		t.Fatalf("expected 1 unsubscription when the last client leaves, got %d", n)
+	}
+
+	//--- A new client subscribes the adapter again
+
+	subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+
+	if n := ta.count(ta.subscribes); n != 2 {
+		t.Errorf("expected 2 adapter subscriptions, got %d", n)
+	}
+}
+
+//=============================================================================
+
+func TestStreamHubRemoveAll(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		close func(ctx *ConnectionContext)
+	}{
+		{ "disconnect", func(ctx *ConnectionContext) { _ = ctx.Disconnect() } },
+		{ "reconnect",  func(ctx *ConnectionContext) { _ = ctx.RefreshToken() } },
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx,ta := newTestStreamContext(t)
+
+			c1 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+			c2 := subscribe(t, ctx, "ESH25", StreamTypeQuotes)
+			c3 := subscribe(t, ctx, "ESM25", StreamTypeBars)
+
+			tc.close(ctx)
+
+			for _, sc := range []*StreamClient{ c1, c2, c3 } {
+				if _,open := <-sc.Events; open {
+					t.Errorf("the events of all clients must be closed")
+				}
+			}
+
+			if n := ta.count(ta.unsubscribes); n != 2 {
+				t.Errorf("expected 2 unsubscriptions, got %d", n)
+			}
+
+			//--- Clients closed after the hub was cleared are ignored
+
+			c1.Close()
+
+			if n := ta.count(ta.unsubscribes); n != 2 {
+				t.Errorf("expected 2 unsubscriptions, got %d", n)
+			}
+		})
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func newTestStreamContext(t *testing.T) (*ConnectionContext, *testStreamAdapter) {
+	ta := &testStreamAdapter{
+		handlers    : map[string]StreamHandler{},
+		subscribes  : map[string]int{},
+		unsubscribes: map[string]int{},
+	}
+
+	ctx,err := NewConnectionContext("tester", "C1", "localhost", ta, map[string]any{}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ctx, ta
+}
+
+//=============================================================================
+
+func subscribe(t *testing.T, ctx *ConnectionContext, symbol string, st StreamType) *StreamClient {
+	t.Helper()
+
+	sc,err := ctx.Subscribe(symbol, st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc
+}
+
+//=============================================================================
+//===
+//=== testStreamAdapter: records the adapter subscriptions. Services that are
+//===                    not overridden are not used by these tests
+//===
+//=============================================================================
+
+type testStreamAdapter struct {
+	Adapter
+	sync.Mutex
+	handlers     map[string]StreamHandler
+	subscribes   map[string]int
+	unsubscribes map[string]int
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) GetInfo() *Info {
+	return &Info{
+		Code     : "TEST",
+		Reconnect: ReconnectPolicy{ MaxAttempts: 1, InitialDelaySec: 60, MaxDelaySec: 60 },
+	}
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) Clone(configParams map[string]any, connectParams map[string]any) Adapter {
+	return a
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) Disconnect(ctx *ConnectionContext) error {
+	return nil
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) RefreshToken() error {
+	return ErrSessionExpired
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) Subscribe(symbol string, st StreamType, handler StreamHandler) error {
+	a.Lock()
+	defer a.Unlock()
+
+	a.handlers  [string(st) +":"+ symbol] = handler
+	a.subscribes[string(st) +":"+ symbol]++
+	return nil
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) Unsubscribe(symbol string, st StreamType) error {
+	a.Lock()
+	defer a.Unlock()
+
+	delete(a.handlers, string(st) +":"+ symbol)
+	a.unsubscribes[string(st) +":"+ symbol]++
+	return nil
+}
+
+//=============================================================================
+
+func (a *testStreamAdapter) publish(symbol string, st StreamType, e *StreamEvent) {
+	a.Lock()
+	handler := a.handlers[string(st) +":"+ symbol]
+	a.Unlock()
+
+	handler(e)
+}
+
+//=============================================================================
+//--- Total calls over all topics
+
+func (a *testStreamAdapter) count(calls map[string]int) int {
+	a.Lock()
+	defer a.Unlock()
+
+	total := 0
+	for _, n := range calls {
+		total += n
+	}
+
+	return total
+}
+
+//=============================================================================
@@ -0,0 +1,173 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package adapter
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//=============================================================================
+//===
+//=== Broker-neutral symbology. A canonical symbol identifies a futures contract
+//=== by root, exchange and contract month:
+//===
+//===    ES:CME:202503   (March 2025 contract)
+//===    ES:CME          (continuous contract)
+//===
+//=== Broker symbols never contain ':', so both forms can be accepted wherever
+//=== a symbol is expected. Adapters convert them through a SymbolMapper
+//===
+//=============================================================================
+
+const CanonicalSeparator = ":"
+
+//-----------------------------------------------------------------------------
+
+const FuturesMonthCodes = "FGHJKMNQUVXZ"
+
+//=============================================================================
+//=== Optional interface for adapters that support canonical symbols
+
+type SymbolMapper interface {
+	ToAdapterSymbol(cs *CanonicalSymbol) (string, error)
+	ToCanonicalSymbol(symbol string, exchange string) (*CanonicalSymbol, error)
+}
+
+//=============================================================================
+
+type CanonicalSymbol struct {
+	Root     string `json:"root"`
+	Exchange string `json:"exchange"`
+	Year     int    `json:"year"`   // 0 for the continuous contract
+	Month    int    `json:"month"`  // 1..12, 0 for the continuous contract
+}
+
+//=============================================================================
+
+var canonicalPartRegex = regexp.MustCompile("^[A-Z0-9_]+$")
+var futuresSymbolRegex = regexp.MustCompile("^([A-Z0-9]+)(["+ FuturesMonthCodes +"])([0-9]{2})$")
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func IsCanonicalSymbol(symbol string) bool {
+	return strings.Contains(symbol, CanonicalSeparator)
+}
+
+//=============================================================================
+
+func ParseCanonicalSymbol(symbol string) (*CanonicalSymbol, error) {
+	parts := strings.Split(strings.ToUpper(symbol), CanonicalSeparator)
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, errors.New("invalid canonical symbol (expected ROOT:EXCHANGE[:YYYYMM]): "+ symbol)
+	}
+
+	cs := &CanonicalSymbol{
+		Root    : parts[0],
+		Exchange: parts[1],
+	}
+
+	if !canonicalPartRegex.MatchString(cs.Root) || !canonicalPartRegex.MatchString(cs.Exchange) {
+		return nil, errors.New("invalid root or exchange in canonical symbol: "+ symbol)
+	}
+
+	if len(parts) == 3 {
+		ym, err := strconv.Atoi(parts[2])
+		if err != nil || len(parts[2]) != 6 || ym%100 < 1 || ym%100 > 12 {
+			return nil, errors.New("invalid contract month in canonical symbol: "+ symbol)
+		}
+
+		cs.Year  = ym / 100
+		cs.Month = ym % 100
+	}
+
+	return cs, nil
+}
+
+//=============================================================================
+//--- Formats the usual futures symbol (like ESH25) used by most brokers
+
+func FormatFuturesSymbol(cs *CanonicalSymbol) string {
+	return cs.Root + cs.MonthCode() + strconv.Itoa(cs.Year/10%10) + strconv.Itoa(cs.Year%10)
+}
+
+//=============================================================================
+//--- Parses symbols like ESH25. Years are assumed to be in this century
+
+func ParseFuturesSymbol(symbol, exchange string) (*CanonicalSymbol, bool) {
+	m := futuresSymbolRegex.FindStringSubmatch(symbol)
+	if m == nil {
+		return nil, false
+	}
+
+	year,_ := strconv.Atoi(m[3])
+
+	return &CanonicalSymbol{
+		Root    : m[1],
+		Exchange: strings.ToUpper(exchange),
+		Year    : 2000 + year,
+		Month   : strings.Index(FuturesMonthCodes, m[2]) +1,
+	}, true
+}
+
+//=============================================================================
+//===
+//=== Methods
+//===
+//=============================================================================
+
+func (cs *CanonicalSymbol) IsContinuous() bool {
+	return cs.Month == 0
+}
+
+//=============================================================================
+
+func (cs *CanonicalSymbol) MonthCode() string {
+	if cs.Month < 1 || cs.Month > 12 {
+		return ""
+	}
+
+	return FuturesMonthCodes[cs.Month-1 : cs.Month]
+}
+
+//=============================================================================
+
+func (cs *CanonicalSymbol) String() string {
+	s := cs.Root + CanonicalSeparator + cs.Exchange
+	if cs.IsContinuous() {
+		return s
+	}
+
+	return s + CanonicalSeparator + strconv.Itoa(cs.Year*100 + cs.Month)
+}
+
+//=============================================================================
@@ -26,7 +26,7 @@ package tradestation
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -35,6 +35,7 @@ import (
 	"maps"
 	"net/http"
 	"net/http/cookiejar"
+	"net/url"
 	"slices"
 	"strconv"
 	"strings"
@@ -69,22 +70,20 @@ func (a *tradestation) Clone(configParams map[string]any, connectParams map[stri
 	b := *a
 	b.configParams  = retrieveConfigParams (configParams)
 	b.connectParams = retrieveConnectParams(connectParams)
+	b.cassette      = adapter.NewCassette(configParams)
+	b.session       = &session{}
+	b.streams       = &streams{
+		cancels: map[string]context.CancelFunc{},
+	}
 	return &b
 }
 
 //=============================================================================
 
 func (a *tradestation) Connect(ctx *adapter.ConnectionContext) (adapter.ConnectionResult,error) {
-	jar,_:= cookiejar.New(nil)
+	defer a.connectParams.clearSecrets()
 
-	a.client = &http.Client{
-		Jar: jar,
-		Timeout: time.Minute * 3,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-			},
-		},
-	}
+	a.client = newClient(a.cassette)
 
 	loginInfo,err := a.createLoginInfo()
 	if err != nil {
@@ -108,10 +107,7 @@ func (a *tradestation) Connect(ctx *adapter.ConnectionContext) (adapter.Connecti
 		return adapter.ConnectionResultError, err
 	}
 
-	a.apiUrl = DemoAPI
-	if a.configParams.LiveAccount {
-		a.apiUrl = LiveAPI
-	}
+	a.apiUrl = a.getApiUrl()
 
 	//--- Test tokens & accounts
 	err = a.testToken()
@@ -125,6 +121,7 @@ func (a *tradestation) Connect(ctx *adapter.ConnectionContext) (adapter.Connecti
 //=============================================================================
 
 func (a *tradestation) Disconnect(ctx *adapter.ConnectionContext) error {
+	a.stopStreams()
 	return nil
 }
 
@@ -150,14 +147,14 @@ func (a *tradestation) GetTokenExpSeconds() int {
 func (a *tradestation) RefreshToken() error {
 	payload := bytes.NewBufferString("")
 
-	rq, err := http.NewRequest("POST", RefreshTokenUrl, payload)
+	rq, err := http.NewRequest("POST", a.configParams.PortalUrl + RefreshTokenPath, payload)
 	if err != nil {
 		return err
 	}
 
 	setupCommonHeader(&rq.Header)
 	rq.Header.Set("Accept",         "*/*")
-	rq.Header.Set("Origin",         "https://my.tradestation.com")
+	rq.Header.Set("Origin",         a.configParams.PortalUrl)
 	rq.Header.Set("Sec-Fetch-Dest", "empty")
 	rq.Header.Set("Sec-Fetch-Mode", "cors")
 	rq.Header.Set("Sec-Fetch-Site", "same-origin")
@@ -168,10 +165,10 @@ func (a *tradestation) RefreshToken() error {
 	err = req.BuildResponse(res, err, &out)
 
 	if err == nil {
-		a.accessToken = out.AccessToken
+		a.setAccessToken(out.AccessToken)
 		a.refreshToken= out.IdToken
 
-		if a.accessToken == "" {
+		if out.AccessToken == "" {
 			err = errors.New("Got an empty access token (refresh token is not working)")
 		}
 	}
@@ -183,6 +180,44 @@ func (a *tradestation) RefreshToken() error {
 	return err
 }
 
+//=============================================================================
+
+func (a *tradestation) GetRefreshToken() string {
+	if a.client == nil {
+		return ""
+	}
+
+	st := SessionToken{
+		IdToken: a.refreshToken,
+		Cookies: a.client.Jar.Cookies(a.configParams.refreshTokenUrl()),
+	}
+
+	data,err := json.Marshal(&st)
+	if err != nil {
+		slog.Error("GetRefreshToken: Cannot marshal the session token", "error", err.Error())
+		return ""
+	}
+
+	return string(data)
+}
+
+//=============================================================================
+
+func (a *tradestation) SetRefreshToken(token string) error {
+	var st SessionToken
+	err := json.Unmarshal([]byte(token), &st)
+	if err != nil {
+		return errors.New("Invalid session token: "+ err.Error())
+	}
+
+	a.client       = newClient(a.cassette)
+	a.refreshToken = st.IdToken
+	a.apiUrl       = a.getApiUrl()
+	a.client.Jar.SetCookies(a.configParams.refreshTokenUrl(), st.Cookies)
+
+	return nil
+}
+
 //=============================================================================
 //===
 //=== Services
@@ -309,76 +344,52 @@ func (a *tradestation) GetInstruments(root string) ([]*adapter.Instrument,error)
 
 //=============================================================================
 
-func (a *tradestation) GetPriceBars(symbol string, date datatype.IntDate) (*adapter.PriceBars,error) {
-	//--- Last time set to 23:59:50 (and not 59) as it seems that Tradestation somethimes returns 1 extra bar
-	query := "unit=minute&interval=1&firstdate="+ date.String() +"T00%3A00%3A00Z&lastdate="+ date.String() +"T23%3A59%3A00Z"
-	apiUrl := a.apiUrl + UrlMarketDataBarcharts +"/"+ symbol +"?"+ query
-
-	priceBars := adapter.PriceBars{
-		Symbol: symbol,
-		Date  : int(date),
-	}
-
-	var res BarchartsResponse
-	rres,err := a.doGetWithResponse(apiUrl)
+func (a *tradestation) GetPriceBars(rq *adapter.PriceBarsRequest) (*adapter.PriceBars,error) {
+	unit,err := toTsBarUnit(rq)
 	if err != nil {
 		return nil, err
 	}
 
-	//--- Handle special cases
+	priceBars := adapter.NewPriceBars(rq)
 
-	if rres.StatusCode == http.StatusNotFound {
-		priceBars.NoData = true
-		return &priceBars, nil
-	}
+	//--- Tradestation returns a limited number of bars per call, so long ranges are split into chunks
 
-	if rres.StatusCode == http.StatusGatewayTimeout {
-		priceBars.Timeout = true
-		return &priceBars, nil
-	}
+	days := maxDaysPerRequest(rq)
 
-	//--- Read response
+	for from := rq.From; from <= rq.To; from = adapter.AddDays(from, days) {
+		to := adapter.AddDays(from, days -1)
+		if to > rq.To {
+			to = rq.To
+		}
 
-	err = req.BuildResponse(rres, err, &res)
-	if err != nil {
-		return nil, err
-	}
+		bars,timeout,err := a.getPriceBarsChunk(rq.Symbol, unit, rq.Interval, from, to)
+		if err != nil {
+			return nil, err
+		}
 
-	for _,bar := range res.Bars {
-		pb := adapter.PriceBar{
-			TimeStamp   : time.UnixMilli(bar.Epoch),
-			High        : toFloat64(bar.High),
-			Low         : toFloat64(bar.Low),
-			Open        : toFloat64(bar.Open),
-			Close       : toFloat64(bar.Close),
-			UpVolume    : bar.UpVolume,
-			DownVolume  : bar.DownVolume,
-			UpTicks     : bar.UpTicks,
-			DownTicks   : bar.DownTicks,
-			OpenInterest: toInt(bar.OpenInterest),
+		if timeout {
+			priceBars.Timeout = true
+			return priceBars, nil
 		}
 
-		priceBars.Bars = append(priceBars.Bars, &pb)
+		priceBars.Bars = append(priceBars.Bars, bars...)
 	}
 
-	return &priceBars, nil
+	priceBars.NoData = len(priceBars.Bars) == 0
+
+	return priceBars, nil
 }
 
 //=============================================================================
 
 func (a *tradestation) GetAccounts() ([]*adapter.Account,error) {
-	apiUrl := a.apiUrl + UrlBrokerageAccounts
-
-	var res AccountsResponse
-	err := a.doGet(apiUrl, &res)
+	accounts,err := a.getAccounts()
 	if err != nil {
 		return nil, err
 	}
 
-	accounts := convertAccounts(&res)
-
 	for _,acc := range accounts {
-		apiUrl = a.apiUrl + UrlBrokerageAccounts +"/"+ acc.Code +"/balances"
+		apiUrl := a.apiUrl + UrlBrokerageAccounts +"/"+ acc.Code +"/balances"
 		var bres BalancesResponse
 		err = a.doGet(apiUrl, &bres)
 		if err != nil {
@@ -404,20 +415,169 @@ func (a *tradestation) GetAccounts() ([]*adapter.Account,error) {
 
 //=============================================================================
 
-func (a *tradestation) GetOrders() (any,error) {
-	return nil, nil
+func (a *tradestation) GetOrders() ([]*adapter.Order,error) {
+	accounts,err := a.getAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+
+	var codes []string
+	for _,acc := range accounts {
+		codes = append(codes, acc.Code)
+	}
+
+	baseUrl := a.apiUrl + UrlBrokerageAccounts +"/"+ strings.Join(codes, ",") +"/orders"
+	apiUrl  := baseUrl
+
+	var orders []*adapter.Order
+
+	for {
+		var res OrdersResponse
+		err = a.doGet(apiUrl, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _,o := range res.Orders {
+			orders = append(orders, convertOrder(&o))
+		}
+
+		if res.NextToken == "" {
+			return orders, nil
+		}
+
+		apiUrl = baseUrl +"?nextToken="+ url.QueryEscape(res.NextToken)
+	}
 }
 
 //=============================================================================
 
-func (a *tradestation) GetPositions() (any,error) {
-	return nil, nil
+func (a *tradestation) PlaceOrder(o *adapter.Order) (*adapter.Order,error) {
+	rq := OrderRequest{
+		AccountID     : o.Account,
+		Symbol        : o.Symbol,
+		Quantity      : formatFloat(o.Quantity),
+		OrderType     : toTsOrderType(o.Type),
+		TradeAction   : toTsTradeAction(o.Side),
+		TimeInForce   : OrderTimeInForce{
+			Duration: toTsDuration(o.TimeInForce),
+		},
+		Route         : "Intelligent",
+		OrderConfirmID: o.ClientOrderId,
+	}
+
+	if o.Type == adapter.OrderTypeLimit || o.Type == adapter.OrderTypeStopLimit {
+		rq.LimitPrice = formatFloat(o.LimitPrice)
+	}
+
+	if o.Type == adapter.OrderTypeStop || o.Type == adapter.OrderTypeStopLimit {
+		rq.StopPrice = formatFloat(o.StopPrice)
+	}
+
+	var res OrderResponse
+	err := a.doPost(a.apiUrl + UrlOrderExecOrders, &rq, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res.Errors) != 0 {
+		return nil, req.NewBadRequestError("Order rejected by Tradestation: %v", res.Errors[0].Message)
+	}
+
+	if len(res.Orders) != 1 {
+		return nil, errors.New(fmt.Sprintf("Incorrect number of orders returned: %d", len(res.Orders)))
+	}
+
+	now := time.Now()
+	po  := *o
+	po.Id        = res.Orders[0].OrderID
+	po.Message   = res.Orders[0].Message
+	po.Status    = adapter.OrderStatusPending
+	po.CreatedAt = &now
+
+	return &po, nil
+}
+
+//=============================================================================
+
+func (a *tradestation) ModifyOrder(id string, oc *adapter.OrderChange) (*adapter.Order,error) {
+	rq := OrderReplaceRequest{}
+
+	if oc.Quantity != 0 {
+		rq.Quantity = formatFloat(oc.Quantity)
+	}
+
+	if oc.LimitPrice != 0 {
+		rq.LimitPrice = formatFloat(oc.LimitPrice)
+	}
+
+	if oc.StopPrice != 0 {
+		rq.StopPrice = formatFloat(oc.StopPrice)
+	}
+
+	var res OrderResult
+	err := a.doPut(a.apiUrl + UrlOrderExecOrders +"/"+ id, &rq, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != "" {
+		return nil, req.NewBadRequestError("Order change rejected by Tradestation: %v", res.Message)
+	}
+
+	return a.findOrder(id)
+}
+
+//=============================================================================
+
+func (a *tradestation) CancelOrder(id string) error {
+	var res OrderResult
+	err := a.doDelete(a.apiUrl + UrlOrderExecOrders +"/"+ id, &res)
+	if err != nil {
+		return err
+	}
+
+	if res.Error != "" {
+		return req.NewBadRequestError("Order cancellation rejected by Tradestation: %v", res.Message)
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func (a *tradestation) GetPositions() ([]*adapter.Position,error) {
+	accounts,err := a.getAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var positions []*adapter.Position
+
+	for _,acc := range accounts {
+		apiUrl := a.apiUrl + UrlBrokerageAccounts +"/"+ acc.Code +"/positions"
+		var res PositionsResponse
+		err = a.doGet(apiUrl, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		for _,p := range res.Positions {
+			positions = append(positions, convertPosition(&p))
+		}
+	}
+
+	return positions, nil
 }
 
 //=============================================================================
 
 func (a *tradestation) TestService(path,param string) (string,error) {
-	slog.Info("TestService: Testing service", "path", path, "param", param)
+	slog.Info("TestService: Testing service", "path", path, "param", adapter.RedactQuery(param))
 	url := a.apiUrl + path
 	if param != "" {
 		url = url + "?" + param
@@ -429,7 +589,7 @@ func (a *tradestation) TestService(path,param string) (string,error) {
 		return "",err
 	}
 
-	rq.Header.Set("Authorization", "Bearer "+ a.accessToken)
+	rq.Header.Set("Authorization", "Bearer "+ a.getAccessToken())
 	rq.Header.Set("Content-Type", "application/json")
 
 	res, err := a.client.Do(rq)
@@ -441,19 +601,72 @@ func (a *tradestation) TestService(path,param string) (string,error) {
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		slog.Error("TestService: Error reading response", "path", path, "param", param, "error", err.Error())
+		slog.Error("TestService: Error reading response", "path", path, "param", adapter.RedactQuery(param), "error", err.Error())
 		return "",err
 	}
 
 	return string(body), nil
 }
 
+//=============================================================================
+
+func (a *tradestation) SetCassetteOwner(username, connectionCode string) {
+	a.cassette.SetOwner(username, connectionCode)
+}
+
+//=============================================================================
+//===
+//=== Symbology
+//===
+//=============================================================================
+//--- Continuous contracts are prefixed by '@' (like @ES)
+
+func (a *tradestation) ToAdapterSymbol(cs *adapter.CanonicalSymbol) (string, error) {
+	if cs.IsContinuous() {
+		return "@"+ cs.Root, nil
+	}
+
+	return adapter.FormatFuturesSymbol(cs), nil
+}
+
+//=============================================================================
+
+func (a *tradestation) ToCanonicalSymbol(symbol string, exchange string) (*adapter.CanonicalSymbol, error) {
+	if root, found := strings.CutPrefix(symbol, "@"); found && root != "" {
+		return &adapter.CanonicalSymbol{ Root: root, Exchange: strings.ToUpper(exchange) }, nil
+	}
+
+	if cs, ok := adapter.ParseFuturesSymbol(symbol, exchange); ok {
+		return cs, nil
+	}
+
+	return nil, errors.New("not a futures symbol: "+ symbol)
+}
+
 //=============================================================================
 //===
 //=== Private functions
 //===
 //=============================================================================
 
+func (a *tradestation) getAccessToken() string {
+	a.session.RLock()
+	defer a.session.RUnlock()
+
+	return a.session.accessToken
+}
+
+//=============================================================================
+
+func (a *tradestation) setAccessToken(token string) {
+	a.session.Lock()
+	defer a.session.Unlock()
+
+	a.session.accessToken = token
+}
+
+//=============================================================================
+
 func (a *tradestation) doGet(url string, output any) error {
 	res, err := a.doGetWithResponse(url)
 	return req.BuildResponse(res, err, &output)
@@ -468,7 +681,7 @@ func (a *tradestation) doGetWithResponse(url string) (*http.Response, error) {
 		return nil,err
 	}
 
-	rq.Header.Set("Authorization", "Bearer "+ a.accessToken)
+	rq.Header.Set("Authorization", "Bearer "+ a.getAccessToken())
 	rq.Header.Set("Content-Type", "application/json")
 
 	return a.client.Do(rq)
@@ -477,21 +690,49 @@ func (a *tradestation) doGetWithResponse(url string) (*http.Response, error) {
 //=============================================================================
 
 func (a *tradestation) doPost(url string, params any, output any) error {
+	return a.doSend("POST", url, params, output)
+}
+
+//=============================================================================
+
+func (a *tradestation) doPut(url string, params any, output any) error {
+	return a.doSend("PUT", url, params, output)
+}
+
+//=============================================================================
+
+func (a *tradestation) doDelete(url string, output any) error {
+	rq, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		slog.Error("Error creating a DELETE request", "error", err.Error())
+		return err
+	}
+
+	rq.Header.Set("Authorization", "Bearer "+ a.getAccessToken())
+	rq.Header.Set("Content-Type", "application/json")
+
+	res, err := a.client.Do(rq)
+	return req.BuildResponse(res, err, &output)
+}
+
+//=============================================================================
+
+func (a *tradestation) doSend(method string, url string, params any, output any) error {
 	body, err := json.Marshal(&params)
 	if err != nil {
-		slog.Error("Error marshalling POST parameter", "error", err.Error())
+		slog.Error("Error marshalling "+ method +" parameter", "error", err.Error())
 		return err
 	}
 
 	reader := bytes.NewReader(body)
 
-	rq, err := http.NewRequest("POST", url, reader)
+	rq, err := http.NewRequest(method, url, reader)
 	if err != nil {
-		slog.Error("Error creating a POST request", "error", err.Error())
+		slog.Error("Error creating a "+ method +" request", "error", err.Error())
 		return err
 	}
 
-	rq.Header.Set("Authorization", "Bearer "+ a.accessToken)
+	rq.Header.Set("Authorization", "Bearer "+ a.getAccessToken())
 	rq.Header.Set("Content-Type", "application/json")
 
 	res, err := a.client.Do(rq)
@@ -500,6 +741,76 @@ func (a *tradestation) doPost(url string, params any, output any) error {
 
 //=============================================================================
 
+func (a *tradestation) getPriceBarsChunk(symbol string, unit string, interval int, from, to datatype.IntDate) ([]*adapter.PriceBar,bool,error) {
+	//--- Last time set to 23:59:50 (and not 59) as it seems that Tradestation somethimes returns 1 extra bar
+	query := "unit="+ unit +"&interval="+ strconv.Itoa(interval) +"&firstdate="+ from.String() +"T00%3A00%3A00Z&lastdate="+ to.String() +"T23%3A59%3A00Z"
+	apiUrl := a.apiUrl + UrlMarketDataBarcharts +"/"+ symbol +"?"+ query
+
+	var res BarchartsResponse
+	rres,err := a.doGetWithResponse(apiUrl)
+	if err != nil {
+		return nil, false, err
+	}
+
+	//--- Handle special cases
+
+	if rres.StatusCode == http.StatusNotFound {
+		_ = rres.Body.Close()
+		return nil, false, nil
+	}
+
+	if rres.StatusCode == http.StatusGatewayTimeout {
+		_ = rres.Body.Close()
+		return nil, true, nil
+	}
+
+	//--- Read response
+
+	err = req.BuildResponse(rres, err, &res)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var bars []*adapter.PriceBar
+
+	for _,bar := range res.Bars {
+		bars = append(bars, convertBar(&bar))
+	}
+
+	return bars, false, nil
+}
+
+//=============================================================================
+
+func (a *tradestation) getAccounts() ([]*adapter.Account,error) {
+	var res AccountsResponse
+	err := a.doGet(a.apiUrl + UrlBrokerageAccounts, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return convertAccounts(&res), nil
+}
+
+//=============================================================================
+
+func (a *tradestation) findOrder(id string) (*adapter.Order,error) {
+	orders,err := a.GetOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	for _,o := range orders {
+		if o.Id == id {
+			return o, nil
+		}
+	}
+
+	return nil, req.NewNotFoundError("Order not found: %v", id)
+}
+
+//=============================================================================
+
 func convertAccounts(ar *AccountsResponse) []*adapter.Account {
 	var list []*adapter.Account
 
@@ -519,6 +830,223 @@ func convertAccounts(ar *AccountsResponse) []*adapter.Account {
 
 //=============================================================================
 
+func convertOrder(o *Order) *adapter.Order {
+	ao := adapter.Order{
+		Id           : o.OrderID,
+		ClientOrderId: o.OrderConfirmID,
+		Account      : o.AccountID,
+		Type         : fromTsOrderType(o.OrderType),
+		LimitPrice   : toOptFloat64(o.LimitPrice),
+		StopPrice    : toOptFloat64(o.StopPrice),
+		TimeInForce  : fromTsDuration(o.Duration),
+		Status       : fromTsOrderStatus(o.Status),
+		Message      : o.RejectReason,
+		CreatedAt    : toOptTime(o.OpenedDateTime),
+		ClosedAt     : toOptTime(o.ClosedDateTime),
+	}
+
+	if len(o.Legs) > 0 {
+		leg := o.Legs[0]
+		ao.Symbol         = leg.Symbol
+		ao.Side           = adapter.OrderSideBuy
+		ao.Quantity       = toOptFloat64(leg.QuantityOrdered)
+		ao.FilledQuantity = toOptFloat64(leg.ExecQuantity)
+		ao.AveragePrice   = toOptFloat64(o.FilledPrice)
+
+		if strings.HasPrefix(leg.BuyOrSell, "Sell") {
+			ao.Side = adapter.OrderSideSell
+		}
+	}
+
+	//--- Tradestation does not report the single executions of an order, so all of them are merged into one fill
+
+	if ao.FilledQuantity > 0 {
+		ts := time.Now()
+		if ao.ClosedAt != nil {
+			ts = *ao.ClosedAt
+		}
+
+		ao.Fills = []*adapter.Fill{
+			{
+				Id        : o.OrderID,
+				Quantity  : ao.FilledQuantity,
+				Price     : ao.AveragePrice,
+				Commission: toOptFloat64(o.CommissionFee),
+				TimeStamp : ts,
+			},
+		}
+	}
+
+	return &ao
+}
+
+//=============================================================================
+
+func toTsBarUnit(rq *adapter.PriceBarsRequest) (string,error) {
+	switch rq.Unit {
+		case adapter.BarUnitMinute:
+			if rq.Interval > 1440 {
+				return "", req.NewBadRequestError("Interval cannot exceed 1440 minutes")
+			}
+			return "Minute", nil
+
+		case adapter.BarUnitDaily, adapter.BarUnitWeekly:
+			if rq.Interval != 1 {
+				return "", req.NewBadRequestError("Only an interval of 1 is supported for unit: %v", rq.Unit)
+			}
+			if rq.Unit == adapter.BarUnitDaily {
+				return "Daily", nil
+			}
+			return "Weekly", nil
+	}
+
+	return "", req.NewBadRequestError("Bar unit not supported by Tradestation: %v", rq.Unit)
+}
+
+//=============================================================================
+
+func maxDaysPerRequest(rq *adapter.PriceBarsRequest) int {
+	if rq.Unit != adapter.BarUnitMinute {
+		return MaxBarsPerRequest
+	}
+
+	barsPerDay := (24*60 + rq.Interval -1) / rq.Interval
+	return max(1, MaxBarsPerRequest / barsPerDay)
+}
+
+//=============================================================================
+
+func convertBar(bar *Bar) *adapter.PriceBar {
+	return &adapter.PriceBar{
+		TimeStamp   : time.UnixMilli(bar.Epoch),
+		High        : toFloat64(bar.High),
+		Low         : toFloat64(bar.Low),
+		Open        : toFloat64(bar.Open),
+		Close       : toFloat64(bar.Close),
+		UpVolume    : bar.UpVolume,
+		DownVolume  : bar.DownVolume,
+		UpTicks     : bar.UpTicks,
+		DownTicks   : bar.DownTicks,
+		OpenInterest: toInt(bar.OpenInterest),
+	}
+}
+
+//=============================================================================
+
+func convertPosition(p *Position) *adapter.Position {
+	quantity := toOptFloat64(p.Quantity)
+	if p.LongShort == "Short" && quantity > 0 {
+		quantity = -quantity
+	}
+
+	return &adapter.Position{
+		Account             : p.AccountID,
+		Symbol              : p.Symbol,
+		Root                : extractRoot(p.Symbol),
+		Quantity            : quantity,
+		AveragePrice        : toOptFloat64(p.AveragePrice),
+		MarketValue         : toOptFloat64(p.MarketValue),
+		UnrealizedProfitLoss: toOptFloat64(p.UnrealizedProfitLoss),
+		OpenTime            : toOptTime(p.Timestamp),
+	}
+}
+
+//=============================================================================
+
+func fromTsOrderStatus(status string) adapter.OrderStatus {
+	switch status {
+		case "ACK", "DON":
+			return adapter.OrderStatusPending
+		case "LAT":
+			//--- Too late to cancel: the order is still live on the exchange
+			return adapter.OrderStatusWorking
+		case "FPR":
+			return adapter.OrderStatusPartiallyFilled
+		case "FLL":
+			return adapter.OrderStatusFilled
+		case "CAN", "FLP", "OUT", "TSC", "BRO":
+			return adapter.OrderStatusCancelled
+		case "REJ":
+			return adapter.OrderStatusRejected
+		case "EXP":
+			return adapter.OrderStatusExpired
+	}
+
+	return adapter.OrderStatusWorking
+}
+
+//=============================================================================
+
+func fromTsOrderType(orderType string) adapter.OrderType {
+	switch orderType {
+		case "Limit":
+			return adapter.OrderTypeLimit
+		case "StopMarket":
+			return adapter.OrderTypeStop
+		case "StopLimit":
+			return adapter.OrderTypeStopLimit
+	}
+
+	return adapter.OrderTypeMarket
+}
+
+//=============================================================================
+
+func toTsOrderType(orderType adapter.OrderType) string {
+	switch orderType {
+		case adapter.OrderTypeLimit:
+			return "Limit"
+		case adapter.OrderTypeStop:
+			return "StopMarket"
+		case adapter.OrderTypeStopLimit:
+			return "StopLimit"
+	}
+
+	return "Market"
+}
+
+//=============================================================================
+
+func fromTsDuration(duration string) adapter.TimeInForce {
+	switch duration {
+		case "GTC", "GCP":
+			return adapter.TimeInForceGTC
+		case "IOC":
+			return adapter.TimeInForceIOC
+		case "FOK":
+			return adapter.TimeInForceFOK
+	}
+
+	return adapter.TimeInForceDay
+}
+
+//=============================================================================
+
+func toTsDuration(tif adapter.TimeInForce) string {
+	switch tif {
+		case adapter.TimeInForceGTC:
+			return "GTC"
+		case adapter.TimeInForceIOC:
+			return "IOC"
+		case adapter.TimeInForceFOK:
+			return "FOK"
+	}
+
+	return "DAY"
+}
+
+//=============================================================================
+
+func toTsTradeAction(side adapter.OrderSide) string {
+	if side == adapter.OrderSideSell {
+		return "SELL"
+	}
+
+	return "BUY"
+}
+
+//=============================================================================
+
 func toFloat64(value string) float64 {
 	val, err := strconv.ParseFloat(value, 64)
 	if err != nil {
@@ -543,6 +1071,38 @@ func toInt(value string) int {
 
 //=============================================================================
 
+func toOptFloat64(value string) float64 {
+	if value == "" {
+		return 0
+	}
+
+	return toFloat64(value)
+}
+
+//=============================================================================
+
+func toOptTime(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+
+	ts,err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		slog.Warn("Tradestation: Error converting value to time", "value", value)
+		return nil
+	}
+
+	return &ts
+}
+
+//=============================================================================
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+//=============================================================================
+
 func convertExpirationDate(date string) (*time.Time,error) {
 	fIdx := strings.Index(date, "(")
 	lIdx := strings.Index(date, ")")
@@ -605,3 +1165,34 @@ func extractMonth(symbol string) string {
 }
 
 //=============================================================================
+
+func extractRoot(symbol string) string {
+	if extractMonth(symbol) == "" {
+		return strings.TrimPrefix(symbol, "@")
+	}
+
+	return symbol[0 : len(symbol)-3]
+}
+
+//=============================================================================
+
+func (a *tradestation) getApiUrl() string {
+	if a.configParams.LiveAccount {
+		return a.configParams.LiveApiUrl
+	}
+
+	return a.configParams.DemoApiUrl
+}
+
+//=============================================================================
+
+func newClient(cassette *adapter.Cassette) *http.Client {
+	jar,_ := cookiejar.New(nil)
+
+	client := adapter.NewHttpClient(jar, time.Minute * 3)
+	client.Transport = cassette.Wrap(client.Transport)
+
+	return client
+}
+
+//=============================================================================
@@ -0,0 +1,508 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package tradestation_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/adaptertest"
+	"github.com/bit-fever/system-adapter/pkg/adapter/tradestation"
+	"github.com/bit-fever/system-adapter/pkg/adapter/tradestation/tradestationtest"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+
+func TestConformance(t *testing.T) {
+	adaptertest.Run(t, func(t *testing.T) *adaptertest.Setup {
+		return newSetup(t, newServer(t))
+	})
+}
+
+//=============================================================================
+
+func TestConnectWithWrongPassword(t *testing.T) {
+	s := newSetup(t, newServer(t))
+	s.ConnectParams[adapter.ParamPassword] = "wrong"
+
+	ctx,err := adapter.NewConnectionContext("tester", "ts", "localhost", s.Adapter, s.ConfigParams, s.ConnectParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_,err = ctx.Connect()
+	if err == nil {
+		t.Fatalf("login must fail with a wrong password")
+	}
+}
+
+//=============================================================================
+
+func TestRefreshAfterTokenExpiration(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	srv.ExpireTokens()
+
+	if _,err := ctx.GetAccounts(); err == nil {
+		t.Fatalf("API calls must fail once the token has expired")
+	}
+
+	if err := ctx.RefreshToken(); err != nil {
+		t.Fatalf("token refresh failed: %v", err)
+	}
+
+	if _,err := ctx.GetAccounts(); err != nil {
+		t.Fatalf("API calls must work after a refresh: %v", err)
+	}
+}
+
+//=============================================================================
+
+func TestFailedRefreshIsReported(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	srv.Close()
+
+	if err := ctx.RefreshToken(); err == nil {
+		t.Fatalf("a failed refresh must return an error even when it will be retried")
+	}
+
+	if !ctx.IsConnected() {
+		t.Errorf("context must stay connected while refresh retries remain, status is %v", ctx.GetStatus())
+	}
+}
+
+//=============================================================================
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	adapter.InitCassettes(&app.Cassette{ Path: dir })
+	t.Cleanup(func() { adapter.InitCassettes(&app.Cassette{}) })
+
+	//--- Record a session against the fake server
+
+	srv := newServer(t)
+	rec := newSetup(t, srv)
+	rec.ConfigParams[adapter.ParamCassetteMode] = string(adapter.CassetteModeRecord)
+	rec.ConfigParams[adapter.ParamCassetteName] = "session"
+
+	ctx := adaptertest.Connect(t, rec)
+	recInst,err1 := ctx.GetInstruments(rec.Fixture.Root)
+	recBars,err2 := ctx.GetPriceBars(rec.Fixture.Bars)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("recording failed: %v, %v", err1, err2)
+	}
+
+	srv.Close()
+
+	//--- Secrets must not reach the disk
+
+	data,err := os.ReadFile(filepath.Join(dir, adaptertest.TestUsername, adaptertest.TestConnection, "session.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//--- One interaction per line
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var i adapter.Interaction
+		if err = json.Unmarshal([]byte(line), &i); err != nil || i.Request.Method == "" {
+			t.Fatalf("bad cassette line: %s (error: %v)", line, err)
+		}
+	}
+
+	for _, secret := range []string{ `"`+ tradestationtest.Password +`"`, "code="+ tradestationtest.TwoFACode, "access-1", "wresult=R1", `\"R1\"` } {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("the cassette contains a secret: %s", secret)
+		}
+	}
+
+	//--- Replay the same session without the server
+
+	rep := newSetup(t, srv)
+	rep.ConfigParams[adapter.ParamCassetteMode] = string(adapter.CassetteModeReplay)
+	rep.ConfigParams[adapter.ParamCassetteName] = "session"
+
+	ctx  = adaptertest.Connect(t, rep)
+	repInst,err1 := ctx.GetInstruments(rep.Fixture.Root)
+	repBars,err2 := ctx.GetPriceBars(rep.Fixture.Bars)
+	if err1 != nil || err2 != nil {
+		t.Fatalf("replay failed: %v, %v", err1, err2)
+	}
+
+	if len(repInst) != len(recInst) || len(repBars.Bars) != len(recBars.Bars) {
+		t.Errorf("replay differs from recording: %d/%d instruments, %d/%d bars", len(repInst), len(recInst), len(repBars.Bars), len(recBars.Bars))
+	}
+
+	//--- Requests that were not recorded fail
+
+	if _,err = ctx.GetPositions(); err == nil {
+		t.Errorf("a request missing from the cassette must fail")
+	}
+
+	//--- Another user cannot replay the session
+
+	other,err := adapter.NewConnectionContext("intruder", adaptertest.TestConnection, adaptertest.TestHost, rep.Adapter, rep.ConfigParams, rep.ConnectParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _,err = other.Connect(); err == nil {
+		t.Errorf("a cassette must not be readable by another user")
+	}
+}
+
+//=============================================================================
+
+func TestBarCache(t *testing.T) {
+	adapter.InitBarCache(&app.BarCache{ Path: t.TempDir() })
+	t.Cleanup(func() { adapter.InitBarCache(&app.BarCache{}) })
+
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	//--- The missing days are asked with one request and all of them are cached,
+	//--- including the empty ones
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250103)
+	rq.From = 20250101
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 15 {
+		t.Fatalf("expected 15 bars, got %v (error: %v)", pb, err)
+	}
+
+	if n := srv.BarchartRequests(); n != 1 {
+		t.Fatalf("expected 1 request for consecutive missing days, got %d", n)
+	}
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 15 || srv.BarchartRequests() != 1 {
+		t.Fatalf("expected 15 bars from the cache, got %v (requests: %d, error: %v)", pb, srv.BarchartRequests(), err)
+	}
+
+	//--- Only the days not cached yet are asked (a saturday, without bars)
+
+	rq.To = 20250104
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 15 || srv.BarchartRequests() != 2 {
+		t.Fatalf("expected 15 bars and 1 more request, got %v (requests: %d, error: %v)", pb, srv.BarchartRequests(), err)
+	}
+}
+
+//=============================================================================
+
+func TestPriceBarsInChunks(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	//--- 1 minute bars are asked 40 days at a time: 2 requests, 43 weekdays
+
+	rq := adapter.NewPriceBarsRequest("ESH25", 20250301)
+	rq.From = 20250101
+
+	pb,err := ctx.GetPriceBars(rq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := srv.BarchartRequests(); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+
+	if len(pb.Bars) != 43*5 {
+		t.Fatalf("expected %d bars, got %d", 43*5, len(pb.Bars))
+	}
+
+	for i,b := range pb.Bars {
+		if i > 0 && !b.TimeStamp.After(pb.Bars[i-1].TimeStamp) {
+			t.Fatalf("bars are not in order or repeated at %v", b.TimeStamp)
+		}
+	}
+
+	//--- Daily bars fit in one request
+
+	rq.Unit = adapter.BarUnitDaily
+
+	pb,err = ctx.GetPriceBars(rq)
+	if err != nil || len(pb.Bars) != 43 || srv.BarchartRequests() != 3 {
+		t.Fatalf("expected 43 daily bars in 1 more request, got %v (requests: %d, error: %v)", pb, srv.BarchartRequests(), err)
+	}
+}
+
+//=============================================================================
+
+func TestModifyOrder(t *testing.T) {
+	s   := newSetup(t, newServer(t))
+	ctx := adaptertest.Connect(t, s)
+
+	po,err := ctx.PlaceOrder(s.Fixture.NewOrder)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	mo,err := ctx.ModifyOrder(po.Id, &adapter.OrderChange{ Quantity: 2, LimitPrice: 5010 })
+	if err != nil {
+		t.Fatalf("ModifyOrder failed: %v", err)
+	}
+
+	if mo.Id != po.Id || mo.Quantity != 2 || mo.LimitPrice != 5010 {
+		t.Errorf("order not modified: %+v", mo)
+	}
+
+	if _,err = ctx.ModifyOrder("9999", &adapter.OrderChange{ LimitPrice: 5010 }); err == nil {
+		t.Errorf("modifying an unknown order must fail")
+	}
+}
+
+//=============================================================================
+
+func TestClientOrderIdRoundTrip(t *testing.T) {
+	s   := newSetup(t, newServer(t))
+	ctx := adaptertest.Connect(t, s)
+
+	no := *s.Fixture.NewOrder
+	no.ClientOrderId = "strategy-42"
+
+	po,err := ctx.PlaceOrder(&no)
+	if err != nil {
+		t.Fatalf("PlaceOrder failed: %v", err)
+	}
+
+	list,err := ctx.GetOrders()
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	for _,o := range list {
+		if o.Id == po.Id {
+			if o.ClientOrderId != no.ClientOrderId {
+				t.Errorf("expected client order id %s, got %s", no.ClientOrderId, o.ClientOrderId)
+			}
+			return
+		}
+	}
+
+	t.Errorf("placed order %v not returned by GetOrders", po.Id)
+}
+
+//=============================================================================
+
+func TestSymbolMapping(t *testing.T) {
+	mapper := tradestation.NewAdapter().(adapter.SymbolMapper)
+
+	for canonical, expected := range map[string]string{
+		"ES:CME:202503": "ESH25",
+		"ES:CME"       : "@ES",
+	} {
+		cs, err := adapter.ParseCanonicalSymbol(canonical)
+		if err != nil {
+			t.Fatalf("cannot parse %s: %v", canonical, err)
+		}
+
+		symbol, err := mapper.ToAdapterSymbol(cs)
+		if err != nil || symbol != expected {
+			t.Fatalf("%s: expected %s, got %s (%v)", canonical, expected, symbol, err)
+		}
+
+		back, err := mapper.ToCanonicalSymbol(symbol, "cme")
+		if err != nil || back.String() != canonical {
+			t.Fatalf("%s: round trip gave %v (%v)", symbol, back, err)
+		}
+	}
+
+	if _, err := mapper.ToCanonicalSymbol("AAPL", "NASDAQ"); err == nil {
+		t.Fatalf("expected an error for a non futures symbol")
+	}
+}
+
+//=============================================================================
+
+func TestQuoteStream(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	c1 := subscribeStream(t, ctx, adapter.StreamTypeQuotes)
+	c2 := subscribeStream(t, ctx, adapter.StreamTypeQuotes)
+
+	waitForStreams(t, srv, adapter.StreamTypeQuotes, 1)
+
+	//--- Both clients get the same events. Fields that did not change are kept
+
+	for _, sc := range []*adapter.StreamClient{ c1, c2 } {
+		var q *adapter.Quote
+		for range 3 {
+			e := nextEvent(t, sc)
+			q  = e.Quote
+		}
+
+		if q.Bid != 5899.75 || q.Ask != 5900.25 || q.Last < 5902 {
+			t.Errorf("unexpected quote: %+v", q)
+		}
+	}
+
+	c1.Close()
+	c2.Close()
+
+	waitForStreams(t, srv, adapter.StreamTypeQuotes, 0)
+}
+
+//=============================================================================
+
+func TestBarStream(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	sc := subscribeStream(t, ctx, adapter.StreamTypeBars)
+
+	for i := range 3 {
+		e := nextEvent(t, sc)
+		if e.Bar == nil || e.Bar.Open != 5900 + float64(i) || e.Closed != (i == 2) {
+			t.Errorf("unexpected bar event %d: %+v (bar: %+v)", i, e, e.Bar)
+		}
+	}
+
+	//--- Disconnecting drops the streams and closes the clients
+
+	_ = ctx.Disconnect()
+
+	waitForStreams(t, srv, adapter.StreamTypeBars, 0)
+
+	for range sc.Events {
+		//--- Drains the events received before the disconnection
+	}
+}
+
+//=============================================================================
+
+func TestStreamErrors(t *testing.T) {
+	srv := newServer(t)
+	ctx := adaptertest.Connect(t, newSetup(t, srv))
+
+	if _,err := ctx.Subscribe("ESH25", "trades"); err == nil {
+		t.Errorf("unknown stream types must be rejected")
+	}
+
+	a := tradestation.NewAdapter().Clone(srv.ConfigParams(), srv.ConnectParams())
+	if err := a.Unsubscribe("ESH25", adapter.StreamTypeQuotes); err == nil {
+		t.Errorf("unsubscribing from a stream never opened must fail")
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func newServer(t *testing.T) *tradestationtest.Server {
+	srv := tradestationtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+//=============================================================================
+
+func newSetup(t *testing.T, srv *tradestationtest.Server) *adaptertest.Setup {
+	return &adaptertest.Setup{
+		Adapter      : tradestation.NewAdapter(),
+		ConfigParams : srv.ConfigParams(),
+		ConnectParams: srv.ConnectParams(),
+		Fixture: adaptertest.Fixture{
+			Root      : "ES",
+			Instrument: "ESH25",
+			Bars      : adapter.NewPriceBarsRequest("ESH25", 20250102),
+			MinBars   : 3,
+			Accounts  : []string{ tradestationtest.Account },
+			Orders    : []string{ "1001", "1002" },
+			Positions : []string{ "ESH25" },
+			NewOrder  : &adapter.Order{
+				Account   : tradestationtest.Account,
+				Symbol    : "ESH25",
+				Side      : adapter.OrderSideBuy,
+				Quantity  : 1,
+				Type      : adapter.OrderTypeLimit,
+				LimitPrice: 5000,
+			},
+		},
+	}
+}
+
+//=============================================================================
+
+func subscribeStream(t *testing.T, ctx *adapter.ConnectionContext, st adapter.StreamType) *adapter.StreamClient {
+	t.Helper()
+
+	sc,err := ctx.Subscribe("ESH25", st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sc
+}
+
+//=============================================================================
+
+func nextEvent(t *testing.T, sc *adapter.StreamClient) *adapter.StreamEvent {
+	t.Helper()
+
+	select {
+		case e,ok := <-sc.Events:
+			if !ok {
+				t.Fatal("the stream was closed")
+			}
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no stream event received")
+			return nil
+	}
+}
+
+//=============================================================================
+
+func waitForStreams(t *testing.T, srv *tradestationtest.Server, st adapter.StreamType, expected int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for srv.OpenStreams(st) != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d open %s streams, got %d", expected, st, srv.OpenStreams(st))
+		}
+		time.Sleep(tradestationtest.StreamPeriod)
+	}
+}
+
+//=============================================================================
@@ -28,12 +28,19 @@ package tradestation
 
 const (
 	UrlBrokerageAccounts  = "/v3/brokerage/accounts"
+	UrlOrderExecOrders    = "/v3/orderexecution/orders"
 	UrlMarketDataSymbols  = "/v3/marketdata/symbols"
 	UrlMarketDataBarcharts= "/v3/marketdata/barcharts"
 	UrlSymbolsSearch      = "/v2/data/symbols/search"
 	UrlSymbolsSuggest     = "/v2/data/symbols/suggest"
+	UrlStreamQuotes       = "/v3/marketdata/stream/quotes"
+	UrlStreamBarcharts    = "/v3/marketdata/stream/barcharts"
 )
 
+//=============================================================================
+
+const MaxBarsPerRequest = 57600
+
 //=============================================================================
 //=== Service: /v3/brokerage/accounts
 //=============================================================================
@@ -117,6 +124,133 @@ type CurrencyDetail struct {
 	AccountConversionRate string
 }
 
+//=============================================================================
+//=== Service: /v3/brokerage/accounts/XXX/orders
+//=============================================================================
+
+type OrdersResponse struct {
+	Orders    []Order
+	Errors    []OrderError
+	NextToken string
+}
+
+//=============================================================================
+
+type Order struct {
+	AccountID         string
+	OrderID           string
+	Status            string
+	StatusDescription string
+	OrderType         string
+	Duration          string
+	LimitPrice        string
+	StopPrice         string
+	FilledPrice       string
+	CommissionFee     string
+	OpenedDateTime    string
+	ClosedDateTime    string
+	RejectReason      string
+	OrderConfirmID    string
+	Legs              []OrderLeg
+}
+
+//=============================================================================
+
+type OrderLeg struct {
+	BuyOrSell         string
+	Symbol            string
+	QuantityOrdered   string
+	ExecQuantity      string
+	QuantityRemaining string
+	ExecutionPrice    string
+	OpenOrClose       string
+}
+
+//=============================================================================
+
+type OrderError struct {
+	AccountID string
+	OrderID   string
+	Error     string
+	Message   string
+}
+
+//=============================================================================
+//=== Service: /v3/brokerage/accounts/XXX/positions
+//=============================================================================
+
+type PositionsResponse struct {
+	Positions []Position
+	Errors    []interface{}
+}
+
+//=============================================================================
+
+type Position struct {
+	AccountID            string
+	PositionID           string
+	AssetType            string
+	Symbol               string
+	LongShort            string
+	Quantity             string
+	AveragePrice         string
+	Last                 string
+	MarketValue          string
+	TotalCost            string
+	TodaysProfitLoss     string
+	UnrealizedProfitLoss string
+	InitialRequirement   string
+	MaintenanceMargin    string
+	ExpirationDate       string
+	Timestamp            string
+}
+
+//=============================================================================
+//=== Service: /v3/orderexecution/orders
+//=============================================================================
+
+type OrderRequest struct {
+	AccountID      string           `json:"AccountID"`
+	Symbol         string           `json:"Symbol"`
+	Quantity       string           `json:"Quantity"`
+	OrderType      string           `json:"OrderType"`
+	TradeAction    string           `json:"TradeAction"`
+	LimitPrice     string           `json:"LimitPrice,omitempty"`
+	StopPrice      string           `json:"StopPrice,omitempty"`
+	TimeInForce    OrderTimeInForce `json:"TimeInForce"`
+	Route          string           `json:"Route"`
+	OrderConfirmID string           `json:"OrderConfirmID,omitempty"` // client order id, returned with the order
+}
+
+//=============================================================================
+
+type OrderTimeInForce struct {
+	Duration string `json:"Duration"`
+}
+
+//=============================================================================
+
+type OrderReplaceRequest struct {
+	Quantity   string `json:"Quantity,omitempty"`
+	LimitPrice string `json:"LimitPrice,omitempty"`
+	StopPrice  string `json:"StopPrice,omitempty"`
+}
+
+//=============================================================================
+
+type OrderResponse struct {
+	Orders []OrderResult
+	Errors []OrderError
+}
+
+//=============================================================================
+
+type OrderResult struct {
+	OrderID string
+	Message string
+	Error   string
+}
+
 //=============================================================================
 //=== Service: /v2/data/symbols/search/XXX
 //=============================================================================
@@ -231,3 +365,37 @@ type Bar struct {
 }
 
 //=============================================================================
+//=== Service: /v3/marketdata/stream/XXX
+//=============================================================================
+
+type StreamStatus struct {
+	Heartbeat int
+	Timestamp string
+	Error     string
+	Message   string
+}
+
+//=============================================================================
+
+type StreamQuote struct {
+	StreamStatus
+	Symbol    string
+	Ask       string
+	AskSize   string
+	Bid       string
+	BidSize   string
+	Last      string
+	Volume    string
+	TradeTime string
+}
+
+//=============================================================================
+
+type StreamBar struct {
+	StreamStatus
+	Bar
+	BarStatus  string
+	IsRealtime bool
+}
+
+//=============================================================================
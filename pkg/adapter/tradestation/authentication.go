@@ -104,6 +104,15 @@ type TokenRefreshResponse struct {
 	Expiry       int    `json:"expiry"`
 }
 
+//=============================================================================
+//--- What is persisted across restarts: the refresh endpoint authenticates
+//--- through the session cookies, so they are saved along with the id token
+
+type SessionToken struct {
+	IdToken string         `json:"idToken"`
+	Cookies []*http.Cookie `json:"cookies"`
+}
+
 //=============================================================================
 //===
 //=== Methods
@@ -111,7 +120,7 @@ type TokenRefreshResponse struct {
 //=============================================================================
 
 func (a *tradestation) createLoginInfo() (*LoginInfo, error){
-	rq, err := http.NewRequest("GET", LoginPageUrl, nil)
+	rq, err := http.NewRequest("GET", a.configParams.PortalUrl + LoginPagePath, nil)
 
 	if err != nil {
 		slog.Error("createLoginInfo: Error creating a GET request", "error", err.Error())
@@ -133,6 +142,10 @@ func (a *tradestation) createLoginInfo() (*LoginInfo, error){
 	rq.Header = h
 
 	res, err := a.client.Do(rq)
+	if err != nil {
+		slog.Error("createLoginInfo: Error getting the login page", "error", err.Error())
+		return nil, err
+	}
 
 	defer res.Body.Close()
 	doc, err := html.Parse(res.Body)
@@ -209,15 +222,19 @@ func (a *tradestation) login(info *LoginInfo) (*LoginResult, error) {
 
 	reader := bytes.NewReader(body)
 
-	rq, err := http.NewRequest("POST", LoginPostUrl, reader)
+	rq, err := http.NewRequest("POST", a.configParams.SigninUrl + LoginPostPath, reader)
 	if err != nil {
 		slog.Error("login: Error creating a POST request", "error", err.Error())
 		return nil,err
 	}
 
 	rq.Header = *a.header
-	setupHeader(&rq.Header)
+	setupHeader(&rq.Header, a.configParams.SigninUrl)
 	res, err := a.client.Do(rq)
+	if err != nil {
+		slog.Error("login: Error sending the request", "error", err.Error())
+		return nil, err
+	}
 
 	defer res.Body.Close()
 	doc, err := html.Parse(res.Body)
@@ -245,17 +262,21 @@ func (a *tradestation) callCallback(lr *LoginResult) (string,error) {
 	params.Set("wctx"   , lr.Wctx)
 	payload := bytes.NewBufferString(params.Encode())
 
-	rq, err := http.NewRequest("POST", LoginCallbackUrl, payload)
+	rq, err := http.NewRequest("POST", a.configParams.SigninUrl + LoginCallbackPath, payload)
 	if err != nil {
 		slog.Error("callCallback: Error creating a POST request", "error", err.Error())
 		return "",err
 	}
 
 	rq.Header = *a.header
-	setupHeader(&rq.Header)
+	setupHeader(&rq.Header, a.configParams.SigninUrl)
 	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	res, err := a.client.Do(rq)
+	if err != nil {
+		slog.Error("callCallback: Error sending the request", "error", err.Error())
+		return "", err
+	}
 
 	defer res.Body.Close()
 	doc, err := html.Parse(res.Body)
@@ -282,7 +303,7 @@ func (a *tradestation) submitTwoFACode(state string) error {
 	params.Set("action", "default")
 	payload := bytes.NewBufferString(params.Encode())
 
-	rq, err := http.NewRequest("POST", LoginTwoFAUrl+"?state="+state, payload)
+	rq, err := http.NewRequest("POST", a.configParams.SigninUrl + LoginTwoFAPath +"?state="+ url.QueryEscape(state), payload)
 	if err != nil {
 		slog.Error("submitTwoFACode: Error creating a POST request", "error", err.Error())
 		return err
@@ -294,6 +315,10 @@ func (a *tradestation) submitTwoFACode(state string) error {
 	rq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 	res, err := a.client.Do(rq)
+	if err != nil {
+		slog.Error("submitTwoFACode: Error sending the request", "error", err.Error())
+		return err
+	}
 
 	defer res.Body.Close()
 	doc, err := html.Parse(res.Body)
@@ -307,7 +332,7 @@ func (a *tradestation) submitTwoFACode(state string) error {
 		return errors.New("Didn't get the dashboard page")
 	}
 
-	a.accessToken  = res.Header.Get("X-Authorization")
+	a.setAccessToken(res.Header.Get("X-Authorization"))
 	a.refreshToken = res.Header.Get("X-Id-Token")
 
 	return nil
@@ -336,18 +361,33 @@ func (a *tradestation) testToken() error {
 
 func retrieveConfigParams(values map[string]any) *ConfigParams {
 	return &ConfigParams{
-		ClientId    : values[ParamClientId]   .(string),
-		LiveAccount : values[ParamLiveAccount].(bool),
+		ClientId    : paramClientId   .GetString(values),
+		LiveAccount : paramLiveAccount.GetBool  (values),
+		LiveApiUrl  : retrieveUrl(paramLiveApiUrl, values),
+		DemoApiUrl  : retrieveUrl(paramDemoApiUrl, values),
+		PortalUrl   : retrieveUrl(paramPortalUrl,  values),
+		SigninUrl   : retrieveUrl(paramSigninUrl,  values),
+	}
+}
+
+//=============================================================================
+
+func retrieveUrl(p *adapter.ParamDef, values map[string]any) string {
+	value := strings.TrimSuffix(p.GetString(values), "/")
+	if value == "" {
+		return p.DefValue
 	}
+
+	return value
 }
 
 //=============================================================================
 
 func retrieveConnectParams(values map[string]any) *ConnectParams {
 	return &ConnectParams{
-		Username  : values[adapter.ParamUsername] .(string),
-		Password  : values[adapter.ParamPassword] .(string),
-		TwoFACode : values[adapter.ParamTwoFACode].(string),
+		Username  : paramUsername .GetString(values),
+		Password  : paramPassword .GetString(values),
+		TwoFACode : paramTwoFACode.GetString(values),
 	}
 }
 
@@ -420,10 +460,10 @@ func getAttributeValue(node *html.Node, name string) string {
 
 //=============================================================================
 
-func setupHeader(h *http.Header) {
+func setupHeader(h *http.Header, origin string) {
 	h.Set("Accept",         "*/*")
 	h.Add("Priority",       "u=0, i")
-	h.Set("Origin",         "https://signin.tradestation.com")
+	h.Set("Origin",         origin)
 	h.Add("Sec-Fetch-Dest", "empty")
 	h.Add("Sec-Fetch-Mode", "cors")
 	h.Add("Sec-Fetch-Site", "same-origin")
@@ -25,8 +25,11 @@ THE SOFTWARE.
 package tradestation
 
 import (
+	"context"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"net/http"
+	"net/url"
+	"sync"
 )
 
 //=============================================================================
@@ -35,76 +38,171 @@ import (
 const (
 	ParamClientId    = "clientId"
 	ParamLiveAccount = "liveAccount"
+	ParamLiveApiUrl  = "liveApiUrl"
+	ParamDemoApiUrl  = "demoApiUrl"
+	ParamPortalUrl   = "portalUrl"
+	ParamSigninUrl   = "signinUrl"
 )
 
 //=============================================================================
+//--- Default base URLs. They can be overridden through the config params
+//--- (for example to point the adapter to a fake server)
 
 const (
-	LiveAPI = "https://api.tradestation.com"
-	DemoAPI = "https://sim-api.tradestation.com"
+	LiveAPI   = "https://api.tradestation.com"
+	DemoAPI   = "https://sim-api.tradestation.com"
+	PortalUrl = "https://my.tradestation.com"
+	SigninUrl = "https://signin.tradestation.com"
+)
 
-	LoginPageUrl       = "https://my.tradestation.com/api/auth/login?returnTo=%2F"
-	LoginPostUrl       = "https://signin.tradestation.com/usernamepassword/login"
-	LoginCallbackUrl   = "https://signin.tradestation.com/login/callback"
-	LoginTwoFAUrl      = "https://signin.tradestation.com/u/mfa-otp-challenge"
-	LoginTwoFAPath     = "/u/mfa-otp-challenge"
+//-----------------------------------------------------------------------------
+//--- Paths relative to the portal (my.tradestation.com)
+
+const (
+	LoginPagePath      = "/api/auth/login?returnTo=%2F"
 	LoginDashboardPath = "/dashboard"
-	RefreshTokenUrl    = "https://my.tradestation.com/api/auth/token"
+	RefreshTokenPath   = "/api/auth/token"
+)
+
+//-----------------------------------------------------------------------------
+//--- Paths relative to the signin server (signin.tradestation.com)
+
+const (
+	LoginAuthorizePath = "/authorize"
+	LoginPostPath      = "/usernamepassword/login"
+	LoginCallbackPath  = "/login/callback"
+	LoginTwoFAPath     = "/u/mfa-otp-challenge"
 )
 
 //=============================================================================
 
+var paramClientId = &adapter.ParamDef{
+	Name     : ParamClientId,
+	Type     : adapter.ParamTypeString,
+	DefValue : "TDoILDTVZjp0k5J0xsWbXS1yEUncnj08",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramLiveAccount = &adapter.ParamDef{
+	Name     : ParamLiveAccount,
+	Type     : adapter.ParamTypeBool,
+	DefValue : "false",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 0,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramLiveApiUrl = &adapter.ParamDef{
+	Name     : ParamLiveApiUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : LiveAPI,
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 128,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramDemoApiUrl = &adapter.ParamDef{
+	Name     : ParamDemoApiUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : DemoAPI,
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 128,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramPortalUrl = &adapter.ParamDef{
+	Name     : ParamPortalUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : PortalUrl,
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 128,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramSigninUrl = &adapter.ParamDef{
+	Name     : ParamSigninUrl,
+	Type     : adapter.ParamTypeString,
+	DefValue : SigninUrl,
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 128,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
 var configParams = []*adapter.ParamDef {
-	{
-		Name     : ParamClientId,
-		Type     : adapter.ParamTypeString,
-		DefValue : "TDoILDTVZjp0k5J0xsWbXS1yEUncnj08",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
-	{
-		Name     : ParamLiveAccount,
-		Type     : adapter.ParamTypeBool,
-		DefValue : "false",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 0,
-		GroupName: "",
-	},
+	paramClientId,
+	paramLiveAccount,
+	paramLiveApiUrl,
+	paramDemoApiUrl,
+	paramPortalUrl,
+	paramSigninUrl,
+	adapter.ParamDefCassetteMode,
+	adapter.ParamDefCassetteName,
+}
+
+//-----------------------------------------------------------------------------
+
+var paramUsername = &adapter.ParamDef{
+	Name     : adapter.ParamUsername,
+	Type     : adapter.ParamTypeString,
+	DefValue : "",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+var paramPassword = &adapter.ParamDef{
+	Name     : adapter.ParamPassword,
+	Type     : adapter.ParamTypePassword,
+	DefValue : "",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
+}
+
+//-----------------------------------------------------------------------------
+
+//--- A password, so that it is encrypted in the vault and never returned
+
+var paramTwoFACode = &adapter.ParamDef{
+	Name     : adapter.ParamTwoFACode,
+	Type     : adapter.ParamTypePassword,
+	DefValue : "",
+	Nullable : false,
+	MinValue : 0,
+	MaxValue : 64,
+	GroupName: "",
 }
 
 //-----------------------------------------------------------------------------
 
 var connectParams = []*adapter.ParamDef {
-	{
-		Name     : adapter.ParamUsername,
-		Type     : adapter.ParamTypeString,
-		DefValue : "",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
-	{
-		Name     : adapter.ParamPassword,
-		Type     : adapter.ParamTypePassword,
-		DefValue : "",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
-	{
-		Name     : adapter.ParamTwoFACode,
-		Type     : adapter.ParamTypeString,
-		DefValue : "",
-		Nullable : false,
-		MinValue : 0,
-		MaxValue : 64,
-		GroupName: "",
-	},
+	paramUsername,
+	paramPassword,
+	paramTwoFACode,
 }
 
 //-----------------------------------------------------------------------------
@@ -118,6 +216,11 @@ var info = adapter.Info{
 	SupportsBroker      : true,
 	SupportsMultipleData: false,
 	SupportsInventory   : true,
+	SharedCatalog       : true,
+	SharedData          : true,
+	//--- No automatic reconnection: every login needs a new 2FA code, so replaying
+	//--- the stored one cannot succeed and could lock the account
+	Reconnect           : adapter.ReconnectPolicy{},
 }
 
 //=============================================================================
@@ -125,6 +228,17 @@ var info = adapter.Info{
 type ConfigParams struct {
 	ClientId    string
 	LiveAccount bool
+	LiveApiUrl  string
+	DemoApiUrl  string
+	PortalUrl   string
+	SigninUrl   string
+}
+
+//-----------------------------------------------------------------------------
+
+func (p *ConfigParams) refreshTokenUrl() *url.URL {
+	u,_ := url.Parse(p.PortalUrl + RefreshTokenPath)
+	return u
 }
 
 //=============================================================================
@@ -135,6 +249,14 @@ type ConnectParams struct {
 	TwoFACode string
 }
 
+//-----------------------------------------------------------------------------
+//--- Password and 2FA code are needed only during login
+
+func (p *ConnectParams) clearSecrets() {
+	p.Password  = ""
+	p.TwoFACode = ""
+}
+
 //=============================================================================
 
 type tradestation struct {
@@ -142,10 +264,28 @@ type tradestation struct {
 	connectParams *ConnectParams
 	client        *http.Client
 	header        *http.Header
-	accessToken    string
+	session        *session
 	refreshToken   string
 	clientId       string
 	apiUrl         string
+	streams        *streams
+	cassette       *adapter.Cassette
+}
+
+//=============================================================================
+//--- The access token is also read by the stream goroutines, that run outside
+//--- the connection context lock
+
+type session struct {
+	sync.RWMutex
+	accessToken string
+}
+
+//=============================================================================
+
+type streams struct {
+	sync.Mutex
+	cancels map[string]context.CancelFunc
 }
 
 //=============================================================================
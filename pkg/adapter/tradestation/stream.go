@@ -0,0 +1,261 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+package tradestation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+
+const (
+	StreamRetryDelay  = time.Second * 5
+	StreamContentType = "application/vnd.tradestation.streams.v2+json"
+)
+
+//=============================================================================
+//===
+//=== Streaming services
+//===
+//=============================================================================
+
+func (a *tradestation) Subscribe(symbol string, st adapter.StreamType, handler adapter.StreamHandler) error {
+	var apiUrl string
+
+	switch st {
+		case adapter.StreamTypeQuotes:
+			apiUrl = a.apiUrl + UrlStreamQuotes +"/"+ symbol
+		case adapter.StreamTypeBars:
+			apiUrl = a.apiUrl + UrlStreamBarcharts +"/"+ symbol +"?unit=Minute&interval=1&barsback=1"
+		default:
+			return errors.New("Unknown stream type: "+ string(st))
+	}
+
+	a.streams.Lock()
+	defer a.streams.Unlock()
+
+	key := string(st) +":"+ symbol
+	if _,ok := a.streams.cancels[key]; ok {
+		return errors.New("Already subscribed to stream: "+ key)
+	}
+
+	ctx,cancel := context.WithCancel(context.Background())
+	a.streams.cancels[key] = cancel
+
+	go a.runStream(ctx, apiUrl, symbol, st, handler)
+
+	return nil
+}
+
+//=============================================================================
+
+func (a *tradestation) Unsubscribe(symbol string, st adapter.StreamType) error {
+	a.streams.Lock()
+	defer a.streams.Unlock()
+
+	key := string(st) +":"+ symbol
+	cancel,ok := a.streams.cancels[key]
+	if !ok {
+		return errors.New("Not subscribed to stream: "+ key)
+	}
+
+	cancel()
+	delete(a.streams.cancels, key)
+
+	return nil
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func (a *tradestation) stopStreams() {
+	a.streams.Lock()
+	defer a.streams.Unlock()
+
+	for key,cancel := range a.streams.cancels {
+		cancel()
+		delete(a.streams.cancels, key)
+	}
+}
+
+//=============================================================================
+
+func (a *tradestation) runStream(ctx context.Context, url string, symbol string, st adapter.StreamType, handler adapter.StreamHandler) {
+	for {
+		err := a.readStream(ctx, url, symbol, st, handler)
+		if ctx.Err() != nil {
+			return
+		}
+
+		slog.Warn("Tradestation: Stream interrupted. Reconnecting...", "symbol", symbol, "type", st, "error", err)
+
+		select {
+			case <-ctx.Done():
+				return
+			case <-time.After(StreamRetryDelay):
+		}
+	}
+}
+
+//=============================================================================
+
+func (a *tradestation) readStream(ctx context.Context, url string, symbol string, st adapter.StreamType, handler adapter.StreamHandler) error {
+	rq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	rq.Header.Set("Authorization", "Bearer "+ a.getAccessToken())
+	rq.Header.Set("Accept", StreamContentType)
+
+	//--- Streams are long lived: we cannot use the client's timeout
+
+	client := &http.Client{
+		Jar      : a.client.Jar,
+		Transport: a.client.Transport,
+	}
+
+	res, err := client.Do(rq)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return errors.New("Bad stream response: "+ res.Status)
+	}
+
+	decoder := json.NewDecoder(res.Body)
+
+	if st == adapter.StreamTypeQuotes {
+		return readQuotes(decoder, symbol, handler)
+	}
+
+	return readBars(decoder, symbol, handler)
+}
+
+//=============================================================================
+//===
+//=== Functions
+//===
+//=============================================================================
+
+func readQuotes(decoder *json.Decoder, symbol string, handler adapter.StreamHandler) error {
+	//--- Tradestation sends only the fields that changed, so we keep the full quote here
+
+	quote := adapter.Quote{}
+
+	for {
+		var sq StreamQuote
+		err := decoder.Decode(&sq)
+		if err != nil {
+			return err
+		}
+
+		if sq.Error != "" {
+			return errors.New(sq.Error +": "+ sq.Message)
+		}
+
+		if sq.Heartbeat != 0 {
+			continue
+		}
+
+		mergeQuote(&quote, &sq)
+
+		q := quote
+		handler(&adapter.StreamEvent{
+			Symbol: symbol,
+			Type  : adapter.StreamTypeQuotes,
+			Quote : &q,
+		})
+	}
+}
+
+//=============================================================================
+
+func readBars(decoder *json.Decoder, symbol string, handler adapter.StreamHandler) error {
+	for {
+		var sb StreamBar
+		err := decoder.Decode(&sb)
+		if err != nil {
+			return err
+		}
+
+		if sb.Error != "" {
+			return errors.New(sb.Error +": "+ sb.Message)
+		}
+
+		if sb.Heartbeat != 0 {
+			continue
+		}
+
+		handler(&adapter.StreamEvent{
+			Symbol: symbol,
+			Type  : adapter.StreamTypeBars,
+			Bar   : convertBar(&sb.Bar),
+			Closed: sb.BarStatus == "Closed",
+		})
+	}
+}
+
+//=============================================================================
+
+func mergeQuote(q *adapter.Quote, sq *StreamQuote) {
+	if sq.Bid != "" {
+		q.Bid = toFloat64(sq.Bid)
+	}
+	if sq.Ask != "" {
+		q.Ask = toFloat64(sq.Ask)
+	}
+	if sq.Last != "" {
+		q.Last = toFloat64(sq.Last)
+	}
+	if sq.BidSize != "" {
+		q.BidSize,_ = strconv.Atoi(sq.BidSize)
+	}
+	if sq.AskSize != "" {
+		q.AskSize,_ = strconv.Atoi(sq.AskSize)
+	}
+	if sq.Volume != "" {
+		q.Volume,_ = strconv.Atoi(sq.Volume)
+	}
+	if ts := toOptTime(sq.TradeTime); ts != nil {
+		q.TimeStamp = *ts
+	}
+}
+
+//=============================================================================
@@ -0,0 +1,710 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package tradestationtest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/adaptertest"
+	"github.com/bit-fever/system-adapter/pkg/adapter/tradestation"
+)
+
+//=============================================================================
+//===
+//=== In-memory Tradestation server. It emulates the Auth0 login flow (login
+//=== page, username/password, callback, 2FA challenge), the token refresh and
+//=== the subset of the REST API used by the adapter, so that the whole flow
+//=== can be tested without network.
+//===
+//=== A single server plays the portal, the signin server and the API: point
+//=== the adapter to it through ConfigParams().
+//===
+//=============================================================================
+
+const (
+	Username  = "tester"
+	Password  = "secret"
+	TwoFACode = "123456"
+	Account   = "SIM123F"
+
+	StreamPeriod = 10 * time.Millisecond   // delay between two events of a stream
+
+	sessionCookie = "appSession"
+	loginState    = "S1"
+	twoFAState    = "S2"
+	csrf          = "csrf"
+)
+
+//=============================================================================
+
+type Server struct {
+	*httptest.Server
+	sync.Mutex
+	tokens map[string]bool
+	nextId int
+	orders map[string]*tradestation.Order
+	charts int
+	open   map[string]int
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func NewServer() *Server {
+	s := &Server{
+		tokens: map[string]bool{},
+		nextId: 2000,
+		open  : map[string]int{},
+		orders: map[string]*tradestation.Order{
+			"1001": newOrder("1001", "FLL", "Market", ""),
+			"1002": newOrder("1002", "ACK", "Limit",  "4900"),
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	//--- Login & session
+
+	mux.HandleFunc("GET /api/auth/login",                       s.loginPage)
+	mux.HandleFunc("GET "+  tradestation.LoginAuthorizePath,    s.authorize)
+	mux.HandleFunc("POST "+ tradestation.LoginPostPath,         s.usernamePassword)
+	mux.HandleFunc("POST "+ tradestation.LoginCallbackPath,     s.callback)
+	mux.HandleFunc("GET "+  tradestation.LoginTwoFAPath,        s.twoFAPage)
+	mux.HandleFunc("POST "+ tradestation.LoginTwoFAPath,        s.twoFASubmit)
+	mux.HandleFunc("GET "+  tradestation.LoginDashboardPath,    s.dashboard)
+	mux.HandleFunc("POST "+ tradestation.RefreshTokenPath,      s.refreshToken)
+
+	//--- API
+
+	mux.HandleFunc("GET "+    tradestation.UrlBrokerageAccounts,                    s.secured(s.accounts))
+	mux.HandleFunc("GET "+    tradestation.UrlBrokerageAccounts +"/{id}/balances",  s.secured(s.balances))
+	mux.HandleFunc("GET "+    tradestation.UrlBrokerageAccounts +"/{id}/orders",    s.secured(s.getOrders))
+	mux.HandleFunc("GET "+    tradestation.UrlBrokerageAccounts +"/{id}/positions", s.secured(s.positions))
+	mux.HandleFunc("POST "+   tradestation.UrlOrderExecOrders,                      s.secured(s.placeOrder))
+	mux.HandleFunc("PUT "+    tradestation.UrlOrderExecOrders +"/{id}",             s.secured(s.replaceOrder))
+	mux.HandleFunc("DELETE "+ tradestation.UrlOrderExecOrders +"/{id}",             s.secured(s.cancelOrder))
+	mux.HandleFunc("GET "+    tradestation.UrlSymbolsSuggest +"/{filter}",          s.secured(s.suggest))
+	mux.HandleFunc("GET "+    tradestation.UrlMarketDataSymbols +"/{list}",         s.secured(s.symbols))
+	mux.HandleFunc("GET "+    tradestation.UrlSymbolsSearch +"/{query}",            s.secured(s.search))
+	mux.HandleFunc("GET "+    tradestation.UrlMarketDataBarcharts +"/{symbol}",     s.secured(s.barcharts))
+	mux.HandleFunc("GET "+    tradestation.UrlStreamQuotes +"/{symbol}",            s.secured(s.stream(adapter.StreamTypeQuotes)))
+	mux.HandleFunc("GET "+    tradestation.UrlStreamBarcharts +"/{symbol}",         s.secured(s.stream(adapter.StreamTypeBars)))
+
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+//=============================================================================
+//===
+//=== Methods
+//===
+//=============================================================================
+
+func (s *Server) ConfigParams() map[string]any {
+	return map[string]any{
+		tradestation.ParamLiveAccount: false,
+		tradestation.ParamLiveApiUrl : s.URL,
+		tradestation.ParamDemoApiUrl : s.URL,
+		tradestation.ParamPortalUrl  : s.URL,
+		tradestation.ParamSigninUrl  : s.URL,
+	}
+}
+
+//=============================================================================
+
+func (s *Server) ConnectParams() map[string]any {
+	return map[string]any{
+		adapter.ParamUsername : Username,
+		adapter.ParamPassword : Password,
+		adapter.ParamTwoFACode: TwoFACode,
+	}
+}
+
+//=============================================================================
+//--- Returns the number of barcharts requests received so far
+
+func (s *Server) BarchartRequests() int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.charts
+}
+
+//=============================================================================
+//--- Returns the number of streams of the given type that are still open
+
+func (s *Server) OpenStreams(st adapter.StreamType) int {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.open[string(st)]
+}
+
+//=============================================================================
+//--- Streams never end by themselves, so they are dropped before waiting for
+//--- the outstanding requests
+
+func (s *Server) Close() {
+	s.CloseClientConnections()
+	s.Server.Close()
+}
+
+//=============================================================================
+//--- Simulates the expiration of all access tokens issued so far
+
+func (s *Server) ExpireTokens() {
+	s.Lock()
+	defer s.Unlock()
+
+	for token := range s.tokens {
+		s.tokens[token] = false
+	}
+}
+
+//=============================================================================
+//===
+//=== Login & session handlers
+//===
+//=============================================================================
+
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	q := "state="+ loginState +"&client=C1&protocol=oauth2&scope=openid&response_type=code&audience=A1&nonce=N1"+
+		"&redirect_uri="+ url.QueryEscape(s.URL +"/api/auth/callback")
+
+	http.SetCookie(w, &http.Cookie{ Name: sessionCookie, Value: "s3ss10n", Path: "/", HttpOnly: true })
+	http.Redirect(w, r, s.URL + tradestation.LoginAuthorizePath +"?"+ q, http.StatusFound)
+}
+
+//=============================================================================
+//--- The adapter extracts the config from the first script without attributes
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	cfg := tradestation.Auth0Config{ ClientId: "C1", Auth0Domain: r.Host, Auth0Tenant: "tradestation" }
+	cfg.InternalOptions.Csrf     = csrf
+	cfg.InternalOptions.Intstate = "intstate"
+
+	data,_  := json.Marshal(&cfg)
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	adaptertest.WriteHtml(w, http.StatusOK, "<html><head><script src=\"/lock.js\"></script>"+
+		"<script>var config = JSON.parse(decodeURIComponent(escape(window.atob('"+ encoded +"'))));</script></head><body></body></html>")
+}
+
+//=============================================================================
+
+func (s *Server) usernamePassword(w http.ResponseWriter, r *http.Request) {
+	var lr tradestation.LoginRequest
+	err := json.NewDecoder(r.Body).Decode(&lr)
+
+	if err != nil || lr.Username != Username || lr.Password != Password || lr.State != loginState || lr.Csrf != csrf {
+		adaptertest.WriteJson(w, http.StatusUnauthorized, map[string]string{ "description": "Wrong email or password." })
+		return
+	}
+
+	adaptertest.WriteHtml(w, http.StatusOK, "<html><body><form method=\"post\" action=\""+ tradestation.LoginCallbackPath +"\">"+
+		"<input type=\"hidden\" name=\"wa\" value=\"wsignin1.0\"/>"+
+		"<input type=\"hidden\" name=\"wresult\" value=\"R1\"/>"+
+		"<input type=\"hidden\" name=\"wctx\" value=\""+ html.EscapeString(`{"state":"`+ loginState +`"}`) +"\"/>"+
+		"</form></body></html>")
+}
+
+//=============================================================================
+
+func (s *Server) callback(w http.ResponseWriter, r *http.Request) {
+	if r.PostFormValue("wa") != "wsignin1.0" || r.PostFormValue("wresult") != "R1" {
+		adaptertest.WriteHtml(w, http.StatusBadRequest, "<html><body>Bad callback</body></html>")
+		return
+	}
+
+	http.Redirect(w, r, s.URL + tradestation.LoginTwoFAPath +"?state="+ twoFAState, http.StatusFound)
+}
+
+//=============================================================================
+
+func (s *Server) twoFAPage(w http.ResponseWriter, r *http.Request) {
+	adaptertest.WriteHtml(w, http.StatusOK, "<html><body><form method=\"post\"><input name=\"code\"/></form></body></html>")
+}
+
+//=============================================================================
+
+func (s *Server) twoFASubmit(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("state") != twoFAState || r.PostFormValue("code") != TwoFACode {
+		adaptertest.WriteHtml(w, http.StatusBadRequest, "<html><body>Wrong code</body></html>")
+		return
+	}
+
+	http.Redirect(w, r, s.URL + tradestation.LoginDashboardPath, http.StatusFound)
+}
+
+//=============================================================================
+
+func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Authorization", s.newToken())
+	w.Header().Set("X-Id-Token",      "id-token")
+	adaptertest.WriteHtml(w, http.StatusOK, "<html><body>Dashboard</body></html>")
+}
+
+//=============================================================================
+//--- Like the real portal, the refresh is authenticated by the session cookie
+
+func (s *Server) refreshToken(w http.ResponseWriter, r *http.Request) {
+	if _,err := r.Cookie(sessionCookie); err != nil {
+		adaptertest.WriteJson(w, http.StatusUnauthorized, map[string]string{ "error": "no session" })
+		return
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.TokenRefreshResponse{ AccessToken: s.newToken(), IdToken: "id-token", Expiry: 1200 })
+}
+
+//=============================================================================
+//===
+//=== API handlers
+//===
+//=============================================================================
+
+func (s *Server) secured(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.Lock()
+		valid := s.tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		s.Unlock()
+
+		if !valid {
+			adaptertest.WriteJson(w, http.StatusUnauthorized, map[string]string{ "Error": "Unauthorized" })
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+//=============================================================================
+
+func (s *Server) accounts(w http.ResponseWriter, r *http.Request) {
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.AccountsResponse{
+		Accounts: []tradestation.Account{
+			{ AccountID: Account,   Currency: "USD", Status: "Active", AccountType: "Futures" },
+			{ AccountID: "SIM123M", Currency: "USD", Status: "Active", AccountType: "Margin"  },
+		},
+	})
+}
+
+//=============================================================================
+
+func (s *Server) balances(w http.ResponseWriter, r *http.Request) {
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.BalancesResponse{
+		Balances: []tradestation.Balance{
+			{
+				AccountID    : r.PathValue("id"),
+				CashBalance  : "100000",
+				Equity       : "100250",
+				BalanceDetail: tradestation.BalanceDetail{ UnrealizedProfitLoss: "250" },
+			},
+		},
+	})
+}
+
+//=============================================================================
+//--- Orders are returned in pages of one, to exercise the paging
+
+func (s *Server) getOrders(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	var ids []string
+	for id := range s.orders {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	page,_ := strconv.Atoi(r.URL.Query().Get("nextToken"))
+	res    := tradestation.OrdersResponse{}
+
+	if page < len(ids) {
+		res.Orders = []tradestation.Order{ *s.orders[ids[page]] }
+		if page +1 < len(ids) {
+			res.NextToken = strconv.Itoa(page +1)
+		}
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, &res)
+}
+
+//=============================================================================
+
+func (s *Server) positions(w http.ResponseWriter, r *http.Request) {
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.PositionsResponse{
+		Positions: []tradestation.Position{
+			{
+				AccountID   : r.PathValue("id"),
+				Symbol      : "ESH25",
+				LongShort   : "Long",
+				Quantity    : "2",
+				AveragePrice: "5900",
+				Timestamp   : "2025-01-02T14:30:00Z",
+			},
+		},
+	})
+}
+
+//=============================================================================
+
+func (s *Server) placeOrder(w http.ResponseWriter, r *http.Request) {
+	var rq tradestation.OrderRequest
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil || rq.AccountID != Account {
+		adaptertest.WriteJson(w, http.StatusOK, &tradestation.OrderResponse{
+			Errors: []tradestation.OrderError{ { Error: "FAILED", Message: "Invalid order" } },
+		})
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	s.nextId++
+	id := strconv.Itoa(s.nextId)
+
+	o := newOrder(id, "ACK", rq.OrderType, rq.LimitPrice)
+	o.Legs[0].Symbol          = rq.Symbol
+	o.Legs[0].QuantityOrdered = rq.Quantity
+	o.OrderConfirmID          = rq.OrderConfirmID
+	s.orders[id] = o
+
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.OrderResponse{
+		Orders: []tradestation.OrderResult{ { OrderID: id, Message: "Sent order" } },
+	})
+}
+
+//=============================================================================
+
+func (s *Server) replaceOrder(w http.ResponseWriter, r *http.Request) {
+	var rq tradestation.OrderReplaceRequest
+	err := json.NewDecoder(r.Body).Decode(&rq)
+	if err != nil {
+		adaptertest.WriteJson(w, http.StatusBadRequest, map[string]string{ "Error": "BadRequest" })
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	id := r.PathValue("id")
+	o,ok := s.orders[id]
+	if !ok {
+		adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "Error": "NotFound" })
+		return
+	}
+
+	if rq.Quantity != "" {
+		o.Legs[0].QuantityOrdered = rq.Quantity
+	}
+
+	if rq.LimitPrice != "" {
+		o.LimitPrice = rq.LimitPrice
+	}
+
+	if rq.StopPrice != "" {
+		o.StopPrice = rq.StopPrice
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.OrderResult{ OrderID: id, Message: "Replace request sent" })
+}
+
+//=============================================================================
+
+func (s *Server) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+
+	id := r.PathValue("id")
+	o,ok := s.orders[id]
+	if !ok {
+		adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "Error": "NotFound" })
+		return
+	}
+
+	o.Status = "CAN"
+	adaptertest.WriteJson(w, http.StatusOK, &tradestation.OrderResult{ OrderID: id, Message: "Cancel request sent" })
+}
+
+//=============================================================================
+
+func (s *Server) suggest(w http.ResponseWriter, r *http.Request) {
+	list := []tradestation.RootFound{}
+
+	if strings.HasPrefix("ES", strings.ToUpper(r.PathValue("filter"))) {
+		list = append(list,
+			tradestation.RootFound{ Root: "ES", Name: "ESH25", Description: "E-mini S&P 500", Exchange: "CME", Currency: "USD", PointValue: 50 },
+			tradestation.RootFound{ Root: "ES", Name: "ESM25", Description: "E-mini S&P 500", Exchange: "CME", Currency: "USD", PointValue: 50 },
+		)
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, list)
+}
+
+//=============================================================================
+
+func (s *Server) symbols(w http.ResponseWriter, r *http.Request) {
+	res := tradestation.SymbolDetailsResponse{ Symbols: []tradestation.SymbolDetails{} }
+
+	if strings.HasPrefix(r.PathValue("list"), "@ES,") {
+		res.Symbols = append(res.Symbols, tradestation.SymbolDetails{
+			AssetType  : "FUTURE",
+			Root       : "ES",
+			Symbol     : "@ES",
+			Description: "E-mini S&P 500",
+			Exchange   : "CME",
+			Currency   : "USD",
+			PriceFormat: tradestation.PriceFormat{ Increment: "0.25", PointValue: "50" },
+		})
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, &res)
+}
+
+//=============================================================================
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	list := []tradestation.SymbolFound{}
+
+	if strings.HasSuffix(r.PathValue("query"), "R=ES") {
+		expiry := time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC).UnixMilli()
+		list = append(list,
+			tradestation.SymbolFound{ Name: "@ES",   Category: "Future", Root: "ES", ExpirationDate: "/Date(-1)/" },
+			tradestation.SymbolFound{ Name: "ESH25", Category: "Future", Root: "ES", ExpirationDate: fmt.Sprintf("/Date(%d)/", expiry), PointValue: 50, MinMove: 0.25 },
+		)
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, list)
+}
+
+//=============================================================================
+//--- Returns, for each weekday between firstdate and lastdate, 5 minute bars
+//--- starting at 14:30 UTC and spaced by the interval, or 1 bar at 21:00 UTC
+//--- for the other units
+
+func (s *Server) barcharts(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	s.charts++
+	s.Unlock()
+
+	if r.PathValue("symbol") != "ESH25" {
+		adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "Error": "NotFound" })
+		return
+	}
+
+	q := r.URL.Query()
+	first,err1    := time.Parse(time.RFC3339, q.Get("firstdate"))
+	last,err2     := time.Parse(time.RFC3339, q.Get("lastdate"))
+	interval,err3 := strconv.Atoi(q.Get("interval"))
+	if err1 != nil || err2 != nil || err3 != nil || interval < 1 {
+		adaptertest.WriteJson(w, http.StatusBadRequest, map[string]string{ "Error": "BadRequest" })
+		return
+	}
+
+	count, offset, step := 1, 21*time.Hour, time.Duration(0)
+	if q.Get("unit") == "Minute" {
+		count, offset, step = 5, 14*time.Hour + 30*time.Minute, time.Duration(interval) * time.Minute
+	}
+
+	res := tradestation.BarchartsResponse{}
+
+	for day := first.Truncate(24*time.Hour); !day.After(last); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+
+		for i := range count {
+			ts := day.Add(offset + time.Duration(i) * step)
+			if ts.Before(first) || ts.After(last) {
+				continue
+			}
+
+			price := 5900 + float64(i)
+			res.Bars = append(res.Bars, tradestation.Bar{
+				TimeStamp: ts.Format(time.RFC3339),
+				Epoch    : ts.UnixMilli(),
+				Open     : formatPrice(price),
+				High     : formatPrice(price + 1),
+				Low      : formatPrice(price - 1),
+				Close    : formatPrice(price + 0.5),
+				UpVolume : 10,
+			})
+		}
+	}
+
+	adaptertest.WriteJson(w, http.StatusOK, &res)
+}
+
+//=============================================================================
+//--- Sends a heartbeat and then an event every StreamPeriod until the client
+//--- leaves. Like Tradestation, quotes carry only the fields that changed and
+//--- bars are updated a few times before being closed
+
+func (s *Server) stream(st adapter.StreamType) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("symbol") != "ESH25" {
+			adaptertest.WriteJson(w, http.StatusNotFound, map[string]string{ "Error": "NotFound" })
+			return
+		}
+
+		s.Lock()
+		s.open[string(st)]++
+		s.Unlock()
+
+		defer func() {
+			s.Lock()
+			s.open[string(st)]--
+			s.Unlock()
+		}()
+
+		w.Header().Set("Content-Type", tradestation.StreamContentType)
+		w.WriteHeader(http.StatusOK)
+
+		encoder := json.NewEncoder(w)
+		flusher := w.(http.Flusher)
+
+		_ = encoder.Encode(&tradestation.StreamStatus{ Heartbeat: 1 })
+		flusher.Flush()
+
+		ticker := time.NewTicker(StreamPeriod)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			select {
+				case <-r.Context().Done():
+					return
+				case <-ticker.C:
+			}
+
+			if st == adapter.StreamTypeQuotes {
+				_ = encoder.Encode(newStreamQuote(i))
+			} else {
+				_ = encoder.Encode(newStreamBar(i))
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func (s *Server) newToken() string {
+	s.Lock()
+	defer s.Unlock()
+
+	token := "access-"+ strconv.Itoa(len(s.tokens) +1)
+	s.tokens[token] = true
+	return token
+}
+
+//=============================================================================
+
+func newOrder(id, status, orderType, limitPrice string) *tradestation.Order {
+	return &tradestation.Order{
+		AccountID : Account,
+		OrderID   : id,
+		Status    : status,
+		OrderType : orderType,
+		Duration  : "DAY",
+		LimitPrice: limitPrice,
+		Legs      : []tradestation.OrderLeg{
+			{ BuyOrSell: "Buy", Symbol: "ESH25", QuantityOrdered: "1" },
+		},
+	}
+}
+
+//=============================================================================
+
+func newStreamQuote(i int) *tradestation.StreamQuote {
+	sq := &tradestation.StreamQuote{
+		Symbol: "ESH25",
+		Last  : formatPrice(5900 + float64(i)),
+	}
+
+	if i == 0 {
+		sq.Bid, sq.Ask, sq.BidSize, sq.AskSize = "5899.75", "5900.25", "10", "12"
+	}
+
+	return sq
+}
+
+//=============================================================================
+//--- Bars last 3 events: two updates, then the closed bar
+
+func newStreamBar(i int) *tradestation.StreamBar {
+	ts    := time.Date(2025, 1, 2, 14, 30, 0, 0, time.UTC).Add(time.Duration(i/3) * time.Minute)
+	price := 5900 + float64(i)
+
+	sb := &tradestation.StreamBar{
+		Bar: tradestation.Bar{
+			TimeStamp: ts.Format(time.RFC3339),
+			Epoch    : ts.UnixMilli(),
+			Open     : formatPrice(price),
+			High     : formatPrice(price + 1),
+			Low      : formatPrice(price - 1),
+			Close    : formatPrice(price + 0.5),
+			UpVolume : 10,
+		},
+		BarStatus : "Open",
+		IsRealtime: true,
+	}
+
+	if i % 3 == 2 {
+		sb.BarStatus = "Closed"
+	}
+
+	return sb
+}
+
+//=============================================================================
+
+func formatPrice(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+//=============================================================================
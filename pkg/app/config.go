@@ -32,6 +32,100 @@ type Config struct {
 	core.Application
 	core.Authentication
 	core.Messaging
+	ConnectionStore
+	Vault
+	WebLogin
+	HttpClient
+	Redaction
+	Cassette
+	BarCache
+	Catalog
+	Calendars
+}
+
+//=============================================================================
+
+const (
+	StoreTypeFile = "file"
+	StoreTypeSql  = "sql"
+	StoreTypeNone = "none"
+)
+
+//-----------------------------------------------------------------------------
+
+type ConnectionStore struct {
+	Type   string   // file (default) | sql | none
+	Path   string   // file store: path of the encrypted file
+	Key    string   // passphrase used to encrypt refresh tokens
+	Driver string   // sql store: name of a registered database/sql driver
+	Dsn    string   // sql store: data source name
+}
+
+//=============================================================================
+
+type Vault struct {
+	Path    string   // path of the credentials file
+	Key     string   // passphrase used to encrypt password values
+	KeyFile string   // alternative to key: file containing the passphrase
+}
+
+//=============================================================================
+
+type WebLogin struct {
+	BaseUrl    string   // public url of this service, used to build the login url
+	TimeoutSec int      // validity of a login url
+}
+
+//=============================================================================
+
+type HttpClient struct {
+	CaFile              string              // PEM bundle of trusted CAs
+	UseSystemRoots      bool                // if true, the CA file is added to the system roots
+	Pins                []HostPins          // SPKI pins by host
+	Proxy               string              // proxy url. If empty, the environment is used
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+}
+
+//-----------------------------------------------------------------------------
+//--- A list and not a map, as host names contain dots that would be read as nesting
+
+type HostPins struct {
+	Host string
+	Pins []string  // base64 SHA-256 of the certificate SubjectPublicKeyInfo
+}
+
+//=============================================================================
+
+type Redaction struct {
+	Headers []string // added to the default deny list
+	Cookies []string // if empty, all cookies are redacted
+	Params  []string // added to the default deny list
+}
+
+//=============================================================================
+
+type Cassette struct {
+	Path string   // directory of the record/replay cassettes. If empty, cassettes are disabled
+}
+
+//=============================================================================
+
+type BarCache struct {
+	Path string   // directory of the price bar cache. If empty, the cache is disabled
+}
+
+//=============================================================================
+
+type Catalog struct {
+	Path   string   // directory of the symbol catalog. If empty, the catalog is kept in memory
+	TtlSec int      // validity of cached roots and instruments
+}
+
+//=============================================================================
+
+type Calendars struct {
+	Path string   // directory of the trading calendar files (<EXCHANGE>.json). Optional
 }
 
 //=============================================================================
@@ -0,0 +1,59 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"log/slog"
+
+	"github.com/bit-fever/core/auth"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+
+func InvalidateBarCache(c *auth.Context, filter *BarCacheFilter) (*BarCacheInvalidation, error) {
+	if !adapter.IsBarCacheEnabled() {
+		return nil, req.NewServiceUnavailableError("Bar cache is not configured")
+	}
+
+	if filter.From != 0 && filter.To != 0 && filter.To < filter.From {
+		return nil, req.NewBadRequestError("Invalid date range: %v - %v", filter.From, filter.To)
+	}
+
+	count,err := adapter.InvalidateBarCache(filter.Adapter, filter.Symbol, filter.From, filter.To)
+
+	slog.Info("InvalidateBarCache: Cached days removed", "username", c.Session.Username, "adapter", filter.Adapter,
+		"symbol", filter.Symbol, "from", int(filter.From), "to", int(filter.To), "deleted", count)
+
+	if err != nil {
+		return nil, req.NewServerErrorByError(err)
+	}
+
+	return &BarCacheInvalidation{ Deleted: count }, nil
+}
+
+//=============================================================================
@@ -0,0 +1,89 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"strings"
+
+	"github.com/bit-fever/core/auth"
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+)
+
+//=============================================================================
+
+func GetTradingDays(c *auth.Context, exchange string, from, to datatype.IntDate) ([]*adapter.TradingDay, error) {
+	cal,err := adapter.GetTradingCalendar(exchange)
+	if err != nil {
+		return nil, req.NewNotFoundError("%v", err.Error())
+	}
+
+	if from <= 0 || to < from || adapter.DaysBetween(from, to) > MaxTradingDays {
+		return nil, req.NewBadRequestError("Invalid date range: %v - %v", from, to)
+	}
+
+	return cal.GetTradingDays(from, to), nil
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+//--- Replaces the automatic calendar with the exchange of the symbol's root
+
+func resolveCalendar(ctx *adapter.ConnectionContext, rq *adapter.PriceBarsRequest) error {
+	if rq.Calendar != adapter.CalendarAuto {
+		return nil
+	}
+
+	root := strings.TrimPrefix(rq.Symbol, "@")
+
+	if adapter.IsCanonicalSymbol(rq.Symbol) {
+		cs,err := adapter.ParseCanonicalSymbol(rq.Symbol)
+		if err != nil {
+			return req.NewBadRequestError("%v", err.Error())
+		}
+
+		rq.Calendar = cs.Exchange
+		return nil
+	}
+
+	if cs,ok := adapter.ParseFuturesSymbol(rq.Symbol, ""); ok {
+		root = cs.Root
+	}
+
+	rs,err := getCatalogRootSymbol(ctx, root)
+	if err != nil || rs == nil || rs.Exchange == "" {
+		return req.NewBadRequestError("Cannot find the exchange of %v: use the 'exchange' parameter", rq.Symbol)
+	}
+
+	rq.Calendar = rs.Exchange
+	return nil
+}
+
+//=============================================================================
@@ -0,0 +1,607 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== Symbol catalog: root symbols and instruments are cached per adapter and
+//=== refreshed in the background. Cached entries are served when the broker
+//=== cannot be reached, and instruments no longer returned by the broker
+//=== (expired contracts) are retained for historical lookups
+//===
+//=============================================================================
+
+const (
+	DefaultCatalogTtl      = 12 * time.Hour
+	CatalogSearchRetention = 7 * 24 * time.Hour
+)
+
+//=============================================================================
+
+var catalog = struct {
+	sync.RWMutex
+	path     string
+	ttl      time.Duration
+	adapters map[string]*adapterCatalog
+}{
+	ttl     : DefaultCatalogTtl,
+	adapters: map[string]*adapterCatalog{},
+}
+
+//=============================================================================
+
+type adapterCatalog struct {
+	Searches map[string]*catalogSearch `json:"searches"`
+	Roots    map[string]*catalogRoot   `json:"roots"`
+}
+
+//-----------------------------------------------------------------------------
+
+type catalogSearch struct {
+	Roots     []string  `json:"roots"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	UsedAt    time.Time `json:"usedAt"`
+}
+
+//-----------------------------------------------------------------------------
+//--- Current holds the instruments returned by the last fetch, in the broker's
+//--- order. Instruments holds them all, including the expired ones
+
+type catalogRoot struct {
+	Symbol        *adapter.RootSymbol            `json:"symbol"`
+	UpdatedAt     time.Time                      `json:"updatedAt"`
+	Instruments   map[string]*adapter.Instrument `json:"instruments"`
+	Current       []string                       `json:"current"`
+	InstrumentsAt time.Time                      `json:"instrumentsAt"`
+}
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+//--- Called periodically: refreshes the entries that are half way through
+//--- their validity, using any connected context of the adapter
+
+func RefreshCatalogs() {
+	for _, code := range getCatalogAdapters() {
+		ctx := findConnectedContext(code)
+		if ctx == nil {
+			continue
+		}
+
+		searches, roots, instruments := getStaleCatalogEntries(code)
+
+		for _, filter := range searches {
+			list, err := ctx.GetRootSymbols(filter)
+			if err == nil {
+				storeSearch(code, filter, list, false)
+			} else {
+				slog.Warn("RefreshCatalogs: Cannot refresh root symbols", "adapter", code, "filter", filter, "error", err.Error())
+			}
+		}
+
+		for _, root := range roots {
+			rs, err := ctx.GetRootSymbol(root)
+			if err == nil {
+				storeRoot(code, rs)
+			} else {
+				slog.Warn("RefreshCatalogs: Cannot refresh root symbol", "adapter", code, "root", root, "error", err.Error())
+			}
+		}
+
+		for _, root := range instruments {
+			list, err := getInstruments(ctx, root)
+			if err == nil {
+				storeInstruments(code, root, list)
+			} else {
+				slog.Warn("RefreshCatalogs: Cannot refresh instruments", "adapter", code, "root", root, "error", err.Error())
+			}
+		}
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func initCatalog(cfg *app.Catalog) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	catalog.path = cfg.Path
+	if cfg.TtlSec > 0 {
+		catalog.ttl = time.Duration(cfg.TtlSec) * time.Second
+	}
+
+	if catalog.path == "" {
+		slog.Info("initCatalog: No catalog path. The catalog is kept in memory")
+		return
+	}
+
+	files, err := filepath.Glob(filepath.Join(catalog.path, "*.json"))
+	if err != nil {
+		slog.Error("initCatalog: Cannot list the catalog files", "error", err.Error())
+		return
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			slog.Error("initCatalog: Cannot read a catalog file", "file", file, "error", err.Error())
+			continue
+		}
+
+		ac := newAdapterCatalog()
+		if err = json.Unmarshal(data, ac); err != nil {
+			slog.Error("initCatalog: Corrupted catalog file", "file", file, "error", err.Error())
+			continue
+		}
+
+		catalog.adapters[strings.TrimSuffix(filepath.Base(file), ".json")] = ac
+	}
+
+	slog.Info("initCatalog: Catalog loaded", "adapters", len(catalog.adapters))
+}
+
+//=============================================================================
+//===
+//=== Cached services
+//===
+//=============================================================================
+
+func getCatalogRootSymbols(ctx *adapter.ConnectionContext, filter string) ([]*adapter.RootSymbol, error) {
+	if !ctx.GetAdapterInfo().SharedCatalog {
+		return ctx.GetRootSymbols(filter)
+	}
+
+	code := ctx.GetAdapterInfo().Code
+	cached, fresh, found := lookupSearch(code, filter)
+	if found && (fresh || !ctx.IsConnected()) {
+		return cached, nil
+	}
+
+	list, err := ctx.GetRootSymbols(filter)
+	if err != nil {
+		if found {
+			slog.Warn("getCatalogRootSymbols: Broker unavailable. Serving the catalog", "adapter", code, "filter", filter, "error", err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	storeSearch(code, filter, list, true)
+	return list, nil
+}
+
+//=============================================================================
+
+func getCatalogRootSymbol(ctx *adapter.ConnectionContext, root string) (*adapter.RootSymbol, error) {
+	if !ctx.GetAdapterInfo().SharedCatalog {
+		return ctx.GetRootSymbol(root)
+	}
+
+	code := ctx.GetAdapterInfo().Code
+	cached, fresh := lookupRoot(code, root)
+	if cached != nil && (fresh || !ctx.IsConnected()) {
+		return cached, nil
+	}
+
+	rs, err := ctx.GetRootSymbol(root)
+	if err != nil {
+		if cached != nil {
+			slog.Warn("getCatalogRootSymbol: Broker unavailable. Serving the catalog", "adapter", code, "root", root, "error", err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	storeRoot(code, rs)
+	return rs, nil
+}
+
+//=============================================================================
+
+func getCatalogInstruments(ctx *adapter.ConnectionContext, root string, includeExpired bool) ([]*adapter.Instrument, error) {
+	if !ctx.GetAdapterInfo().SharedCatalog {
+		return getInstruments(ctx, root)
+	}
+
+	code := ctx.GetAdapterInfo().Code
+	cached, fresh, found := lookupInstruments(code, root, includeExpired)
+	if found && (fresh || !ctx.IsConnected()) {
+		return cached, nil
+	}
+
+	list, err := getInstruments(ctx, root)
+	if err != nil {
+		if found {
+			slog.Warn("getCatalogInstruments: Broker unavailable. Serving the catalog", "adapter", code, "root", root, "error", err.Error())
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	storeInstruments(code, root, list)
+
+	if includeExpired {
+		cached, _, _ = lookupInstruments(code, root, true)
+		return cached, nil
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+//--- Instruments without an exchange take the root's one for their canonical
+//--- symbol. The root comes from the catalog, so it is usually not asked again
+
+func getInstruments(ctx *adapter.ConnectionContext, root string) ([]*adapter.Instrument, error) {
+	list, err := ctx.GetInstruments(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if slices.ContainsFunc(list, func(i *adapter.Instrument) bool { return i.Canonical == "" && i.Exchange == "" }) {
+		if rs, err := getCatalogRootSymbol(ctx, root); err == nil {
+			ctx.SetCanonicalSymbols(list, rs.Exchange)
+		}
+	}
+
+	return list, nil
+}
+
+//=============================================================================
+//===
+//=== Catalog access
+//===
+//=============================================================================
+
+func lookupSearch(code, filter string) ([]*adapter.RootSymbol, bool, bool) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	ac := catalog.adapters[code]
+	if ac == nil {
+		return nil, false, false
+	}
+
+	cs := ac.Searches[strings.ToUpper(filter)]
+	if cs == nil {
+		return nil, false, false
+	}
+
+	var list []*adapter.RootSymbol
+	for _, root := range cs.Roots {
+		if cr := ac.Roots[root]; cr != nil && cr.Symbol != nil {
+			list = append(list, cr.Symbol)
+		}
+	}
+
+	cs.UsedAt = time.Now()
+	return list, time.Since(cs.UpdatedAt) < catalog.ttl, true
+}
+
+//=============================================================================
+
+func lookupRoot(code, root string) (*adapter.RootSymbol, bool) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	cr := getCatalogRoot(code, root)
+	if cr == nil || cr.Symbol == nil {
+		return nil, false
+	}
+
+	return cr.Symbol, time.Since(cr.UpdatedAt) < catalog.ttl
+}
+
+//=============================================================================
+
+func lookupInstruments(code, root string, includeExpired bool) ([]*adapter.Instrument, bool, bool) {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	cr := getCatalogRoot(code, root)
+	if cr == nil || cr.InstrumentsAt.IsZero() {
+		return nil, false, false
+	}
+
+	var list []*adapter.Instrument
+	for _, name := range cr.Current {
+		if i := cr.Instruments[name]; i != nil {
+			list = append(list, i)
+		}
+	}
+
+	if includeExpired {
+		var expired []*adapter.Instrument
+		for name, i := range cr.Instruments {
+			if !slices.Contains(cr.Current, name) {
+				expired = append(expired, i)
+			}
+		}
+
+		//--- Most recent first
+		slices.SortFunc(expired, func(a, b *adapter.Instrument) int {
+			return compareExpiration(b, a)
+		})
+
+		list = append(list, expired...)
+	}
+
+	return list, time.Since(cr.InstrumentsAt) < catalog.ttl, true
+}
+
+//=============================================================================
+//--- The search result updates the roots too. Background refreshes don't count
+//--- as a use of the search
+
+func storeSearch(code, filter string, list []*adapter.RootSymbol, used bool) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	ac  := getAdapterCatalog(code)
+	now := time.Now()
+	key := strings.ToUpper(filter)
+
+	cs := ac.Searches[key]
+	if cs == nil {
+		cs = &catalogSearch{ UsedAt: now }
+		ac.Searches[key] = cs
+	}
+
+	cs.Roots     = nil
+	cs.UpdatedAt = now
+	if used {
+		cs.UsedAt = now
+	}
+
+	for _, rs := range list {
+		cr := getOrCreateCatalogRoot(ac, rs.Code)
+		cr.Symbol    = rs
+		cr.UpdatedAt = now
+		cs.Roots     = append(cs.Roots, rs.Code)
+	}
+
+	saveCatalog(code, ac)
+}
+
+//=============================================================================
+
+func storeRoot(code string, rs *adapter.RootSymbol) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	ac := getAdapterCatalog(code)
+	cr := getOrCreateCatalogRoot(ac, rs.Code)
+	cr.Symbol    = rs
+	cr.UpdatedAt = time.Now()
+
+	saveCatalog(code, ac)
+}
+
+//=============================================================================
+//--- Instruments are never removed: the ones missing from the list are expired
+
+func storeInstruments(code, root string, list []*adapter.Instrument) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	ac := getAdapterCatalog(code)
+	cr := getOrCreateCatalogRoot(ac, root)
+	cr.Current       = nil
+	cr.InstrumentsAt = time.Now()
+
+	for _, i := range list {
+		cr.Instruments[i.Name] = i
+		cr.Current = append(cr.Current, i.Name)
+	}
+
+	saveCatalog(code, ac)
+}
+
+//=============================================================================
+
+func getCatalogAdapters() []string {
+	catalog.RLock()
+	defer catalog.RUnlock()
+
+	var list []string
+	for code := range catalog.adapters {
+		list = append(list, code)
+	}
+
+	return list
+}
+
+//=============================================================================
+//--- Entries are refreshed half way through their validity, so that requests
+//--- seldom find them expired. Searches that are no longer used are dropped
+
+func getStaleCatalogEntries(code string) (searches, roots, instruments []string) {
+	catalog.Lock()
+	defer catalog.Unlock()
+
+	ac := catalog.adapters[code]
+	if ac == nil {
+		return
+	}
+
+	limit := catalog.ttl / 2
+	now   := time.Now()
+
+	for key, cs := range ac.Searches {
+		if now.Sub(cs.UsedAt) > CatalogSearchRetention {
+			delete(ac.Searches, key)
+		} else if now.Sub(cs.UpdatedAt) > limit {
+			searches = append(searches, key)
+		}
+	}
+
+	for root, cr := range ac.Roots {
+		if cr.Symbol != nil && now.Sub(cr.UpdatedAt) > limit {
+			roots = append(roots, root)
+		}
+		if !cr.InstrumentsAt.IsZero() && now.Sub(cr.InstrumentsAt) > limit {
+			instruments = append(instruments, root)
+		}
+	}
+
+	return
+}
+
+//=============================================================================
+
+func findConnectedContext(code string) *adapter.ConnectionContext {
+	userConnections.RLock()
+	defer userConnections.RUnlock()
+
+	for _, uc := range userConnections.m {
+		for _, ctx := range uc.contexts {
+			if ctx.IsConnected() && ctx.GetAdapterInfo().Code == code {
+				return ctx
+			}
+		}
+	}
+
+	return nil
+}
+
+//=============================================================================
+//--- The catalog lock must be held by the callers of the functions below
+
+func newAdapterCatalog() *adapterCatalog {
+	return &adapterCatalog{
+		Searches: map[string]*catalogSearch{},
+		Roots   : map[string]*catalogRoot{},
+	}
+}
+
+//=============================================================================
+
+func getAdapterCatalog(code string) *adapterCatalog {
+	ac := catalog.adapters[code]
+	if ac == nil {
+		ac = newAdapterCatalog()
+		catalog.adapters[code] = ac
+	}
+
+	return ac
+}
+
+//=============================================================================
+
+func getCatalogRoot(code, root string) *catalogRoot {
+	if ac := catalog.adapters[code]; ac != nil {
+		return ac.Roots[root]
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func getOrCreateCatalogRoot(ac *adapterCatalog, root string) *catalogRoot {
+	cr := ac.Roots[root]
+	if cr == nil {
+		cr = &catalogRoot{ Instruments: map[string]*adapter.Instrument{} }
+		ac.Roots[root] = cr
+	}
+
+	if cr.Instruments == nil {
+		cr.Instruments = map[string]*adapter.Instrument{}
+	}
+
+	return cr
+}
+
+//=============================================================================
+
+func saveCatalog(code string, ac *adapterCatalog) {
+	if catalog.path == "" {
+		return
+	}
+
+	err := writeCatalogFile(filepath.Join(catalog.path, code +".json"), ac)
+	if err != nil {
+		slog.Error("saveCatalog: Cannot write the catalog", "adapter", code, "error", err.Error())
+	}
+}
+
+//=============================================================================
+
+func writeCatalogFile(file string, ac *adapterCatalog) error {
+	data, err := json.Marshal(ac)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	tmp := file +".tmp"
+	if err = os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, file)
+}
+
+//=============================================================================
+//--- Contracts without expiration (continuous) are considered the most recent
+
+func compareExpiration(a, b *adapter.Instrument) int {
+	switch {
+		case a.ExpirationDate == nil && b.ExpirationDate == nil:
+			return strings.Compare(a.Name, b.Name)
+		case a.ExpirationDate == nil:
+			return 1
+		case b.ExpirationDate == nil:
+			return -1
+	}
+
+	return a.ExpirationDate.Compare(*b.ExpirationDate)
+}
+
+//=============================================================================
@@ -0,0 +1,182 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/adaptertest"
+	"github.com/bit-fever/system-adapter/pkg/adapter/tradestation"
+	"github.com/bit-fever/system-adapter/pkg/adapter/tradestation/tradestationtest"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+
+func TestCatalogServesCachedEntriesWhenBrokerIsDown(t *testing.T) {
+	initTestCatalog(t)
+
+	srv := tradestationtest.NewServer()
+	ctx := connectTestContext(t, srv)
+
+	//--- Entries never stay fresh, so that every request goes to the broker
+
+	catalog.ttl = 0
+
+	roots,err1 := getCatalogRootSymbols(ctx, "ES")
+	root, err2 := getCatalogRootSymbol (ctx, "ES")
+	list, err3 := getCatalogInstruments(ctx, "ES", false)
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatalf("cannot fill the catalog: %v, %v, %v", err1, err2, err3)
+	}
+
+	srv.Close()
+
+	cachedRoots,err1 := getCatalogRootSymbols(ctx, "es")
+	cachedRoot, err2 := getCatalogRootSymbol (ctx, "ES")
+	cachedList, err3 := getCatalogInstruments(ctx, "ES", false)
+	if err1 != nil || err2 != nil || err3 != nil {
+		t.Fatalf("the catalog must be served when the broker is down: %v, %v, %v", err1, err2, err3)
+	}
+
+	if len(cachedRoots) != len(roots) || cachedRoot.Code != root.Code || len(cachedList) != len(list) {
+		t.Errorf("cached entries differ from the broker's ones")
+	}
+
+	if _,err := getCatalogInstruments(ctx, "NQ", false); err == nil {
+		t.Errorf("a root missing from the catalog must fail when the broker is down")
+	}
+}
+
+//=============================================================================
+
+func TestCatalogRetainsExpiredInstruments(t *testing.T) {
+	initTestCatalog(t)
+
+	expired := time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC)
+	storeInstruments("TS", "ES", []*adapter.Instrument{
+		{ Name: "ESZ24", Root: "ES", ExpirationDate: &expired },
+	})
+
+	srv := tradestationtest.NewServer()
+	t.Cleanup(srv.Close)
+	ctx := connectTestContext(t, srv)
+
+	catalog.ttl = 0
+
+	current,err := getCatalogInstruments(ctx, "ES", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all,err := getCatalogInstruments(ctx, "ES", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != len(current) +1 || all[len(all)-1].Name != "ESZ24" {
+		t.Errorf("expected the expired contract after the %d current ones, got %d instruments", len(current), len(all))
+	}
+
+	for _, i := range current {
+		if i.Name == "ESZ24" {
+			t.Errorf("expired contracts must not be returned by default")
+		}
+	}
+}
+
+//=============================================================================
+
+func TestCanonicalSymbolsUseTheCatalogRoot(t *testing.T) {
+	initTestCatalog(t)
+
+	srv := tradestationtest.NewServer()
+	t.Cleanup(srv.Close)
+	ctx := connectTestContext(t, srv)
+
+	list,err := getCatalogInstruments(ctx, "ES", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, i := range list {
+		if i.Name == "ESH25" {
+			found = true
+			if i.Canonical != "ES:CME:202503" {
+				t.Errorf("instruments without an exchange must take the root's one, got '%s'", i.Canonical)
+			}
+		}
+	}
+
+	if !found {
+		t.Fatalf("ESH25 not found in %d instruments", len(list))
+	}
+
+	if err := checkSymbolExchange(ctx, "ES:CME:202503"); err != nil {
+		t.Errorf("the root's exchange must be accepted: %v", err)
+	}
+
+	if err := checkSymbolExchange(ctx, "ES:NYMEX:202503"); err == nil {
+		t.Errorf("an exchange different from the root's one must be rejected")
+	}
+}
+
+//=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func initTestCatalog(t *testing.T) {
+	catalog.Lock()
+	catalog.adapters = map[string]*adapterCatalog{}
+	catalog.Unlock()
+
+	initCatalog(&app.Catalog{ Path: t.TempDir() })
+
+	t.Cleanup(func() {
+		catalog.Lock()
+		catalog.path     = ""
+		catalog.ttl      = DefaultCatalogTtl
+		catalog.adapters = map[string]*adapterCatalog{}
+		catalog.Unlock()
+	})
+}
+
+//=============================================================================
+
+func connectTestContext(t *testing.T, srv *tradestationtest.Server) *adapter.ConnectionContext {
+	return adaptertest.Connect(t, &adaptertest.Setup{
+		Adapter      : tradestation.NewAdapter(),
+		ConfigParams : srv.ConfigParams(),
+		ConnectParams: srv.ConnectParams(),
+	})
+}
+
+//=============================================================================
@@ -0,0 +1,324 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/app"
+	"golang.org/x/crypto/scrypt"
+)
+
+//=============================================================================
+//===
+//=== Connection store: keeps what is needed to restore a connection after a
+//=== restart. Connect params (username, password, 2FA code) are never stored
+//===
+//=============================================================================
+
+type StoredConnection struct {
+	Username       string         `json:"username"`
+	ConnectionCode string         `json:"connectionCode"`
+	SystemCode     string         `json:"systemCode"`
+	Host           string         `json:"host"`
+	ConfigParams   map[string]any `json:"configParams"`
+	RefreshToken   string         `json:"refreshToken"`
+	CredentialId   string         `json:"credentialId,omitempty"`  // needed to reconnect after a restart
+}
+
+//-----------------------------------------------------------------------------
+
+type ConnectionStore interface {
+	Load() ([]*StoredConnection, error)
+	Save(sc *StoredConnection) error
+	Delete(username string, connectionCode string) error
+}
+
+//=============================================================================
+
+var store ConnectionStore
+
+//=============================================================================
+//===
+//=== Private methods
+//===
+//=============================================================================
+
+func initStore(cfg *app.ConnectionStore) {
+	if cfg.Type == app.StoreTypeNone {
+		slog.Info("initStore: Connection store is disabled")
+		return
+	}
+
+	if cfg.Key == "" {
+		slog.Warn("initStore: Missing encryption key. Connections will not be persisted")
+		return
+	}
+
+	cr,err := newCrypter(cfg.Key)
+	if err != nil {
+		slog.Error("initStore: Cannot create the crypter. Connections will not be persisted", "error", err.Error())
+		return
+	}
+
+	switch cfg.Type {
+		case "", app.StoreTypeFile:
+			store = newFileStore(cfg.Path, cr)
+
+		case app.StoreTypeSql:
+			store,err = newSqlStore(cfg.Driver, cfg.Dsn, cr)
+
+		default:
+			err = errors.New("unknown store type : "+ cfg.Type)
+	}
+
+	if err != nil {
+		slog.Error("initStore: Cannot open the connection store. Connections will not be persisted", "error", err.Error())
+		store = nil
+	}
+}
+
+//=============================================================================
+
+func storeConnection(ctx *adapter.ConnectionContext) {
+	if store == nil {
+		return
+	}
+
+	sc := &StoredConnection{
+		Username      : ctx.Username,
+		ConnectionCode: ctx.ConnectionCode,
+		SystemCode    : ctx.GetAdapterInfo().Code,
+		Host          : ctx.Host,
+		ConfigParams  : ctx.GetConfigParams(),
+		RefreshToken  : ctx.GetRefreshToken(),
+		CredentialId  : ctx.CredentialId,
+	}
+
+	err := store.Save(sc)
+	if err != nil {
+		slog.Error("storeConnection: Cannot save the connection", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+	}
+}
+
+//=============================================================================
+//--- A reconnecting context keeps its stored entry until the policy gives up
+
+func updateStoredConnection(ctx *adapter.ConnectionContext) {
+	if ctx.IsConnected() {
+		storeConnection(ctx)
+	} else if ctx.IsDisconnected() {
+		unstoreConnection(ctx.Username, ctx.ConnectionCode)
+	}
+}
+
+//=============================================================================
+
+func unstoreConnection(username string, connectionCode string) {
+	if store == nil {
+		return
+	}
+
+	err := store.Delete(username, connectionCode)
+	if err != nil {
+		slog.Error("unstoreConnection: Cannot delete the connection", "username", username, "connection", connectionCode, "error", err.Error())
+	}
+}
+
+//=============================================================================
+//===
+//=== Crypter: AES-GCM with a key derived from the configured passphrase with
+//=== scrypt. The salt is stored in front of every encrypted value:
+//===
+//===    <version> <salt> <nonce> <data>
+//===
+//=== Values written before the KDF (key = sha256 of the passphrase, no header)
+//=== can still be read and are rewritten in the new format on the next save
+//===
+//=============================================================================
+
+const (
+	crypterVersion  = 1
+	crypterSaltSize = 16
+)
+
+//=============================================================================
+
+type crypter struct {
+	sync.Mutex
+	passphrase string
+	salt       []byte
+	aead       cipher.AEAD
+	aeads      map[string]cipher.AEAD   // salt --> aead, as scrypt is slow by design
+	legacy     cipher.AEAD
+}
+
+//=============================================================================
+
+func newCrypter(passphrase string) (*crypter, error) {
+	salt := make([]byte, crypterSaltSize)
+	_,err := io.ReadFull(rand.Reader, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	legacyKey := sha256.Sum256([]byte(passphrase))
+	legacy,err := newAead(legacyKey[:])
+	if err != nil {
+		return nil, err
+	}
+
+	c := &crypter{
+		passphrase: passphrase,
+		salt      : salt,
+		aeads     : map[string]cipher.AEAD{},
+		legacy    : legacy,
+	}
+
+	c.aead,err = c.getAead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+//=============================================================================
+
+func (c *crypter) encrypt(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	_,err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{ crypterVersion }, c.salt...)
+	header  = append(header, nonce...)
+
+	return c.aead.Seal(header, nonce, data, nil), nil
+}
+
+//=============================================================================
+
+func (c *crypter) decrypt(data []byte) ([]byte, error) {
+	if len(data) > 1 + crypterSaltSize && data[0] == crypterVersion {
+		aead,err := c.getAead(data[1 : 1+crypterSaltSize])
+		if err != nil {
+			return nil, err
+		}
+
+		res,err := open(aead, data[1+crypterSaltSize:])
+		if err == nil {
+			return res, nil
+		}
+	}
+
+	return open(c.legacy, data)
+}
+
+//=============================================================================
+
+func (c *crypter) getAead(salt []byte) (cipher.AEAD, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if aead,found := c.aeads[string(salt)]; found {
+		return aead, nil
+	}
+
+	key,err := scrypt.Key([]byte(c.passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	aead,err := newAead(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.aeads[string(salt)] = aead
+	return aead, nil
+}
+
+//=============================================================================
+
+func (c *crypter) encryptString(s string) (string, error) {
+	data,err := c.encrypt([]byte(s))
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+//=============================================================================
+
+func (c *crypter) decryptString(s string) (string, error) {
+	data,err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+
+	data,err = c.decrypt(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+//=============================================================================
+
+func newAead(key []byte) (cipher.AEAD, error) {
+	block,err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+//=============================================================================
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(data) < size {
+		return nil, errors.New("encrypted data is too short")
+	}
+
+	return aead.Open(nil, data[:size], data[size:], nil)
+}
+
+//=============================================================================
@@ -0,0 +1,150 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/local"
+)
+
+//=============================================================================
+
+func TestStoredConnectionKeepsCredential(t *testing.T) {
+	cr,err := newCrypter("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "connections.dat")
+
+	ctx,err := adapter.NewConnectionContext("tester", "C1", "localhost", local.NewAdapter(), map[string]any{}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	setCredentialProvider(ctx, &ConnectionSpec{ SystemCode: "LOCAL", CredentialId: "CRED1" })
+
+	store = newFileStore(path, cr)
+	t.Cleanup(func() { store = nil })
+
+	if _,err = store.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	storeConnection(ctx)
+
+	list,err := newFileStore(path, cr).Load()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected 1 stored connection, got %d (error: %v)", len(list), err)
+	}
+
+	if list[0].CredentialId != "CRED1" {
+		t.Errorf("the credential id must be stored, got '%s'", list[0].CredentialId)
+	}
+}
+
+//=============================================================================
+
+func TestFileStoreRefusesWritesAfterFailedLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "connections.dat")
+
+	good,_ := newCrypter("good")
+	fs     := newFileStore(path, good)
+	if _,err := fs.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Save(&StoredConnection{ Username: "tester", ConnectionCode: "C1" }); err != nil {
+		t.Fatal(err)
+	}
+
+	original,_ := os.ReadFile(path)
+
+	//--- A wrong key must not lead to the file being rewritten
+
+	bad,_ := newCrypter("bad")
+	fs     = newFileStore(path, bad)
+	if _,err := fs.Load(); err == nil {
+		t.Fatal("loading with a wrong key must fail")
+	}
+
+	if err := fs.Save(&StoredConnection{ Username: "tester", ConnectionCode: "C2" }); err == nil {
+		t.Error("saving after a failed load must fail")
+	}
+
+	if err := fs.Delete("tester", "C1"); err != nil {
+		t.Error(err)
+	}
+
+	current,_ := os.ReadFile(path)
+	if !bytes.Equal(original, current) {
+		t.Error("the store file must be left untouched")
+	}
+}
+
+//=============================================================================
+
+func TestCrypter(t *testing.T) {
+	cr1,_ := newCrypter("secret")
+	cr2,_ := newCrypter("secret")
+	cr3,_ := newCrypter("other")
+
+	data,err := cr1.encrypt([]byte("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//--- The salt is stored with the data, so any instance with the same passphrase can read it
+
+	res,err := cr2.decrypt(data)
+	if err != nil || string(res) != "payload" {
+		t.Errorf("expected 'payload', got '%s' (error: %v)", res, err)
+	}
+
+	if _,err = cr3.decrypt(data); err == nil {
+		t.Error("decrypting with another passphrase must fail")
+	}
+
+	//--- Data written before the KDF is still readable
+
+	key    := sha256.Sum256([]byte("secret"))
+	aead,_ := newAead(key[:])
+	nonce  := make([]byte, aead.NonceSize())
+	legacy := aead.Seal(nonce, nonce, []byte("legacy"), nil)
+
+	res,err = cr1.decrypt(legacy)
+	if err != nil || string(res) != "legacy" {
+		t.Errorf("expected 'legacy', got '%s' (error: %v)", res, err)
+	}
+}
+
+//=============================================================================
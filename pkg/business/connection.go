@@ -25,12 +25,18 @@ THE SOFTWARE.
 package business
 
 import (
+	"errors"
 	"github.com/bit-fever/core/auth"
 	"github.com/bit-fever/core/datatype"
 	"github.com/bit-fever/core/msg"
 	"github.com/bit-fever/core/req"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/app"
+	"log/slog"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 //=============================================================================
@@ -40,6 +46,24 @@ var userConnections = struct {
 	m map[string]*UserConnections
 }{m: make(map[string]*UserConnections)}
 
+//-----------------------------------------------------------------------------
+//--- Contexts waiting for a web login, indexed by instance code. The expiry is
+//--- kept here so that checking a code never depends on the context lock
+
+type webLoginEntry struct {
+	ctx    *adapter.ConnectionContext
+	expiry time.Time
+}
+
+var instanceCodes = struct {
+	sync.RWMutex
+	m map[string]*webLoginEntry
+}{m: make(map[string]*webLoginEntry)}
+
+//-----------------------------------------------------------------------------
+
+var webLogin app.WebLogin
+
 //=============================================================================
 //===
 //=== Public methods
@@ -62,6 +86,7 @@ func GetConnections(c *auth.Context, filter map[string]any, offset int, limit in
 				ConnectionCode: ctx.ConnectionCode,
 				SystemCode    : ctx.GetAdapterInfo().Code,
 				SystemName    : ctx.GetAdapterInfo().Name,
+				NextRefresh   : ctx.GetNextRefreshTime(),
 			}
 			list = append(list, &ci)
 		}
@@ -70,6 +95,24 @@ func GetConnections(c *auth.Context, filter map[string]any, offset int, limit in
 	return &list
 }
 
+//=============================================================================
+//--- Used by the metrics endpoint
+
+func CountConnectionsByStatus() map[string]float64 {
+	userConnections.RLock()
+	defer userConnections.RUnlock()
+
+	counts := map[string]float64{}
+
+	for _,uc := range userConnections.m {
+		for _, ctx := range uc.contexts {
+			counts[ctx.GetStatus().String()]++
+		}
+	}
+
+	return counts
+}
+
 //=============================================================================
 
 func GetConnectionsToRefresh() []*adapter.ConnectionContext {
@@ -91,22 +134,88 @@ func GetConnectionsToRefresh() []*adapter.ConnectionContext {
 
 //=============================================================================
 
-func GetConnectionContextByInstanceCode(instanceCode string) *adapter.ConnectionContext {
+func GetConnectionsToReconnect() []*adapter.ConnectionContext {
 	userConnections.RLock()
 	defer userConnections.RUnlock()
 
-	//TODO
-	//for _,uc := range userConnections.m {
-	//	for _,ctx := range uc.contexts {
-	//		if ctx.InstanceCode == instanceCode {
-	//			return ctx
-	//		}
-	//	}
-	//}
+	var list []*adapter.ConnectionContext
+
+	for _,uc := range userConnections.m {
+		for _, ctx := range uc.contexts {
+			if ctx.NeedsReconnect() {
+				list = append(list, ctx)
+			}
+		}
+	}
+
+	return list
+}
+
+//=============================================================================
+
+func GetConnectionContextByInstanceCode(instanceCode string) *adapter.ConnectionContext {
+	instanceCodes.RLock()
+	defer instanceCodes.RUnlock()
+
+	entry,found := instanceCodes.m[instanceCode]
+	if !found || !time.Now().Before(entry.expiry) {
+		return nil
+	}
+
+	return entry.ctx
+}
+
+//=============================================================================
+
+func CompleteWebLogin(ctx *adapter.ConnectionContext, reqHeader *http.Header, resCookies []*http.Cookie) error {
+	code := ctx.GetInstanceCode()
+
+	err := ctx.InitFromWebLogin(reqHeader, resCookies)
+	if err != nil {
+		return err
+	}
+
+	removeInstanceCode(code)
+	storeConnection(ctx)
+
+	err = publishConnectionChange(ctx)
+	if err != nil {
+		slog.Error("CompleteWebLogin: Could not publish the change message", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+	}
 
 	return nil
 }
 
+//=============================================================================
+//--- Web logins not completed in time are cancelled and their context is
+//--- set back to disconnected
+
+func ExpireWebLogins() {
+	instanceCodes.Lock()
+
+	var expired []*adapter.ConnectionContext
+	now := time.Now()
+
+	for code,entry := range instanceCodes.m {
+		if !now.Before(entry.expiry) {
+			delete(instanceCodes.m, code)
+			expired = append(expired, entry.ctx)
+		}
+	}
+
+	instanceCodes.Unlock()
+
+	for _,ctx := range expired {
+		slog.Info("ExpireWebLogins: Web login timed out", "username", ctx.Username, "connection", ctx.ConnectionCode)
+		ctx.ExpireWebLogin()
+
+		err := publishConnectionChange(ctx)
+		if err != nil {
+			slog.Error("ExpireWebLogins: Could not publish the change message", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+		}
+	}
+}
+
 //=============================================================================
 
 func Connect(c *auth.Context, connectionCode string, cs *ConnectionSpec) (*ConnectionResult, error) {
@@ -138,6 +247,13 @@ func Connect(c *auth.Context, connectionCode string, cs *ConnectionSpec) (*Conne
 				Message: "Still connecting",
 			}, nil
 		}
+
+		if ctx.IsReconnecting() {
+			return &ConnectionResult{
+				Status : ConnectionStatusReconnecting,
+				Message: "Reconnecting",
+			}, nil
+		}
 	}
 
 	ad,ok := adapters[cs.SystemCode]
@@ -145,8 +261,25 @@ func Connect(c *auth.Context, connectionCode string, cs *ConnectionSpec) (*Conne
 		return nil, req.NewNotFoundError("System not found: %v", cs.SystemCode)
 	}
 
-	var err error
-	ctx,err = adapter.NewConnectionContext(c.Session.Username, connectionCode, c.Gin.Request.Host, ad, cs.ConfigParams, cs.ConnectParams)
+	err := checkPriceFeed(uc, connectionCode, cs.ConfigParams)
+	if err != nil {
+		return nil, err
+	}
+
+	connectParams,err := resolveConnectParams(user, cs)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx,err = adapter.NewConnectionContext(c.Session.Username, connectionCode, c.Gin.Request.Host, ad, cs.ConfigParams, connectParams)
+	if err != nil {
+		return &ConnectionResult{
+			Status : ConnectionStatusError,
+			Message: err.Error(),
+		}, nil
+	}
+
+	err = setPriceFeed(uc, connectionCode, ctx, cs.ConfigParams)
 	if err != nil {
 		return &ConnectionResult{
 			Status : ConnectionStatusError,
@@ -154,10 +287,17 @@ func Connect(c *auth.Context, connectionCode string, cs *ConnectionSpec) (*Conne
 		}, nil
 	}
 
+	setCredentialProvider(ctx, cs)
+
 	//--- It is better to store again the context even if it is already there: the user could use the
 	//--- same connection code but with a different adapter
 
+	if old,found := uc.contexts[connectionCode]; found {
+		removeInstanceCode(old.GetInstanceCode())
+	}
+
 	uc.contexts[connectionCode] = ctx
+	updatePriceFeeds(uc, connectionCode, ctx)
 
 	res := &ConnectionResult{
 		Status : ConnectionStatusError,
@@ -180,17 +320,24 @@ func Connect(c *auth.Context, connectionCode string, cs *ConnectionSpec) (*Conne
 	switch cr {
 		case adapter.ConnectionResultConnected:
 			res.Status = ConnectionStatusConnected
+			storeConnection(ctx)
 
 		case adapter.ConnectionResultOpenUrl:
 			res.Status  = ConnectionStatusConnecting
 			res.Action  = ConnectionActionOpenUrl
 			res.Message = ctx.GetAdapterAuthUrl()
 
-		//TODO: to review: hardcoded url
 		case adapter.ConnectionResultProxyUrl:
+			code,err := addInstanceCode(ctx)
+			if err != nil {
+				res.Status  = ConnectionStatusError
+				res.Message = err.Error()
+				return res, nil
+			}
+
 			res.Status  = ConnectionStatusConnecting
 			res.Action  = ConnectionActionOpenUrl
-			res.Message = "https://bitfever-server:8449/api/system/v1/weblogin/"+ user +"/"+ connectionCode +"/login"
+			res.Message = webLogin.BaseUrl +"/api/system/v1/weblogin/"+ code
 	}
 
 	return res, nil
@@ -224,11 +371,34 @@ func Disconnect(c *auth.Context, connectionCode string) error {
 	}
 
 	delete(uc.contexts, connectionCode)
+	updatePriceFeeds(uc, connectionCode, nil)
+	removeInstanceCode(ctx.GetInstanceCode())
+	unstoreConnection(user, connectionCode)
 	_ = ctx.Disconnect()
 
 	return nil
 }
 
+//=============================================================================
+//--- Called by the token refresher: a successful refresh may rotate the token
+//--- so the store is updated, while a lost connection is dropped from it
+
+func RefreshToken(ctx *adapter.ConnectionContext) error {
+	err := ctx.RefreshToken()
+	updateStoredConnection(ctx)
+
+	return err
+}
+
+//=============================================================================
+
+func Reconnect(ctx *adapter.ConnectionContext) error {
+	err := ctx.Reconnect()
+	updateStoredConnection(ctx)
+
+	return err
+}
+
 //=============================================================================
 //===
 //=== Services
@@ -241,7 +411,7 @@ func GetRootSymbols(c *auth.Context, connectionCode string, filter string) ([]*a
 		return nil,err
 	}
 
-	return ctx.GetRootSymbols(filter)
+	return getCatalogRootSymbols(ctx, filter)
 }
 
 //=============================================================================
@@ -252,29 +422,72 @@ func GetRootSymbol(c *auth.Context, connectionCode string, root string) (*adapte
 		return nil,err
 	}
 
-	return ctx.GetRootSymbol(root)
+	return getCatalogRootSymbol(ctx, root)
+}
+
+//=============================================================================
+
+func GetInstruments(c *auth.Context, connectionCode string, root string, includeExpired bool) ([]*adapter.Instrument, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	return getCatalogInstruments(ctx, root, includeExpired)
 }
 
 //=============================================================================
 
-func GetInstruments(c *auth.Context, connectionCode string, root string) ([]*adapter.Instrument, error){
+func GetPriceBars(c *auth.Context, connectionCode string, rq *adapter.PriceBarsRequest) (*adapter.PriceBars, error){
 	ctx,err := getConnectionContext(c, connectionCode)
 	if err != nil {
 		return nil,err
 	}
 
-	return ctx.GetInstruments(root)
+	err = rq.Validate()
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid price bars request: %v", err.Error())
+	}
+
+	err = resolveCalendar(ctx, rq)
+	if err != nil {
+		return nil,err
+	}
+
+	err = checkSymbolExchange(ctx, rq.Symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	return ctx.GetPriceBars(rq)
 }
 
 //=============================================================================
+//--- Expired contracts come from the catalog, so history goes back as far as the
+//--- catalog has seen the root's instruments
 
-func GetPriceBars(c *auth.Context, connectionCode string, symbol string, date datatype.IntDate) (*adapter.PriceBars, error){
+func GetContinuousBars(c *auth.Context, connectionCode string, rq *adapter.ContinuousRequest) (*adapter.ContinuousBars, error){
 	ctx,err := getConnectionContext(c, connectionCode)
 	if err != nil {
 		return nil,err
 	}
 
-	return ctx.GetPriceBars(symbol, date)
+	err = rq.Validate()
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid continuous bars request: %v", err.Error())
+	}
+
+	err = resolveCalendar(ctx, &rq.PriceBarsRequest)
+	if err != nil {
+		return nil,err
+	}
+
+	list,err := getCatalogInstruments(ctx, rq.Symbol, true)
+	if err != nil {
+		return nil,err
+	}
+
+	return adapter.BuildContinuousBars(ctx, list, rq)
 }
 
 //=============================================================================
@@ -290,14 +503,118 @@ func GetAccounts(c *auth.Context, connectionCode string) ([]*adapter.Account, er
 
 //=============================================================================
 
-func GetOrders(c *auth.Context, connectionCode string) (any, error){
-	return nil,nil
+func GetOrders(c *auth.Context, connectionCode string) ([]*adapter.Order, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	return ctx.GetOrders()
+}
+
+//=============================================================================
+
+func PlaceOrder(c *auth.Context, connectionCode string, o *adapter.Order) (*adapter.Order, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	err = o.Validate()
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid order: %v", err.Error())
+	}
+
+	err = checkSymbolExchange(ctx, o.Symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	po,err := ctx.PlaceOrder(o)
+	if err == nil {
+		c.Log.Info("PlaceOrder: Order placed", "connection", connectionCode, "id", po.Id, "symbol", po.Symbol, "side", po.Side, "quantity", po.Quantity)
+	}
+
+	return po,err
+}
+
+//=============================================================================
+
+func ModifyOrder(c *auth.Context, connectionCode string, id string, oc *adapter.OrderChange) (*adapter.Order, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	err = oc.Validate()
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid order change: %v", err.Error())
+	}
+
+	return ctx.ModifyOrder(id, oc)
+}
+
+//=============================================================================
+
+func CancelOrder(c *auth.Context, connectionCode string, id string) error {
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.CancelOrder(id)
+	if err == nil {
+		c.Log.Info("CancelOrder: Order cancelled", "connection", connectionCode, "id", id)
+	}
+
+	return err
+}
+
+//=============================================================================
+
+func GetPositions(c *auth.Context, connectionCode string) ([]*adapter.Position, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	return ctx.GetPositions()
+}
+
+//=============================================================================
+
+func Subscribe(c *auth.Context, connectionCode string, symbol string, st adapter.StreamType) (*adapter.StreamClient, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	if st != adapter.StreamTypeQuotes && st != adapter.StreamTypeBars {
+		return nil, req.NewBadRequestError("Invalid stream type: %v", st)
+	}
+
+	err = checkSymbolExchange(ctx, symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	return ctx.Subscribe(symbol, st)
 }
 
 //=============================================================================
 
-func GetPositions(c *auth.Context, connectionCode string) (any, error){
-	return nil,nil
+func Replay(c *auth.Context, connectionCode string, spec *ReplaySpec) (*adapter.ReplayResult, error){
+	ctx,err := getConnectionContext(c, connectionCode)
+	if err != nil {
+		return nil,err
+	}
+
+	err = checkSymbolExchange(ctx, spec.Symbol)
+	if err != nil {
+		return nil,err
+	}
+
+	return ctx.Replay(spec.Symbol, datatype.IntDate(spec.Date))
 }
 
 //=============================================================================
@@ -328,22 +645,236 @@ func TestAdapter(c *auth.Context, connectionCode string, tar *TestAdapterRequest
 //=============================================================================
 
 func sendConnectionChangeMessage(c *auth.Context, ctx *adapter.ConnectionContext) error {
+	err := publishConnectionChange(ctx)
+
+	if err != nil {
+		c.Log.Error("sendConnectionChangeMessage: Could not publish the change message", "error", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func initWebLogin(cfg *app.WebLogin) {
+	webLogin = *cfg
+
+	if webLogin.BaseUrl == "" {
+		webLogin.BaseUrl = DefaultWebLoginBaseUrl
+	}
+
+	if webLogin.TimeoutSec <= 0 {
+		webLogin.TimeoutSec = DefaultWebLoginTimeout
+	}
+
+	webLogin.BaseUrl = strings.TrimSuffix(webLogin.BaseUrl, "/")
+}
+
+//=============================================================================
+
+func addInstanceCode(ctx *adapter.ConnectionContext) (string, error) {
+	code,err := ctx.NewInstanceCode()
+	if err != nil {
+		return "", err
+	}
+
+	entry := &webLoginEntry{
+		ctx   : ctx,
+		expiry: time.Now().Add(time.Duration(webLogin.TimeoutSec) * time.Second),
+	}
+
+	instanceCodes.Lock()
+	instanceCodes.m[code] = entry
+	instanceCodes.Unlock()
+
+	return code, nil
+}
+
+//=============================================================================
+
+func removeInstanceCode(code string) {
+	if code == "" {
+		return
+	}
+
+	instanceCodes.Lock()
+	delete(instanceCodes.m, code)
+	instanceCodes.Unlock()
+}
+
+//=============================================================================
+
+func publishConnectionChange(ctx *adapter.ConnectionContext) error {
 	ccm := ConnectionChangeSystemMessage{
 		Username      : ctx.Username,
 		ConnectionCode: ctx.ConnectionCode,
 		SystemCode    : ctx.GetAdapterInfo().Code,
 		Status        : ctx.GetStatus(),
 	}
-	err := msg.SendMessage(msg.ExSystem, msg.SourceConnection, msg.TypeChange, &ccm)
 
+	return msg.SendMessage(msg.ExSystem, msg.SourceConnection, msg.TypeChange, &ccm)
+}
+
+//=============================================================================
+//--- Connections that use another connection as price feed are restored last,
+//--- when their feed is already in place
+
+func restoreConnections() {
+	list,err := store.Load()
+	if err != nil {
+		slog.Error("restoreConnections: Cannot load stored connections", "error", err.Error())
+		return
+	}
+
+	userConnections.Lock()
+	defer userConnections.Unlock()
+
+	var withFeed []*StoredConnection
+
+	for _,sc := range list {
+		if feedCode,_ := sc.ConfigParams[adapter.ParamFeedConnection].(string); feedCode != "" {
+			withFeed = append(withFeed, sc)
+		} else {
+			restoreConnection(sc)
+		}
+	}
+
+	for _,sc := range withFeed {
+		restoreConnection(sc)
+	}
+}
+
+//=============================================================================
+
+func restoreConnection(sc *StoredConnection) {
+	ad,ok := adapters[sc.SystemCode]
+	if !ok {
+		slog.Warn("restoreConnection: System not found. Dropping connection", "username", sc.Username, "connection", sc.ConnectionCode, "system", sc.SystemCode)
+		unstoreConnection(sc.Username, sc.ConnectionCode)
+		return
+	}
+
+	uc,found := userConnections.m[sc.Username]
+	if !found {
+		uc = NewUserConnections()
+		userConnections.m[sc.Username] = uc
+	}
+
+	ctx,err := adapter.RestoreConnectionContext(sc.Username, sc.ConnectionCode, sc.Host, ad, sc.ConfigParams)
+	if err == nil {
+		//--- Connect params given at connect time are not stored, so a reconnection uses the credential only
+		setCredentialProvider(ctx, &ConnectionSpec{ SystemCode: sc.SystemCode, CredentialId: sc.CredentialId })
+		err = setPriceFeed(uc, sc.ConnectionCode, ctx, sc.ConfigParams)
+		if err == nil {
+			err = ctx.Restore(sc.RefreshToken)
+		}
+	}
+
+	if err != nil {
+		slog.Warn("restoreConnection: Cannot restore connection. A new login is needed", "username", sc.Username, "connection", sc.ConnectionCode, "error", err.Error())
+		unstoreConnection(sc.Username, sc.ConnectionCode)
+		return
+	}
+
+	uc.contexts[sc.ConnectionCode] = ctx
+	updatePriceFeeds(uc, sc.ConnectionCode, ctx)
+
+	//--- The refresh may have rotated the token
+	storeConnection(ctx)
+
+	err = publishConnectionChange(ctx)
+	if err != nil {
+		slog.Error("restoreConnection: Could not publish the change message", "username", sc.Username, "connection", sc.ConnectionCode, "error", err.Error())
+	}
+
+	slog.Info("restoreConnection: Connection restored", "username", sc.Username, "connection", sc.ConnectionCode, "system", sc.SystemCode)
+}
+
+//=============================================================================
+//--- The credential is resolved again at each reconnection, so that vault changes apply
+
+func setCredentialProvider(ctx *adapter.ConnectionContext, cs *ConnectionSpec) {
+	if cs.CredentialId == "" {
+		return
+	}
+
+	spec := *cs
+	ctx.CredentialId = cs.CredentialId
+	ctx.SetCredentialProvider(func() (map[string]any, error) {
+		return resolveConnectParams(ctx.Username, &spec)
+	})
+}
+
+//=============================================================================
+
+func setPriceFeed(uc *UserConnections, connectionCode string, ctx *adapter.ConnectionContext, configParams map[string]any) error {
+	feedCode,_ := configParams[adapter.ParamFeedConnection].(string)
+	if feedCode == "" {
+		return nil
+	}
+
+	feed,found := uc.contexts[feedCode]
+	if !found {
+		return errors.New("Feed connection not found: "+ feedCode)
+	}
+
+	err := checkPriceFeed(uc, connectionCode, configParams)
 	if err != nil {
-		c.Log.Error("sendConnectionChangeMessage: Could not publish the change message", "error", err.Error())
 		return err
 	}
 
+	return ctx.SetPriceFeed(feed)
+}
+
+//=============================================================================
+//--- Follows the chain of feed connections: reaching connectionCode again would
+//--- make price bar requests recurse forever
+
+func checkPriceFeed(uc *UserConnections, connectionCode string, configParams map[string]any) error {
+	visited    := map[string]bool{}
+	feedCode,_ := configParams[adapter.ParamFeedConnection].(string)
+
+	for feedCode != "" && !visited[feedCode] {
+		if feedCode == connectionCode {
+			return req.NewBadRequestError("The price feed of connection %v leads back to itself", connectionCode)
+		}
+
+		visited[feedCode] = true
+
+		feed,found := uc.contexts[feedCode]
+		if !found {
+			return nil
+		}
+
+		feedCode,_ = feed.GetConfigParams()[adapter.ParamFeedConnection].(string)
+	}
+
 	return nil
 }
 
+//=============================================================================
+//--- Connections that use feedCode as price feed must not keep a reference to a
+//--- disconnected (or replaced) context, so they get the new one or nil
+
+func updatePriceFeeds(uc *UserConnections, feedCode string, feed *adapter.ConnectionContext) {
+	var pf adapter.PriceFeed
+	if feed != nil {
+		pf = feed
+	}
+
+	for code, ctx := range uc.contexts {
+		if code == feedCode || ctx.GetConfigParams()[adapter.ParamFeedConnection] != feedCode {
+			continue
+		}
+
+		err := ctx.SetPriceFeed(pf)
+		if err != nil {
+			slog.Warn("updatePriceFeeds: Cannot set the price feed", "username", ctx.Username, "connection", code, "feed", feedCode, "error", err.Error())
+		}
+	}
+}
+
 //=============================================================================
 
 func getConnectionContext(c *auth.Context, connectionCode string) (*adapter.ConnectionContext, error) {
@@ -366,3 +897,29 @@ func getConnectionContext(c *auth.Context, connectionCode string) (*adapter.Conn
 }
 
 //=============================================================================
+//--- Adapters address contracts by root and month only, so the exchange of a
+//--- canonical symbol is checked here against the root's one, from the catalog
+
+func checkSymbolExchange(ctx *adapter.ConnectionContext, symbol string) error {
+	if !adapter.IsCanonicalSymbol(symbol) {
+		return nil
+	}
+
+	cs,err := adapter.ParseCanonicalSymbol(symbol)
+	if err != nil {
+		return req.NewBadRequestError("Invalid symbol: %v", err.Error())
+	}
+
+	rs,err := getCatalogRootSymbol(ctx, cs.Root)
+	if err != nil {
+		return err
+	}
+
+	if rs.Exchange != "" && !strings.EqualFold(rs.Exchange, cs.Exchange) {
+		return req.NewBadRequestError("Exchange %v does not match the one of root %v: %v", cs.Exchange, cs.Root, rs.Exchange)
+	}
+
+	return nil
+}
+
+//=============================================================================
@@ -0,0 +1,182 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/adapter/local"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//--- Run with -race: the expiration reads the instance code while a new web
+//--- login is writing it
+
+func TestExpireWebLoginsWhileStarting(t *testing.T) {
+	initWebLogin(&app.WebLogin{ TimeoutSec: 60 })
+
+	ctx,err := adapter.NewConnectionContext("tester", "C1", "localhost", local.NewAdapter(), map[string]any{}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for range 100 {
+			code,err := addInstanceCode(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			removeInstanceCode(code)
+		}
+	}()
+
+	for running := true; running; {
+		select {
+			case <-done:
+				running = false
+			default:
+				ExpireWebLogins()
+		}
+	}
+
+	code,err := addInstanceCode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { removeInstanceCode(code) })
+
+	if GetConnectionContextByInstanceCode(code) != ctx || ctx.GetInstanceCode() != code {
+		t.Errorf("the context must be found by its instance code")
+	}
+}
+
+//=============================================================================
+//--- An expired code must be rejected even while the context is busy
+
+func TestExpiredInstanceCodeIsRejected(t *testing.T) {
+	initWebLogin(&app.WebLogin{ TimeoutSec: 60 })
+
+	ctx,err := adapter.NewConnectionContext("tester", "C1", "localhost", local.NewAdapter(), map[string]any{}, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code,err := addInstanceCode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { removeInstanceCode(code) })
+
+	instanceCodes.Lock()
+	instanceCodes.m[code].expiry = time.Now().Add(-time.Second)
+	instanceCodes.Unlock()
+
+	ctx.Lock()
+	found := GetConnectionContextByInstanceCode(code)
+	ctx.Unlock()
+
+	if found != nil {
+		t.Errorf("an expired instance code must not be accepted")
+	}
+}
+
+//=============================================================================
+
+func TestPriceFeedFollowsTheFeedConnection(t *testing.T) {
+	newContext := func(code string, configParams map[string]any) *adapter.ConnectionContext {
+		ctx,err := adapter.NewConnectionContext("tester", code, "localhost", local.NewAdapter(), configParams, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ctx
+	}
+
+	uc   := NewUserConnections()
+	feed := newContext("FEED", map[string]any{})
+	sim  := newContext("SIM",  map[string]any{ adapter.ParamFeedConnection: "FEED" })
+
+	uc.contexts["FEED"] = feed
+	uc.contexts["SIM"]  = sim
+
+	updatePriceFeeds(uc, "FEED", feed)
+
+	if _,err := sim.Replay("ESH25", 20250102); err == nil || strings.Contains(err.Error(), "No price feed") {
+		t.Fatalf("the replay must use the feed connection, got: %v", err)
+	}
+
+	//--- The feed is disconnected
+
+	delete(uc.contexts, "FEED")
+	updatePriceFeeds(uc, "FEED", nil)
+
+	if _,err := sim.Replay("ESH25", 20250102); err == nil || !strings.Contains(err.Error(), "No price feed") {
+		t.Fatalf("the replay must fail without a feed connection, got: %v", err)
+	}
+}
+
+//=============================================================================
+
+func TestPriceFeedCycle(t *testing.T) {
+	uc := NewUserConnections()
+
+	//--- C takes its bars from B, that takes them from A
+
+	for code, feed := range map[string]string{ "A": "", "B": "A", "C": "B" } {
+		ctx,err := adapter.NewConnectionContext("tester", code, "localhost", local.NewAdapter(), map[string]any{ adapter.ParamFeedConnection: feed }, map[string]any{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uc.contexts[code] = ctx
+	}
+
+	for _, tc := range []struct {
+		connection string
+		feed       string
+		cycle      bool
+	}{
+		{ "A", "",  false },
+		{ "A", "A", true  },
+		{ "A", "B", true  },
+		{ "A", "C", true  },
+		{ "B", "C", true  },
+		{ "D", "C", false },
+		{ "D", "X", false },
+	} {
+		err := checkPriceFeed(uc, tc.connection, map[string]any{ adapter.ParamFeedConnection: tc.feed })
+		if (err != nil) != tc.cycle {
+			t.Errorf("connection %s with feed '%s': expected cycle %v, got %v", tc.connection, tc.feed, tc.cycle, err)
+		}
+	}
+}
+
+//=============================================================================
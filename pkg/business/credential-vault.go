@@ -0,0 +1,385 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bit-fever/core/auth"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/app"
+)
+
+//=============================================================================
+//===
+//=== Credential vault: users register the connect params of a system once and
+//=== reference them by id when connecting. Values of password params are kept
+//=== encrypted, both on disk and in memory, and decrypted only to connect
+//===
+//=============================================================================
+
+const DefaultVaultPath = "config/credentials.json"
+
+//=============================================================================
+
+type credential struct {
+	Id         string         `json:"id"`
+	Username   string         `json:"username"`
+	SystemCode string         `json:"systemCode"`
+	Name       string         `json:"name"`
+	Params     map[string]any `json:"params"`
+}
+
+//-----------------------------------------------------------------------------
+
+type credentialVault struct {
+	sync.RWMutex
+	path        string
+	crypter     *crypter
+	credentials map[string]*credential
+}
+
+//=============================================================================
+
+var vault *credentialVault
+
+//=============================================================================
+//===
+//=== Public methods
+//===
+//=============================================================================
+
+func GetCredentials(c *auth.Context) (*[]*CredentialInfo, error) {
+	if vault == nil {
+		return nil, req.NewServiceUnavailableError("Credential vault is not configured")
+	}
+
+	vault.RLock()
+	defer vault.RUnlock()
+
+	list := []*CredentialInfo{}
+
+	for _,cr := range vault.credentials {
+		if cr.Username == c.Session.Username {
+			list = append(list, cr.toInfo())
+		}
+	}
+
+	return &list, nil
+}
+
+//=============================================================================
+
+func AddCredential(c *auth.Context, spec *CredentialSpec) (*CredentialInfo, error) {
+	if vault == nil {
+		return nil, req.NewServiceUnavailableError("Credential vault is not configured")
+	}
+
+	ad,ok := adapters[spec.SystemCode]
+	if !ok {
+		return nil, req.NewNotFoundError("System not found: %v", spec.SystemCode)
+	}
+
+	params := map[string]any{}
+
+	for name, value := range spec.Params {
+		p := findParam(ad.GetInfo().ConnectParams, name)
+		if p == nil {
+			return nil, req.NewBadRequestError("Unknown connect parameter: %v", name)
+		}
+
+		if p.Type == adapter.ParamTypePassword {
+			s,_ := value.(string)
+			enc,err := vault.crypter.encryptString(s)
+			if err != nil {
+				return nil, req.NewServerErrorByError(err)
+			}
+			value = enc
+		}
+
+		params[name] = value
+	}
+
+	id,err := newCredentialId()
+	if err != nil {
+		return nil, req.NewServerErrorByError(err)
+	}
+
+	cr := &credential{
+		Id        : id,
+		Username  : c.Session.Username,
+		SystemCode: spec.SystemCode,
+		Name      : spec.Name,
+		Params    : params,
+	}
+
+	vault.Lock()
+	defer vault.Unlock()
+
+	vault.credentials[id] = cr
+	err = vault.write()
+	if err != nil {
+		delete(vault.credentials, id)
+		return nil, req.NewServerErrorByError(err)
+	}
+
+	c.Log.Info("AddCredential: Credential registered", "id", id, "system", spec.SystemCode)
+	return cr.toInfo(), nil
+}
+
+//=============================================================================
+
+func DeleteCredential(c *auth.Context, id string) error {
+	if vault == nil {
+		return req.NewServiceUnavailableError("Credential vault is not configured")
+	}
+
+	vault.Lock()
+	defer vault.Unlock()
+
+	cr,found := vault.credentials[id]
+	if !found || cr.Username != c.Session.Username {
+		return req.NewNotFoundError("Credential not found: %v", id)
+	}
+
+	delete(vault.credentials, id)
+	err := vault.write()
+	if err != nil {
+		vault.credentials[id] = cr
+		return req.NewServerErrorByError(err)
+	}
+
+	return nil
+}
+
+//=============================================================================
+//===
+//=== Private methods
+//===
+//=============================================================================
+
+func initVault(cfg *app.Vault) {
+	key := cfg.Key
+
+	if cfg.KeyFile != "" {
+		data,err := os.ReadFile(cfg.KeyFile)
+		if err != nil {
+			slog.Error("initVault: Cannot read the key file. Credential vault is disabled", "error", err.Error())
+			return
+		}
+		key = strings.TrimSpace(string(data))
+	}
+
+	if key == "" {
+		slog.Info("initVault: No key configured. Credential vault is disabled")
+		return
+	}
+
+	cr,err := newCrypter(key)
+	if err != nil {
+		slog.Error("initVault: Cannot create the crypter. Credential vault is disabled", "error", err.Error())
+		return
+	}
+
+	path := cfg.Path
+	if path == "" {
+		path = DefaultVaultPath
+	}
+
+	v := &credentialVault{
+		path       : path,
+		crypter    : cr,
+		credentials: map[string]*credential{},
+	}
+
+	err = v.read()
+	if err != nil {
+		slog.Error("initVault: Cannot read the credentials file. Credential vault is disabled", "error", err.Error())
+		return
+	}
+
+	vault = v
+}
+
+//=============================================================================
+//--- Returns the connect params of a credential, with password values decrypted.
+//--- Params provided at connect time (like the 2FA code) take precedence
+
+func resolveConnectParams(username string, cs *ConnectionSpec) (map[string]any, error) {
+	if cs.CredentialId == "" {
+		return cs.ConnectParams, nil
+	}
+
+	if vault == nil {
+		return nil, req.NewServiceUnavailableError("Credential vault is not configured")
+	}
+
+	vault.RLock()
+	defer vault.RUnlock()
+
+	cr,found := vault.credentials[cs.CredentialId]
+	if !found || cr.Username != username {
+		return nil, req.NewNotFoundError("Credential not found: %v", cs.CredentialId)
+	}
+
+	if cr.SystemCode != cs.SystemCode {
+		return nil, req.NewBadRequestError("Credential %v is not for system %v", cs.CredentialId, cs.SystemCode)
+	}
+
+	ad := adapters[cr.SystemCode]
+	params := map[string]any{}
+
+	for name, value := range cr.Params {
+		p := findParam(ad.GetInfo().ConnectParams, name)
+		if p != nil && p.Type == adapter.ParamTypePassword {
+			s,_ := value.(string)
+			dec,err := vault.crypter.decryptString(s)
+			if err != nil {
+				return nil, req.NewServerErrorByError(errors.New("cannot decrypt credential (wrong key?) : "+ err.Error()))
+			}
+			value = dec
+		}
+
+		params[name] = value
+	}
+
+	for name, value := range cs.ConnectParams {
+		params[name] = value
+	}
+
+	return params, nil
+}
+
+//=============================================================================
+
+func (v *credentialVault) read() error {
+	data,err := os.ReadFile(v.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	var list []*credential
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return err
+	}
+
+	for _,cr := range list {
+		v.credentials[cr.Id] = cr
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func (v *credentialVault) write() error {
+	list := []*credential{}
+	for _,cr := range v.credentials {
+		list = append(list, cr)
+	}
+
+	data,err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(v.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp := v.path +".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, v.path)
+}
+
+//=============================================================================
+//--- Password values are never returned, not even encrypted
+
+func (cr *credential) toInfo() *CredentialInfo {
+	ad,_ := adapters[cr.SystemCode]
+	params := map[string]any{}
+
+	for name, value := range cr.Params {
+		if ad != nil {
+			p := findParam(ad.GetInfo().ConnectParams, name)
+			if p != nil && p.Type == adapter.ParamTypePassword {
+				continue
+			}
+		}
+		params[name] = value
+	}
+
+	return &CredentialInfo{
+		Id        : cr.Id,
+		SystemCode: cr.SystemCode,
+		Name      : cr.Name,
+		Params    : params,
+	}
+}
+
+//=============================================================================
+
+func findParam(params []*adapter.ParamDef, name string) *adapter.ParamDef {
+	for _,p := range params {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+//=============================================================================
+
+func newCredentialId() (string, error) {
+	b := make([]byte, 8)
+	_,err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+//=============================================================================
@@ -0,0 +1,174 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+//=============================================================================
+//===
+//=== File store: all connections are kept in a single file, encrypted as a
+//=== whole and rewritten on every change. Writes are refused until the file
+//=== has been loaded: rewriting a file that could not be read (wrong key,
+//=== corrupted content) would silently drop all the other connections
+//===
+//=============================================================================
+
+const DefaultStorePath = "config/connections.dat"
+
+//=============================================================================
+
+type fileStore struct {
+	sync.Mutex
+	path        string
+	crypter     *crypter
+	connections map[string]*StoredConnection
+	loaded      bool
+}
+
+//=============================================================================
+
+func newFileStore(path string, cr *crypter) *fileStore {
+	if path == "" {
+		path = DefaultStorePath
+	}
+
+	return &fileStore{
+		path       : path,
+		crypter    : cr,
+		connections: map[string]*StoredConnection{},
+	}
+}
+
+//=============================================================================
+
+func (s *fileStore) Load() ([]*StoredConnection, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	data,err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.loaded = true
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data,err = s.crypter.decrypt(data)
+	if err != nil {
+		return nil, errors.New("cannot decrypt the connection store (wrong key?) : "+ err.Error())
+	}
+
+	var list []*StoredConnection
+	err = json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	for _,sc := range list {
+		s.connections[buildStoreKey(sc.Username, sc.ConnectionCode)] = sc
+	}
+
+	s.loaded = true
+	return list, nil
+}
+
+//=============================================================================
+
+func (s *fileStore) Save(sc *StoredConnection) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.connections[buildStoreKey(sc.Username, sc.ConnectionCode)] = sc
+	return s.write()
+}
+
+//=============================================================================
+
+func (s *fileStore) Delete(username string, connectionCode string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	key := buildStoreKey(username, connectionCode)
+	if _,found := s.connections[key]; !found {
+		return nil
+	}
+
+	delete(s.connections, key)
+	return s.write()
+}
+
+//=============================================================================
+//--- The file is written to a temporary file first, so that a crash cannot
+//--- leave a truncated store behind
+
+func (s *fileStore) write() error {
+	if !s.loaded {
+		return errors.New("the connection store has not been loaded: refusing to overwrite "+ s.path)
+	}
+
+	list := []*StoredConnection{}
+	for _,sc := range s.connections {
+		list = append(list, sc)
+	}
+
+	data,err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+
+	data,err = s.crypter.encrypt(data)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(s.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	tmp := s.path +".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, s.path)
+}
+
+//=============================================================================
+
+func buildStoreKey(username string, connectionCode string) string {
+	return username +"/"+ connectionCode
+}
+
+//=============================================================================
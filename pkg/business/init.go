@@ -26,14 +26,45 @@ package business
 
 import (
 	"github.com/bit-fever/core/msg"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
+	"github.com/bit-fever/system-adapter/pkg/app"
+	"github.com/bit-fever/system-adapter/pkg/metrics"
 	"log/slog"
 	"os"
 )
 
 //=============================================================================
+//--- Consumers reset their state on the restart message, then receive a change
+//--- message for every connection that has been restored
 
-func Init() {
+func Init(cfg *app.Config) {
+	adapter.InitRedaction(&cfg.Redaction)
+	adapter.InitCassettes(&cfg.Cassette)
+	adapter.InitBarCache(&cfg.BarCache)
+
+	err := adapter.InitHttpClients(&cfg.HttpClient)
+	if err != nil {
+		slog.Error("Init: Cannot configure the HTTP clients", "error", err.Error())
+		os.Exit(1)
+	}
+
+	err = adapter.InitCalendars(&cfg.Calendars)
+	if err != nil {
+		slog.Error("Init: Cannot load the trading calendars", "error", err.Error())
+		os.Exit(1)
+	}
+
+	metrics.Connections.SetCollector(CountConnectionsByStatus)
+
+	initStore(&cfg.ConnectionStore)
+	initVault(&cfg.Vault)
+	initWebLogin(&cfg.WebLogin)
+	initCatalog(&cfg.Catalog)
 	sendSystemRestartMessage()
+
+	if store != nil {
+		restoreConnections()
+	}
 }
 
 //=============================================================================
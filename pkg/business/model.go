@@ -25,8 +25,10 @@ THE SOFTWARE.
 package business
 
 import (
+	"github.com/bit-fever/core/datatype"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"sync"
+	"time"
 )
 
 //=============================================================================
@@ -34,17 +36,36 @@ import (
 type ConnectionSpec struct {
 	SystemCode     string         `json:"systemCode"     binding:"required"`
 	ConfigParams   map[string]any `json:"configParams"   binding:"required"`
-	ConnectParams  map[string]any `json:"connectParams"  binding:"required"`
+	ConnectParams  map[string]any `json:"connectParams"`
+	CredentialId   string         `json:"credentialId"`
+}
+
+//=============================================================================
+
+type CredentialSpec struct {
+	SystemCode string         `json:"systemCode" binding:"required"`
+	Name       string         `json:"name"       binding:"required"`
+	Params     map[string]any `json:"params"     binding:"required"`
+}
+
+//-----------------------------------------------------------------------------
+
+type CredentialInfo struct {
+	Id         string         `json:"id"`
+	SystemCode string         `json:"systemCode"`
+	Name       string         `json:"name"`
+	Params     map[string]any `json:"params"`
 }
 
 //=============================================================================
 
 type ConnectionInfo struct {
-	Username       string `json:"username"`
-	ConnectionCode string `json:"connectionCode"`
-	SystemCode     string `json:"systemCode"`
-	SystemName     string `json:"systemName"`
-	Status         string `json:"status"`
+	Username       string     `json:"username"`
+	ConnectionCode string     `json:"connectionCode"`
+	SystemCode     string     `json:"systemCode"`
+	SystemName     string     `json:"systemName"`
+	Status         string     `json:"status"`
+	NextRefresh    *time.Time `json:"nextRefresh"`
 }
 
 //=============================================================================
@@ -76,12 +97,13 @@ type ConnectionChangeSystemMessage struct {
 //=============================================================================
 
 const (
-	ConnectionStatusConnecting = "connecting"
-	ConnectionStatusConnected  = "connected"
-	ConnectionStatusError      = "error"
+	ConnectionStatusConnecting   = "connecting"
+	ConnectionStatusConnected    = "connected"
+	ConnectionStatusReconnecting = "reconnecting"
+	ConnectionStatusError        = "error"
 
-	ConnectionActionNone       = "none"
-	ConnectionActionOpenUrl    = "open-url"
+	ConnectionActionNone         = "none"
+	ConnectionActionOpenUrl      = "open-url"
 )
 
 //-----------------------------------------------------------------------------
@@ -94,9 +116,44 @@ type ConnectionResult struct {
 
 //=============================================================================
 
+const (
+	DefaultWebLoginBaseUrl = "https://bitfever-server:8449"
+	DefaultWebLoginTimeout = 10*60
+)
+
+//=============================================================================
+
 type TestAdapterRequest struct {
 	Service string `json:"service"`
 	Query   string `json:"query"`
 }
 
 //=============================================================================
+
+type ReplaySpec struct {
+	Symbol string `json:"symbol" binding:"required"`
+	Date   int    `json:"date"   binding:"required"`
+}
+
+//=============================================================================
+
+type BarCacheFilter struct {
+	Adapter string
+	Symbol  string
+	From    datatype.IntDate
+	To      datatype.IntDate
+}
+
+//-----------------------------------------------------------------------------
+
+type BarCacheInvalidation struct {
+	Deleted int `json:"deleted"`
+}
+
+//=============================================================================
+
+//--- Maximum range of a trading days request
+
+const MaxTradingDays = 366
+
+//=============================================================================
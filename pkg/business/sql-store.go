@@ -0,0 +1,218 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package business
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+//=============================================================================
+//===
+//=== SQL store: one row per connection. Only the refresh token is encrypted.
+//=== The driver must be linked into the binary (blank import) to be usable
+//===
+//=============================================================================
+
+const sqlCreateTable = `
+	CREATE TABLE IF NOT EXISTS stored_connection (
+		username        VARCHAR(64)  NOT NULL,
+		connection_code VARCHAR(64)  NOT NULL,
+		system_code     VARCHAR(16)  NOT NULL,
+		host            VARCHAR(255) NOT NULL,
+		config_params   TEXT         NOT NULL,
+		refresh_token   TEXT         NOT NULL,
+		credential_id   VARCHAR(64)  NOT NULL DEFAULT '',
+		PRIMARY KEY (username, connection_code)
+	)`
+
+//--- Tables created before the credential id was stored. The column is added
+//--- only when the probe query fails
+
+const (
+	sqlProbeCredentialId = `SELECT credential_id FROM stored_connection WHERE 1=0`
+	sqlAddCredentialId   = `ALTER TABLE stored_connection ADD COLUMN credential_id VARCHAR(64) NOT NULL DEFAULT ''`
+)
+
+//=============================================================================
+
+type sqlStore struct {
+	db       *sql.DB
+	crypter  *crypter
+	numbered bool
+}
+
+//=============================================================================
+
+func newSqlStore(driver string, dsn string, cr *crypter) (*sqlStore, error) {
+	if driver == "" || dsn == "" {
+		return nil, errors.New("missing driver or dsn for the sql store")
+	}
+
+	db,err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_,err = db.Exec(sqlCreateTable)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	err = addCredentialId(db)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &sqlStore{
+		db      : db,
+		crypter : cr,
+		numbered: driver == "postgres" || driver == "pgx",
+	}, nil
+}
+
+//=============================================================================
+
+func (s *sqlStore) Load() ([]*StoredConnection, error) {
+	rows,err := s.db.Query("SELECT username, connection_code, system_code, host, config_params, refresh_token, credential_id FROM stored_connection")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*StoredConnection
+
+	for rows.Next() {
+		var sc StoredConnection
+		var params, token string
+
+		err = rows.Scan(&sc.Username, &sc.ConnectionCode, &sc.SystemCode, &sc.Host, &params, &token, &sc.CredentialId)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(params), &sc.ConfigParams)
+		if err != nil {
+			return nil, err
+		}
+
+		sc.RefreshToken,err = s.crypter.decryptString(token)
+		if err != nil {
+			return nil, errors.New("cannot decrypt a refresh token (wrong key?) : "+ err.Error())
+		}
+
+		list = append(list, &sc)
+	}
+
+	return list, rows.Err()
+}
+
+//=============================================================================
+
+func (s *sqlStore) Save(sc *StoredConnection) error {
+	params,err := json.Marshal(sc.ConfigParams)
+	if err != nil {
+		return err
+	}
+
+	token,err := s.crypter.encryptString(sc.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	//--- Delete + insert is portable across databases, unlike upsert
+
+	tx,err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	_,err = tx.Exec(s.rebind("DELETE FROM stored_connection WHERE username = ? AND connection_code = ?"), sc.Username, sc.ConnectionCode)
+	if err == nil {
+		_,err = tx.Exec(s.rebind("INSERT INTO stored_connection (username, connection_code, system_code, host, config_params, refresh_token, credential_id) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+			sc.Username, sc.ConnectionCode, sc.SystemCode, sc.Host, string(params), token, sc.CredentialId)
+	}
+
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+//=============================================================================
+
+func (s *sqlStore) Delete(username string, connectionCode string) error {
+	_,err := s.db.Exec(s.rebind("DELETE FROM stored_connection WHERE username = ? AND connection_code = ?"), username, connectionCode)
+	return err
+}
+
+//=============================================================================
+//--- Converts '?' placeholders into '$n' for drivers that need them
+
+func (s *sqlStore) rebind(query string) string {
+	if !s.numbered {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+
+	for _,c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString("$"+ strconv.Itoa(n))
+		} else {
+			sb.WriteRune(c)
+		}
+	}
+
+	return sb.String()
+}
+
+//=============================================================================
+
+func addCredentialId(db *sql.DB) error {
+	rows,err := db.Query(sqlProbeCredentialId)
+	if err == nil {
+		return rows.Close()
+	}
+
+	_,err = db.Exec(sqlAddCredentialId)
+	if err != nil {
+		return errors.New("cannot add the credential_id column to the sql store : "+ err.Error())
+	}
+
+	return nil
+}
+
+//=============================================================================
@@ -0,0 +1,202 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+//=============================================================================
+//===
+//=== Service metrics
+//===
+//=============================================================================
+
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+//=============================================================================
+
+var DefaultBuckets = []float64{ .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60 }
+
+//=============================================================================
+
+var adapterCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_adapter_calls_total",
+	Help: "Number of adapter calls made through a connection context",
+}, []string{ "adapter", "service", "result" })
+
+var adapterCallDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name   : "system_adapter_adapter_call_duration_seconds",
+	Help   : "Duration of adapter calls made through a connection context",
+	Buckets: DefaultBuckets,
+}, []string{ "adapter", "service" })
+
+var tokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_token_refreshes_total",
+	Help: "Number of token refreshes run by the scheduler",
+}, []string{ "adapter", "result" })
+
+var reconnections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_reconnections_total",
+	Help: "Number of reconnections run by the scheduler",
+}, []string{ "adapter", "result" })
+
+var priceBarsTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_price_bars_timeouts_total",
+	Help: "Number of GetPriceBars calls retried because of a broker timeout",
+}, []string{ "adapter" })
+
+var barCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_bar_cache_requests_total",
+	Help: "Number of completed days looked up in the price bar cache",
+}, []string{ "adapter", "result" })
+
+var brokerResponses = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "system_adapter_broker_responses_total",
+	Help: "HTTP status codes returned by the broker APIs",
+}, []string{ "host", "code" })
+
+var Connections = newGaugeFunc("system_adapter_connections",
+	"Number of connections per status", "status")
+
+//=============================================================================
+//===
+//=== Public functions
+//===
+//=============================================================================
+
+func ObserveAdapterCall(adapter, service string, start time.Time, err error) {
+	adapterCalls.WithLabelValues(adapter, service, result(err)).Inc()
+	adapterCallDuration.WithLabelValues(adapter, service).Observe(time.Since(start).Seconds())
+}
+
+//=============================================================================
+
+func ObserveTokenRefresh(adapter string, err error) {
+	tokenRefreshes.WithLabelValues(adapter, result(err)).Inc()
+}
+
+//=============================================================================
+
+func ObserveReconnection(adapter string, err error) {
+	reconnections.WithLabelValues(adapter, result(err)).Inc()
+}
+
+//=============================================================================
+
+func ObservePriceBarsTimeout(adapter string) {
+	priceBarsTimeouts.WithLabelValues(adapter).Inc()
+}
+
+//=============================================================================
+
+func ObserveBarCache(adapter string, hit bool) {
+	if hit {
+		barCacheRequests.WithLabelValues(adapter, "hit").Inc()
+	} else {
+		barCacheRequests.WithLabelValues(adapter, "miss").Inc()
+	}
+}
+
+//=============================================================================
+
+func ObserveBrokerResponse(host string, statusCode int) {
+	brokerResponses.WithLabelValues(host, strconv.Itoa(statusCode)).Inc()
+}
+
+//=============================================================================
+
+func result(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+
+	return ResultSuccess
+}
+
+//=============================================================================
+//===
+//=== GaugeFunc: values are collected when the metrics are scraped
+//===
+//=============================================================================
+
+type GaugeFunc struct {
+	sync.Mutex
+	desc    *prometheus.Desc
+	collect func() map[string]float64
+}
+
+//=============================================================================
+
+func newGaugeFunc(name, help, label string) *GaugeFunc {
+	g := &GaugeFunc{
+		desc: prometheus.NewDesc(name, help, []string{ label }, nil),
+	}
+
+	prometheus.MustRegister(g)
+	return g
+}
+
+//=============================================================================
+
+func (g *GaugeFunc) SetCollector(collect func() map[string]float64) {
+	g.Lock()
+	defer g.Unlock()
+
+	g.collect = collect
+}
+
+//=============================================================================
+
+func (g *GaugeFunc) Describe(ch chan<- *prometheus.Desc) {
+	ch <- g.desc
+}
+
+//=============================================================================
+
+func (g *GaugeFunc) Collect(ch chan<- prometheus.Metric) {
+	g.Lock()
+	collect := g.collect
+	g.Unlock()
+
+	if collect == nil {
+		return
+	}
+
+	for key, value := range collect() {
+		ch <- prometheus.MustNewConstMetric(g.desc, prometheus.GaugeValue, value, key)
+	}
+}
+
+//=============================================================================
@@ -26,13 +26,17 @@ package process
 
 import (
 	"github.com/bit-fever/system-adapter/pkg/app"
+	"github.com/bit-fever/system-adapter/pkg/process/catalogrefresh"
 	"github.com/bit-fever/system-adapter/pkg/process/tokenrefresh"
+	"github.com/bit-fever/system-adapter/pkg/process/weblogin"
 )
 
 //=============================================================================
 
 func Init(cfg *app.Config) {
-	tokenrefresh.InitRefresh(cfg)
+	tokenrefresh  .InitRefresh(cfg)
+	weblogin      .InitCleanup(cfg)
+	catalogrefresh.InitRefresh(cfg)
 }
 
 //=============================================================================
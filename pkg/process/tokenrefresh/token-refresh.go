@@ -22,6 +22,7 @@ THE SOFTWARE.
 */
 //=============================================================================
 
+
 package tokenrefresh
 
 import (
@@ -29,21 +30,45 @@ import (
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"github.com/bit-fever/system-adapter/pkg/app"
 	"github.com/bit-fever/system-adapter/pkg/business"
+	"github.com/bit-fever/system-adapter/pkg/metrics"
 	"log/slog"
+	"sync"
 	"time"
 )
 
+//=============================================================================
+//===
+//=== Scheduler: every tick, contexts whose refresh (or reconnection) deadline
+//=== has passed are dispatched to a pool of workers. Deadlines and backoff are
+//=== computed by the contexts themselves
+//===
+//=============================================================================
+
+const (
+	SchedulerTick = time.Second
+	WorkerCount   = 4
+)
+
+//=============================================================================
+
+var inFlight = struct {
+	sync.Mutex
+	m map[*adapter.ConnectionContext]bool
+}{m: make(map[*adapter.ConnectionContext]bool)}
+
 //=============================================================================
 
 func InitRefresh(cfg *app.Config) *time.Ticker {
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(SchedulerTick)
+	jobs   := make(chan *adapter.ConnectionContext, WorkerCount)
 
-	go func() {
-		time.Sleep(10 * time.Second)
-		run()
+	for i:=0; i<WorkerCount; i++ {
+		go worker(jobs)
+	}
 
+	go func() {
 		for range ticker.C {
-			run()
+			schedule(jobs)
 		}
 	}()
 
@@ -52,20 +77,105 @@ func InitRefresh(cfg *app.Config) *time.Ticker {
 
 //=============================================================================
 
-func run() {
+func schedule(jobs chan<- *adapter.ConnectionContext) {
 	list := business.GetConnectionsToRefresh()
+	list  = append(list, business.GetConnectionsToReconnect()...)
 
 	for _, ctx := range list {
-		err := ctx.RefreshToken()
-		if err != nil {
-			slog.Error("TokenRefresher: Cannot refresh token. Disconnecting", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
-			err = sendConnectionChangeMessage(ctx)
-			if err != nil {
-				slog.Error("TokenRefresher:  Could not publish the disconnection message (!)", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
-			}
+		if !acquire(ctx) {
+			continue
+		}
+
+		select {
+			case jobs <- ctx:
+			default:
+				//--- All workers are busy: the context will be picked up on the next tick
+				release(ctx)
+		}
+	}
+}
+
+//=============================================================================
+
+func worker(jobs <-chan *adapter.ConnectionContext) {
+	for ctx := range jobs {
+		if ctx.IsReconnecting() {
+			reconnect(ctx)
 		} else {
-			slog.Info("TokenRefresher: Refreshed token complete", "username", ctx.Username, "connection", ctx.ConnectionCode)
+			refresh(ctx)
 		}
+
+		release(ctx)
+	}
+}
+
+//=============================================================================
+
+func refresh(ctx *adapter.ConnectionContext) {
+	err := business.RefreshToken(ctx)
+	metrics.ObserveTokenRefresh(ctx.GetAdapterInfo().Code, err)
+
+	if err != nil {
+		if ctx.IsConnected() {
+			slog.Warn("TokenRefresher: Cannot refresh token. Retrying", "username", ctx.Username, "connection", ctx.ConnectionCode, "next", ctx.GetNextRefreshTime(), "error", err.Error())
+		} else if ctx.IsReconnecting() {
+			slog.Warn("TokenRefresher: Cannot refresh token. Reconnecting", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+			publishChange(ctx)
+		} else {
+			slog.Error("TokenRefresher: Cannot refresh token. Disconnecting", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+			publishChange(ctx)
+		}
+	} else if ctx.IsConnected() {
+		slog.Info("TokenRefresher: Refreshed token complete", "username", ctx.Username, "connection", ctx.ConnectionCode, "next", ctx.GetNextRefreshTime())
+	}
+}
+
+//=============================================================================
+
+func reconnect(ctx *adapter.ConnectionContext) {
+	err := business.Reconnect(ctx)
+	metrics.ObserveReconnection(ctx.GetAdapterInfo().Code, err)
+
+	if err == nil {
+		slog.Info("TokenRefresher: Reconnection complete", "username", ctx.Username, "connection", ctx.ConnectionCode)
+		publishChange(ctx)
+	} else if ctx.IsDisconnected() {
+		slog.Error("TokenRefresher: Cannot reconnect. Giving up", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+		publishChange(ctx)
+	} else {
+		slog.Warn("TokenRefresher: Cannot reconnect. Retrying later", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
+	}
+}
+
+//=============================================================================
+
+func acquire(ctx *adapter.ConnectionContext) bool {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+
+	if inFlight.m[ctx] {
+		return false
+	}
+
+	inFlight.m[ctx] = true
+	return true
+}
+
+//=============================================================================
+
+func release(ctx *adapter.ConnectionContext) {
+	inFlight.Lock()
+	defer inFlight.Unlock()
+
+	delete(inFlight.m, ctx)
+}
+
+//=============================================================================
+
+func publishChange(ctx *adapter.ConnectionContext) {
+	err := sendConnectionChangeMessage(ctx)
+	if err != nil {
+		slog.Error("TokenRefresher: Could not publish the change message (!)", "username", ctx.Username, "connection", ctx.ConnectionCode, "error", err.Error())
 	}
 }
 
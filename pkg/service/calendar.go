@@ -0,0 +1,61 @@
+//=============================================================================
+/*
+Copyright © 2025 Andrea Carboni andrea.carboni71@gmail.com
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in
+all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+THE SOFTWARE.
+*/
+//=============================================================================
+
+
+package service
+
+import (
+	"github.com/bit-fever/core/auth"
+	"github.com/bit-fever/core/datatype"
+	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/business"
+)
+
+//=============================================================================
+
+func getTradingDays(c *auth.Context) {
+	exchange := c.Gin.Param("exchange")
+
+	from,err := datatype.ParseIntDate(c.Gin.Query("from"), true)
+	if err != nil {
+		c.ReturnError(req.NewBadRequestError("Missing or invalid 'from' parameter"))
+		return
+	}
+
+	to,err := datatype.ParseIntDate(c.Gin.Query("to"), true)
+	if err != nil {
+		c.ReturnError(req.NewBadRequestError("Missing or invalid 'to' parameter"))
+		return
+	}
+
+	res,err := business.GetTradingDays(c, exchange, from, to)
+	if err == nil {
+		_ = c.ReturnList(res, 0, len(res), len(res))
+		return
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
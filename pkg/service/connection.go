@@ -28,15 +28,24 @@ import (
 	"github.com/bit-fever/core/auth"
 	"github.com/bit-fever/core/datatype"
 	"github.com/bit-fever/core/req"
+	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"github.com/bit-fever/system-adapter/pkg/business"
 	"github.com/gin-gonic/gin"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 //=============================================================================
 
+const StreamHeartbeat = time.Second * 15
+
+//=============================================================================
+
 func getConnections(c *auth.Context) {
 	var filter map[string]any
 	offset, limit, err := c.GetPagingParams()
@@ -99,7 +108,7 @@ func webLogin(c *gin.Context) {
 		return
 	}
 
-	c.SetCookie(InstanceCode, code, 0, "", "", true, true)
+	c.SetCookie(InstanceCode, code, 0, "/", "", true, true)
 
 	location := target.Path
 	slog.Info("Redirecting initial request to : "+location)
@@ -145,7 +154,10 @@ func getInstruments(c *auth.Context) {
 	code := c.GetCodeFromUrl()
 	root := c.Gin.Param("root")
 
-	res, err := business.GetInstruments(c, code, root)
+	//--- Expired contracts are returned only on request, for historical lookups
+	includeExpired := c.Gin.Query("includeExpired") == "true"
+
+	res, err := business.GetInstruments(c, code, root, includeExpired)
 	if err == nil {
 		_ = c.ReturnList(res, 0, 10000, len(res))
 		return
@@ -159,16 +171,35 @@ func getInstruments(c *auth.Context) {
 func getPriceBars(c *auth.Context) {
 	code  := c.GetCodeFromUrl()
 	symbol:= c.Gin.Param("symbol")
-	date  := c.Gin.Query("date")
 
-	id,err := datatype.ParseIntDate(date, true)
+	rq,err := parsePriceBarsRequest(c, symbol)
+	if err != nil {
+		c.ReturnError(err)
+		return
+	}
+
+	res, err := business.GetPriceBars(c, code, rq)
+	if err == nil {
+		_ = c.ReturnObject(res)
+		return
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
 
+func getContinuousBars(c *auth.Context) {
+	code := c.GetCodeFromUrl()
+	root := c.Gin.Param("root")
+
+	rq,err := parseContinuousRequest(c, root)
 	if err != nil {
-		c.ReturnError(req.NewBadRequestError("Missing or invalid 'date' parameter"))
+		c.ReturnError(err)
 		return
 	}
 
-	res, err := business.GetPriceBars(c, code, symbol, id)
+	res, err := business.GetContinuousBars(c, code, rq)
 	if err == nil {
 		_ = c.ReturnObject(res)
 		return
@@ -179,6 +210,43 @@ func getPriceBars(c *auth.Context) {
 
 //=============================================================================
 
+func getStream(c *auth.Context) {
+	code  := c.GetCodeFromUrl()
+	symbol:= c.Gin.Param("symbol")
+	st    := c.Gin.DefaultQuery("type", string(adapter.StreamTypeQuotes))
+
+	client, err := business.Subscribe(c, code, symbol, adapter.StreamType(st))
+	if err != nil {
+		c.ReturnError(err)
+		return
+	}
+
+	defer client.Close()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Gin.Stream(func(w io.Writer) bool {
+		select {
+			case e, ok := <-client.Events:
+				if !ok {
+					return false
+				}
+				c.Gin.SSEvent(string(e.Type), e)
+				return true
+
+			case <-heartbeat.C:
+				c.Gin.SSEvent("heartbeat", time.Now().Unix())
+				return true
+
+			case <-c.Gin.Request.Context().Done():
+				return false
+		}
+	})
+}
+
+//=============================================================================
+
 func getAccounts(c *auth.Context) {
 	code := c.GetCodeFromUrl()
 
@@ -198,7 +266,60 @@ func getOrders(c *auth.Context) {
 
 	res, err := business.GetOrders(c, code)
 	if err == nil {
-		_ = c.ReturnObject(res)
+		_ = c.ReturnList(res, 0, 10000, len(res))
+		return
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
+
+func placeOrder(c *auth.Context) {
+	code  := c.GetCodeFromUrl()
+	order := adapter.Order{}
+	err   := c.BindParamsFromBody(&order)
+
+	if err == nil {
+		var res *adapter.Order
+		res, err = business.PlaceOrder(c, code, &order)
+		if err == nil {
+			_ = c.ReturnObject(res)
+			return
+		}
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
+
+func modifyOrder(c *auth.Context) {
+	code := c.GetCodeFromUrl()
+	id   := c.Gin.Param("orderId")
+	oc   := adapter.OrderChange{}
+	err  := c.BindParamsFromBody(&oc)
+
+	if err == nil {
+		var res *adapter.Order
+		res, err = business.ModifyOrder(c, code, id, &oc)
+		if err == nil {
+			_ = c.ReturnObject(res)
+			return
+		}
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
+
+func cancelOrder(c *auth.Context) {
+	code := c.GetCodeFromUrl()
+	id   := c.Gin.Param("orderId")
+	err  := business.CancelOrder(c, code, id)
+
+	if err == nil {
 		return
 	}
 
@@ -212,7 +333,7 @@ func getPositions(c *auth.Context) {
 
 	res, err := business.GetPositions(c, code)
 	if err == nil {
-		_ = c.ReturnObject(res)
+		_ = c.ReturnList(res, 0, 10000, len(res))
 		return
 	}
 
@@ -221,6 +342,25 @@ func getPositions(c *auth.Context) {
 
 //=============================================================================
 
+func replay(c *auth.Context) {
+	code := c.GetCodeFromUrl()
+	spec := business.ReplaySpec{}
+	err  := c.BindParamsFromBody(&spec)
+
+	if err == nil {
+		var res *adapter.ReplayResult
+		res, err = business.Replay(c, code, &spec)
+		if err == nil {
+			_ = c.ReturnObject(res)
+			return
+		}
+	}
+
+	c.ReturnError(err)
+}
+
+//=============================================================================
+
 func testAdapter(c *auth.Context) {
 	code := c.GetCodeFromUrl()
 	tar  := business.TestAdapterRequest{}
@@ -239,3 +379,76 @@ func testAdapter(c *auth.Context) {
 }
 
 //=============================================================================
+//===
+//=== Private functions
+//===
+//=============================================================================
+
+func parsePriceBarsRequest(c *auth.Context, symbol string) (*adapter.PriceBarsRequest, error) {
+	interval,err := strconv.Atoi(c.Gin.DefaultQuery("interval", "1"))
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid 'interval' parameter")
+	}
+
+	rq := &adapter.PriceBarsRequest{
+		Symbol  : symbol,
+		Unit    : adapter.BarUnit(c.Gin.DefaultQuery("unit", string(adapter.BarUnitMinute))),
+		Interval: interval,
+	}
+
+	//--- With 'session=true' dates are trading dates of the symbol's exchange (or of
+	//--- 'exchange', if given) instead of UTC days. The session timeframe implies it
+
+	rq.Timeframe = c.Gin.Query("timeframe")
+
+	if c.Gin.Query("session") == "true" || strings.EqualFold(rq.Timeframe, adapter.TimeframeSession) {
+		rq.Calendar = c.Gin.DefaultQuery("exchange", adapter.CalendarAuto)
+	}
+
+	//--- A single day can be requested with 'date', a range with 'from' and 'to'
+
+	if date := c.Gin.Query("date"); date != "" {
+		rq.From,err = datatype.ParseIntDate(date, true)
+		if err != nil {
+			return nil, req.NewBadRequestError("Invalid 'date' parameter")
+		}
+
+		rq.To = rq.From
+		return rq, nil
+	}
+
+	rq.From,err = datatype.ParseIntDate(c.Gin.Query("from"), true)
+	if err != nil {
+		return nil, req.NewBadRequestError("Missing or invalid 'date' or 'from' parameter")
+	}
+
+	rq.To,err = datatype.ParseIntDate(c.Gin.Query("to"), true)
+	if err != nil {
+		return nil, req.NewBadRequestError("Missing or invalid 'to' parameter")
+	}
+
+	return rq, nil
+}
+
+//=============================================================================
+
+func parseContinuousRequest(c *auth.Context, root string) (*adapter.ContinuousRequest, error) {
+	pbr,err := parsePriceBarsRequest(c, root)
+	if err != nil {
+		return nil, err
+	}
+
+	rollDays,err := strconv.Atoi(c.Gin.DefaultQuery("rollDays", strconv.Itoa(adapter.DefaultRollDays)))
+	if err != nil {
+		return nil, req.NewBadRequestError("Invalid 'rollDays' parameter")
+	}
+
+	return &adapter.ContinuousRequest{
+		PriceBarsRequest: *pbr,
+		Roll            : adapter.RollMethod  (c.Gin.DefaultQuery("roll",   string(adapter.RollMethodExpiration))),
+		RollDays        : rollDays,
+		Adjust          : adapter.AdjustMethod(c.Gin.DefaultQuery("adjust", string(adapter.AdjustMethodDifference))),
+	}, nil
+}
+
+//=============================================================================
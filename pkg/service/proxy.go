@@ -26,7 +26,6 @@ package service
 
 import (
 	"bytes"
-	"crypto/tls"
 	"github.com/bit-fever/core/req"
 	"github.com/bit-fever/system-adapter/pkg/adapter"
 	"github.com/bit-fever/system-adapter/pkg/business"
@@ -47,9 +46,9 @@ const InstanceCode = "InstanceCode"
 //=============================================================================
 
 func proxyLoginRequests(c *gin.Context) {
-	cookie,err := c.Request.Cookie("InstanceCode")
+	cookie,err := c.Request.Cookie(InstanceCode)
 	if err != nil {
-		slog.Warn("Called without cookie", "url", c.Request.URL.String())
+		slog.Warn("Called without cookie", "url", adapter.RedactUrl(c.Request.URL))
 		return
 	}
 
@@ -70,6 +69,7 @@ func proxyLoginRequests(c *gin.Context) {
 
 	proxy := buildProxy(c, target, c.Request.URL.Path, ctx)
 	proxy.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
 }
 
 //=============================================================================
@@ -79,10 +79,14 @@ func buildProxy(c *gin.Context, target *url.URL, forwardPath string, ctx *adapte
 
 	proxy.Rewrite = func (r *httputil.ProxyRequest) {
 		out := r.Out
+		debug := slog.Default().Enabled(c, slog.LevelDebug)
 
-		sb := strings.Builder{}
-		sb.WriteString("\n====== REQUEST : "+ out.Method +" "+out.URL.String()+" --> "+target.Host+forwardPath+" =========\n")
-		sb.WriteString(dumpHeader(&out.Header, "Original header:"))
+		var original slog.Attr
+		var source   string
+		if debug {
+			original = adapter.HeaderAttr("originalHeader", out.Header)
+			source   = adapter.RedactUrl(out.URL)
+		}
 
 		out.URL.Scheme = target.Scheme
 		out.URL.Host   = target.Host
@@ -90,30 +94,37 @@ func buildProxy(c *gin.Context, target *url.URL, forwardPath string, ctx *adapte
 		out.Host       = target.Host
 
 		remapHeader(&out.Header, c.Request.Host, target, false)
+		cookies := out.Cookies()
+		remapCookies(cookies, &out.Header, c.Request.Host, target.Host, false)
 
-		sb.WriteString(dumpHeader(&out.Header, "Modified header:"))
-		sb.WriteString(remapCookies(r.Out.Cookies(), &r.Out.Header, c.Request.Host, target.Host, false))
-		sb.WriteString("==============================================================\n")
-		slog.Info("Proxy request", "data", sb.String())
+		if debug {
+			slog.Debug("Proxy request", "method", out.Method, "url", source, "target", target.Host+forwardPath,
+				original, adapter.HeaderAttr("header", out.Header), adapter.CookieAttr("cookies", cookies))
+		}
 	}
 
-	proxy.Transport = &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
+	proxy.Transport = adapter.GetHttpTransport()
 
 	proxy.ModifyResponse = func(res *http.Response) error {
 		r := res.Request
-		sb := strings.Builder{}
-		sb.WriteString("\n====== RESPONSE : "+ r.Method +" "+r.URL.String()+" --> "+target.Host+forwardPath+" =========\n")
-		sb.WriteString(dumpHeader(&res.Header, "Response header"))
+		debug := slog.Default().Enabled(c, slog.LevelDebug)
+
+		var original slog.Attr
+		if debug {
+			original = adapter.HeaderAttr("originalHeader", res.Header)
+		}
+
 		remapHeader(&res.Header, c.Request.Host, target, true)
-		sb.WriteString(dumpHeader(&res.Header, "Modified header:"))
-		sb.WriteString(remapCookies(res.Cookies(), &res.Header, c.Request.Host, target.Host, true))
-		sb.WriteString("==============================================================\n")
-		slog.Info("Proxy response", "data", sb.String())
+		cookies := res.Cookies()
+		remapCookies(cookies, &res.Header, c.Request.Host, target.Host, true)
+
+		if debug {
+			slog.Debug("Proxy response", "method", r.Method, "url", adapter.RedactUrl(r.URL), "status", res.StatusCode,
+				original, adapter.HeaderAttr("header", res.Header), adapter.CookieAttr("cookies", cookies))
+		}
 
 		if ctx.IsWebLoginCompleted(res.StatusCode, res.Request.URL.Path) {
-			err := ctx.InitFromWebLogin(&res.Request.Header, res.Cookies())
+			err := business.CompleteWebLogin(ctx, &res.Request.Header, res.Cookies())
 			defer res.Body.Close()
 
 			message := htmlfy("Success", "This page can be closed")
@@ -164,10 +175,7 @@ func remapHeader(header *http.Header, source string, target *url.URL, isResponse
 
 //=============================================================================
 
-func remapCookies(cookies []*http.Cookie, header *http.Header, source, destin string, isResponse bool) string {
-	sb := strings.Builder{}
-	sb.WriteString("Cookies:\n")
-
+func remapCookies(cookies []*http.Cookie, header *http.Header, source, destin string, isResponse bool) {
 	host := destin
 
 	if isResponse {
@@ -177,18 +185,11 @@ func remapCookies(cookies []*http.Cookie, header *http.Header, source, destin st
 	domain := extractDomain(host)
 
 	for _, cookie := range cookies {
-		sb.WriteString("   "+ cookie.Name+": "+ cookie.Value)
-
 		if cookie.Domain != "" {
-			sb.WriteString(" (remapped: "+ cookie.Domain +" --> "+ domain +")")
 			cookie.Domain = domain
 			header.Set("Set-Cookie", cookie.String())
 		}
-
-		sb.WriteString("\n")
 	}
-
-	return sb.String()
 }
 
 //=============================================================================
@@ -204,24 +205,6 @@ func extractDomain(host string) string {
 
 //=============================================================================
 
-func dumpHeader(header *http.Header, message string) string {
-	sb := strings.Builder{}
-	sb.WriteString(message +"\n")
-
-	for x,y := range *header {
-		sb.WriteString("   "+ x + " : ")
-		for _,s := range y {
-			sb.WriteString(s +" | ")
-		}
-		sb.WriteString("\n")
-	}
-
-	print(sb.String())
-	return sb.String()
-}
-
-//=============================================================================
-
 func htmlfy(title, message string) string {
 	var sb strings.Builder
 	sb.WriteString("<html><body><h1>")
@@ -44,21 +44,38 @@ func Init(router *gin.Engine, cfg *app.Config, logger *slog.Logger) {
 	router.GET   ("/api/system/v1/connections",                ctrl.Secure(getConnections, roles.Admin_User))
 	router.PUT   ("/api/system/v1/connections/:code",          ctrl.Secure(connect,        roles.Admin_User))
 	router.DELETE("/api/system/v1/connections/:code",          ctrl.Secure(disconnect,     roles.Admin_User))
+	router.GET   ("/api/system/v1/credentials",                ctrl.Secure(getCredentials,   roles.Admin_User))
+	router.POST  ("/api/system/v1/credentials",                ctrl.Secure(addCredential,    roles.Admin_User))
+	router.DELETE("/api/system/v1/credentials/:code",          ctrl.Secure(deleteCredential, roles.Admin_User))
+	router.DELETE("/api/system/v1/bar-cache",                  ctrl.Secure(invalidateBarCache, roles.Admin))
+	router.GET   ("/api/system/v1/calendars/:exchange/days",   ctrl.Secure(getTradingDays,     roles.Admin_User_Service))
 
 	//--- Adapter services
 
-	router.GET   ("/api/system/v1/connections/:code/roots",                    ctrl.Secure(getRootSymbols, roles.Admin_User))
-	router.GET   ("/api/system/v1/connections/:code/roots/:root",              ctrl.Secure(getRootSymbol,  roles.Admin_User))
-	router.GET   ("/api/system/v1/connections/:code/roots/:root/instruments",  ctrl.Secure(getInstruments, roles.Admin_User_Service))
-	router.GET   ("/api/system/v1/connections/:code/instruments/:symbol/bars", ctrl.Secure(getPriceBars,   roles.Admin_User_Service))
-	router.GET   ("/api/system/v1/connections/:code/accounts",                 ctrl.Secure(getAccounts,    roles.Admin_User_Service))
-	router.GET   ("/api/system/v1/connections/:code/orders",                   ctrl.Secure(getOrders,      roles.Admin_User_Service))
-	router.GET   ("/api/system/v1/connections/:code/positions",                ctrl.Secure(getPositions,   roles.Admin_User_Service))
-	router.POST  ("/api/system/v1/connections/:code/test",                     ctrl.Secure(testAdapter,    roles.Admin_User))
-
-	//TODO: To review
-	//router.GET   ("/api/system/v1/connections/:code/login",   webLogin)
-	//router.Use   (proxyLoginRequests)
+	router.GET   ("/api/system/v1/connections/:code/roots",                      ctrl.Secure(getRootSymbols, roles.Admin_User))
+	router.GET   ("/api/system/v1/connections/:code/roots/:root",                ctrl.Secure(getRootSymbol,  roles.Admin_User))
+	router.GET   ("/api/system/v1/connections/:code/roots/:root/instruments",    ctrl.Secure(getInstruments, roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/roots/:root/continuous/bars",ctrl.Secure(getContinuousBars, roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/instruments/:symbol/bars",   ctrl.Secure(getPriceBars,   roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/instruments/:symbol/stream", ctrl.Secure(getStream,      roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/accounts",                   ctrl.Secure(getAccounts,    roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/orders",                     ctrl.Secure(getOrders,      roles.Admin_User_Service))
+	router.POST  ("/api/system/v1/connections/:code/orders",                     ctrl.Secure(placeOrder,     roles.Admin_User_Service))
+	router.PATCH ("/api/system/v1/connections/:code/orders/:orderId",            ctrl.Secure(modifyOrder,    roles.Admin_User_Service))
+	router.DELETE("/api/system/v1/connections/:code/orders/:orderId",            ctrl.Secure(cancelOrder,    roles.Admin_User_Service))
+	router.GET   ("/api/system/v1/connections/:code/positions",                  ctrl.Secure(getPositions,   roles.Admin_User_Service))
+	router.POST  ("/api/system/v1/connections/:code/replay",                     ctrl.Secure(replay,         roles.Admin_User_Service))
+	router.POST  ("/api/system/v1/connections/:code/test",                       ctrl.Secure(testAdapter,    roles.Admin_User))
+
+	//--- Metrics are scraped without authentication and contain no user data
+
+	router.GET   ("/metrics", getMetrics)
+
+	//--- Web login: the browser is redirected to the adapter login page and all requests not
+	//--- matching a route are proxied to it (this is why the middleware is added last)
+
+	router.GET   ("/api/system/v1/weblogin/:code", webLogin)
+	router.Use   (proxyLoginRequests)
 }
 
 //=============================================================================